@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+// bulkLoad remembers what --unlogged and --defer-indexes changed, to restore it after the load
+type bulkLoad struct {
+	unlogged []*db.Table // in insert order
+	dropped  []droppedIndex
+}
+
+type droppedIndex struct {
+	table *db.Table
+	index db.SecondaryIndex
+}
+
+// startBulkLoad makes the tables UNLOGGED and drops their secondary indexes. On error, what was changed is already restored
+func (cmd *RunCmd) startBulkLoad(tablesSorted []*db.Table) (*bulkLoad, error) {
+	b := &bulkLoad{}
+	if cmd.DryRun || (!cmd.Unlogged && !cmd.DeferIndexes) {
+		return b, nil
+	}
+	// a table inserted twice is changed once
+	tables := []*db.Table{}
+	for _, table := range tablesSorted {
+		if !slices.ContainsFunc(tables, func(t *db.Table) bool { return t.FullName() == table.FullName() }) {
+			tables = append(tables, table)
+		}
+	}
+
+	if cmd.Unlogged {
+		// a logged table cannot reference an unlogged one, children go first
+		for _, table := range slices.Backward(tables) {
+			err := db.SetLogged(table, false)
+			if errors.Is(err, db.ErrNoUnloggedTables) {
+				return nil, errors.Wrap(err, "--unlogged")
+			}
+			if err != nil {
+				log.Warn().Err(err).Str("table", table.FullName()).Msg("cannot make the table UNLOGGED for the load, tables outside of the run could reference it")
+				continue
+			}
+			b.unlogged = append([]*db.Table{table}, b.unlogged...)
+			log.Info().Str("table", table.FullName()).Msg("table is UNLOGGED for the load")
+		}
+	}
+
+	if cmd.DeferIndexes {
+		for _, table := range tables {
+			indexes, err := db.GetSecondaryIndexes(table.Schema, table.Name)
+			if err != nil {
+				if restoreErr := b.restore(cmd.WorkersCount); restoreErr != nil {
+					log.Error().Err(restoreErr).Msg("cannot restore the tables")
+				}
+				return nil, err
+			}
+			for _, index := range indexes {
+				if err := db.DropIndex(table, index); err != nil {
+					log.Warn().Err(err).Str("table", table.FullName()).Str("index", index.Name).Msg("cannot drop the index for the load, it is kept")
+					continue
+				}
+				b.dropped = append(b.dropped, droppedIndex{table: table, index: index})
+				log.Info().Str("table", table.FullName()).Str("index", index.Name).Msg("index dropped for the load, it will be created again after it")
+			}
+		}
+	}
+	return b, nil
+}
+
+// restore creates the dropped indexes again, in parallel, then makes the tables logged again, parents first
+func (b *bulkLoad) restore(workers int) error {
+	start := time.Now()
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		failed []string
+	)
+	indexes := make(chan droppedIndex)
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range indexes {
+				if err := db.CreateIndex(d.table, d.index); err != nil {
+					log.Error().Err(err).Str("table", d.table.FullName()).Str("definition", d.index.Definition).Msg("cannot create the index again, it has to be created manually")
+					mutex.Lock()
+					failed = append(failed, d.index.Name)
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	for _, d := range b.dropped {
+		indexes <- d
+	}
+	close(indexes)
+	wg.Wait()
+	if len(b.dropped) > 0 {
+		log.Info().Int("indexes", len(b.dropped)-len(failed)).Dur("duration", time.Since(start)).Msg("indexes created again")
+	}
+
+	stillUnlogged := []string{}
+	for _, table := range b.unlogged {
+		if err := db.SetLogged(table, true); err != nil {
+			log.Error().Err(err).Str("table", table.FullName()).Msg("cannot make the table logged again, it has to be altered manually")
+			stillUnlogged = append(stillUnlogged, table.FullName())
+			continue
+		}
+		log.Info().Str("table", table.FullName()).Msg("table is logged again")
+	}
+	b.dropped, b.unlogged = nil, nil
+
+	if len(failed) > 0 || len(stillUnlogged) > 0 {
+		return errors.Errorf("the tables were not fully restored, indexes not created: %v, tables still UNLOGGED: %v", failed, stillUnlogged)
+	}
+	return nil
+}
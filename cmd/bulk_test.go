@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/ylacancellera/random-data-load/db"
+	"github.com/ylacancellera/random-data-load/db/memdb"
+	"github.com/ylacancellera/random-data-load/query"
+)
+
+// bulkEngine records the changes made to the tables for --unlogged and --defer-indexes
+type bulkEngine struct {
+	*memdb.Database
+	indexes    map[string][]db.SecondaryIndex
+	failCreate string
+	calls      []string
+}
+
+func (e *bulkEngine) GetSecondaryIndexes(_, table string) ([]db.SecondaryIndex, error) {
+	return e.indexes[table], nil
+}
+
+func (e *bulkEngine) DropIndex(table *db.Table, index db.SecondaryIndex) error {
+	e.calls = append(e.calls, "drop "+table.Name+"."+index.Name)
+	return nil
+}
+
+func (e *bulkEngine) CreateIndex(table *db.Table, index db.SecondaryIndex) error {
+	if index.Name == e.failCreate {
+		return errors.New("cannot create")
+	}
+	return nil
+}
+
+func (e *bulkEngine) SetLogged(table *db.Table, logged bool) error {
+	e.calls = append(e.calls, map[bool]string{true: "logged ", false: "unlogged "}[logged]+table.Name)
+	return nil
+}
+
+func TestBulkLoad(t *testing.T) {
+	tests := []struct {
+		name       string
+		failCreate string
+		err        bool
+	}{
+		{name: "restored"},
+		{name: "index not created again", failCreate: "t2_t1_id", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, d := newTestRun(t, 10)
+			e := &bulkEngine{Database: d, failCreate: test.failCreate, indexes: map[string][]db.SecondaryIndex{
+				"t2": {{Name: "t2_t1_id"}, {Name: "t2_id_t1_id"}},
+			}}
+			db.Register(db.EngineInfo{Name: "memory", Dialect: query.DialectPostgres, Engine: e})
+			if _, err := db.Connect(cmd.DB); err != nil {
+				t.Fatal(err)
+			}
+			cmd.Unlogged, cmd.DeferIndexes = true, true
+			t1, t2 := &db.Table{Schema: "public", Name: "t1"}, &db.Table{Schema: "public", Name: "t2"}
+
+			// t2 is inserted twice, it is changed once
+			b, err := cmd.startBulkLoad([]*db.Table{t1, t2, t2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// children are made UNLOGGED first
+			expected := []string{"unlogged t2", "unlogged t1", "drop t2.t2_t1_id", "drop t2.t2_id_t1_id"}
+			if !slices.Equal(e.calls, expected) {
+				t.Fatalf("expected %v, got %v", expected, e.calls)
+			}
+
+			e.calls = nil
+			err = b.restore(2)
+			if test.err != (err != nil) {
+				t.Fatalf("expected error: %v, got %v", test.err, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), test.failCreate) {
+				t.Fatalf("expected the error to name %s, got %v", test.failCreate, err)
+			}
+			// parents are logged again first, even when an index failed
+			expected = []string{"logged t1", "logged t2"}
+			if !slices.Equal(e.calls, expected) {
+				t.Fatalf("expected %v, got %v", expected, e.calls)
+			}
+		})
+	}
+}
+
+func TestBulkLoadUnsupported(t *testing.T) {
+	cmd, _ := newTestRun(t, 10)
+	if _, err := db.Connect(cmd.DB); err != nil {
+		t.Fatal(err)
+	}
+	cmd.Unlogged = true
+
+	_, err := cmd.startBulkLoad([]*db.Table{{Schema: "public", Name: "t1"}})
+	if !errors.Is(err, db.ErrNoUnloggedTables) {
+		t.Fatalf("expected %v, got %v", db.ErrNoUnloggedTables, err)
+	}
+}
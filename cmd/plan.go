@@ -0,0 +1,451 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ylacancellera/random-data-load/db"
+	"github.com/ylacancellera/random-data-load/frequency"
+	"github.com/ylacancellera/random-data-load/generate"
+)
+
+type PlanCmd struct {
+	RunCmd `embed:""`
+
+	Format string `name:"format" help:"Output format of the plan. text, json, or dot/mermaid to get the foreign keys graph" enum:"text,json,dot,mermaid" default:"text"`
+}
+
+// Plan is everything a run would do, without inserting anything
+type Plan struct {
+	InsertionOrder []string    `json:"insertion_order"`
+	Tables         []TablePlan `json:"tables"`
+}
+
+type TablePlan struct {
+	Schema      string           `json:"schema"`
+	Name        string           `json:"name"`
+	Pass        int              `json:"pass"` // self-referencing tables are inserted twice
+	Rows        int64            `json:"rows"`
+	Columns     []ColumnPlan     `json:"columns"`
+	Constraints []ConstraintPlan `json:"constraints"`
+	Checks      []CheckPlan      `json:"checks,omitempty"`
+	Hints       []HintPlan       `json:"hints,omitempty"` // rdl: annotations of the column comments
+	Partitions  *PartitionPlan   `json:"partitions,omitempty"`
+	Hierarchy   string           `json:"hierarchy,omitempty"` // self-referencing tables inserted level by level
+	Triggers    []TriggerPlan    `json:"triggers,omitempty"`
+}
+
+type TriggerPlan struct {
+	Name     string   `json:"name"`
+	Timing   string   `json:"timing"`
+	Events   []string `json:"events"`
+	Writes   []string `json:"writes,omitempty"`
+	Disabled bool     `json:"disabled"`
+}
+
+type ColumnPlan struct {
+	Name       string `json:"name"`
+	DataType   string `json:"data_type"`
+	Action     string `json:"action"` // generate, sample, default or skip
+	Generator  string `json:"generator,omitempty"`
+	SkipReason string `json:"skip_reason,omitempty"`
+}
+
+type CheckPlan struct {
+	Name     string `json:"name"`
+	Clause   string `json:"clause"`
+	Enforced bool   `json:"enforced"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type HintPlan struct {
+	Column     string `json:"column"`
+	Annotation string `json:"annotation"`
+	Applied    bool   `json:"applied"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+type PartitionPlan struct {
+	Method       string               `json:"method"`
+	Key          string               `json:"key"`
+	Distribution string               `json:"distribution"`
+	Supported    bool                 `json:"supported"`
+	Reason       string               `json:"reason,omitempty"`
+	Partitions   []PartitionSharePlan `json:"partitions"`
+}
+
+type PartitionSharePlan struct {
+	Name   string  `json:"name"`
+	Bounds string  `json:"bounds,omitempty"`
+	Share  float64 `json:"share"` // expected fraction of the rows
+}
+
+type ConstraintPlan struct {
+	Name              string   `json:"name"`
+	Virtual           bool     `json:"virtual"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referenced_schema"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	Sampler           string   `json:"sampler,omitempty"`
+	InsertedDuringRun bool     `json:"inserted_during_run"`
+	Deferred          bool     `json:"deferred,omitempty"` // left NULL to break a loop, updated after the inserts
+	Reason            string   `json:"reason,omitempty"`
+}
+
+const (
+	columnActionGenerate = "generate"
+	columnActionSample   = "sample"
+	columnActionDefault  = "default"
+	columnActionSkip     = "skip"
+)
+
+// Run prints the plan.
+func (cmd *PlanCmd) Run() error {
+	tablesSorted, err := cmd.prepare()
+	if err != nil {
+		return err
+	}
+
+	plan := cmd.buildPlan(tablesSorted)
+
+	switch cmd.Format {
+	case "json":
+		return plan.writeJSON(os.Stdout)
+	case "dot":
+		return plan.writeDot(os.Stdout)
+	case "mermaid":
+		return plan.writeMermaid(os.Stdout)
+	default:
+		return plan.writeText(os.Stdout)
+	}
+}
+
+func (cmd *PlanCmd) buildPlan(tablesSorted []*db.Table) Plan {
+	plan := Plan{}
+	passes := map[string]int{}
+
+	for _, table := range tablesSorted {
+		passes[table.FullName()]++
+		plan.InsertionOrder = append(plan.InsertionOrder, table.FullName())
+
+		ins := generate.New(table, cmd.ForeignKeyLinks, cmd.WorkersCount, cmd.MaxTextSize, cmd.UUIDVersion, frequency.ForTable(cmd.tableKeys(table)...))
+		tp := TablePlan{
+			Schema: table.Schema,
+			Name:   table.Name,
+			Pass:   passes[table.FullName()],
+			Rows:   valueForTable(cmd.Rows, cmd.RowsPerTable, cmd.tableKeys(table)),
+		}
+		if h := cmd.hierarchyFor(table); h != nil {
+			roots := h.RootCount(tp.Rows)
+			if isSelfReferencing(table) {
+				tp.Rows -= roots
+				tp.Hierarchy = "levels below the roots, " + h.String()
+			} else {
+				tp.Rows = roots
+				tp.Hierarchy = "roots, " + h.String()
+			}
+		}
+
+		toSample := table.ConstraintsToSample()
+		for _, field := range table.Fields {
+			tp.Columns = append(tp.Columns, columnPlan(table, toSample, field, ins))
+		}
+
+		for _, constraint := range table.Constraints {
+			cp := ConstraintPlan{
+				Name:              constraint.ConstraintName,
+				Virtual:           constraint.IsVirtual(),
+				Columns:           constraint.ColumnsName,
+				ReferencedSchema:  constraint.ReferencedTableSchema,
+				ReferencedTable:   constraint.ReferencedTableName,
+				ReferencedColumns: constraint.ReferencedColumnsName,
+				InsertedDuringRun: constraint.WillBeInsertedDuringThisRun(),
+				Deferred:          constraint.IsDeferred(),
+			}
+			if isConstraintSampled(toSample, constraint) || isConstraintSampled(table.DeferredConstraints(), constraint) {
+				cp.Sampler = cmd.SamplerName(constraint.ReferencedTableName, table.Name)
+			} else if constraint.HasGeneratedFields() {
+				cp.Reason = "generated by the database"
+			} else {
+				cp.Reason = "every column of the constraint is skipped"
+			}
+			tp.Constraints = append(tp.Constraints, cp)
+		}
+
+		for _, trigger := range table.Triggers {
+			tp.Triggers = append(tp.Triggers, TriggerPlan{
+				Name:     trigger.Name,
+				Timing:   trigger.Timing,
+				Events:   trigger.Events,
+				Writes:   trigger.Writes,
+				Disabled: cmd.Triggers == db.TriggersDisable,
+			})
+		}
+
+		for _, status := range ins.CheckStatuses() {
+			tp.Checks = append(tp.Checks, CheckPlan(status))
+		}
+		for _, status := range ins.HintStatuses() {
+			tp.Hints = append(tp.Hints, HintPlan(status))
+		}
+		if status := ins.PartitionStatus(); status != nil {
+			tp.Partitions = &PartitionPlan{
+				Method:       status.Method,
+				Key:          status.Expression,
+				Distribution: status.Distribution,
+				Supported:    status.Supported,
+				Reason:       status.Reason,
+			}
+			for _, share := range status.Partitions {
+				tp.Partitions.Partitions = append(tp.Partitions.Partitions, PartitionSharePlan(share))
+			}
+		}
+		plan.Tables = append(plan.Tables, tp)
+	}
+	return plan
+}
+
+func columnPlan(table *db.Table, toSample db.Constraints, field db.Field, ins *generate.Insert) ColumnPlan {
+	cp := ColumnPlan{Name: field.ColumnName, DataType: field.DataType}
+
+	for _, f := range table.FieldsToInsertAsDefault() {
+		if f.ColumnName == field.ColumnName {
+			cp.Action = columnActionDefault
+			return cp
+		}
+	}
+	for _, constraint := range toSample {
+		for _, f := range constraint.Fields {
+			if f.ColumnName == field.ColumnName {
+				cp.Action = columnActionSample
+				cp.Generator = "sampled from " + constraint.ReferencedTableName + " by " + constraint.ConstraintName
+				return cp
+			}
+		}
+	}
+	for _, constraint := range table.DeferredConstraints() {
+		for _, f := range constraint.Fields {
+			if f.ColumnName == field.ColumnName {
+				cp.Action = columnActionSample
+				cp.Generator = "left NULL, then updated from " + constraint.ReferencedTableName + " by " + constraint.ConstraintName + " to break a loop"
+				return cp
+			}
+		}
+	}
+	for _, f := range table.FieldsToGenerate() {
+		if f.ColumnName == field.ColumnName {
+			cp.Action = columnActionGenerate
+			cp.Generator = ins.GeneratorName(field)
+			return cp
+		}
+	}
+
+	cp.Action = columnActionSkip
+	switch {
+	case field.Skip:
+		cp.SkipReason = field.SkipReason
+	case field.Generated && field.GeneratedBy != "":
+		cp.SkipReason = field.GeneratedBy
+	case field.Generated:
+		cp.SkipReason = "generated by the database"
+	case !field.IsSupportedType():
+		cp.SkipReason = "unsupported datatype"
+	case field.AutoIncrement:
+		cp.SkipReason = "auto-increment"
+	case table.IsFieldInAnyConstraints(field):
+		cp.SkipReason = "part of a foreign key that is not sampled"
+	}
+	return cp
+}
+
+func isConstraintSampled(toSample db.Constraints, constraint *db.Constraint) bool {
+	for _, c := range toSample {
+		if c == constraint {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Plan) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(p)
+}
+
+func (p Plan) writeText(w io.Writer) error {
+	var sb strings.Builder
+
+	sb.WriteString("Insertion order:\n")
+	for i, name := range p.InsertionOrder {
+		fmt.Fprintf(&sb, "  %d. %s\n", i+1, name)
+	}
+
+	for _, table := range p.Tables {
+		fmt.Fprintf(&sb, "\n%s.%s", table.Schema, table.Name)
+		if table.Pass > 1 {
+			fmt.Fprintf(&sb, " (pass %d)", table.Pass)
+		}
+		fmt.Fprintf(&sb, ": %d rows\n", table.Rows)
+		if table.Hierarchy != "" {
+			fmt.Fprintf(&sb, "  hierarchy: %s\n", table.Hierarchy)
+		}
+
+		sb.WriteString("  columns:\n")
+		for _, col := range table.Columns {
+			fmt.Fprintf(&sb, "    %s %s: %s", col.Name, col.DataType, col.Action)
+			switch {
+			case col.Generator != "":
+				fmt.Fprintf(&sb, " (%s)", col.Generator)
+			case col.SkipReason != "":
+				fmt.Fprintf(&sb, " (%s)", col.SkipReason)
+			}
+			sb.WriteString("\n")
+		}
+
+		if len(table.Constraints) > 0 {
+			sb.WriteString("  constraints:\n")
+		}
+		for _, c := range table.Constraints {
+			kind := "fk"
+			if c.Virtual {
+				kind = "virtual fk"
+			}
+			fmt.Fprintf(&sb, "    %s %s: (%s) -> %s.%s(%s)", kind, c.Name, strings.Join(c.Columns, ","), c.ReferencedSchema, c.ReferencedTable, strings.Join(c.ReferencedColumns, ","))
+			if c.Sampler != "" {
+				fmt.Fprintf(&sb, ", %s sampler", c.Sampler)
+			} else {
+				fmt.Fprintf(&sb, ", not sampled: %s", c.Reason)
+			}
+			if c.Deferred {
+				sb.WriteString(", updated after the inserts to break a loop")
+			}
+			if !c.InsertedDuringRun {
+				sb.WriteString(", referenced table is not part of this run")
+			}
+			sb.WriteString("\n")
+		}
+
+		if len(table.Checks) > 0 {
+			sb.WriteString("  checks:\n")
+		}
+		for _, c := range table.Checks {
+			fmt.Fprintf(&sb, "    %s: %s", c.Name, c.Clause)
+			if !c.Enforced {
+				fmt.Fprintf(&sb, ", not enforced: %s", c.Reason)
+			}
+			sb.WriteString("\n")
+		}
+
+		if len(table.Hints) > 0 {
+			sb.WriteString("  hints:\n")
+		}
+		for _, h := range table.Hints {
+			fmt.Fprintf(&sb, "    %s: %s", h.Column, h.Annotation)
+			if !h.Applied {
+				fmt.Fprintf(&sb, ", ignored: %s", h.Reason)
+			}
+			sb.WriteString("\n")
+		}
+
+		if len(table.Triggers) > 0 {
+			sb.WriteString("  triggers:\n")
+		}
+		for _, t := range table.Triggers {
+			fmt.Fprintf(&sb, "    %s: %s %s", t.Name, t.Timing, strings.Join(t.Events, " OR "))
+			if len(t.Writes) > 0 {
+				fmt.Fprintf(&sb, ", writes %s", strings.Join(t.Writes, ", "))
+			}
+			if t.Disabled {
+				sb.WriteString(", disabled")
+			}
+			sb.WriteString("\n")
+		}
+
+		if p := table.Partitions; p != nil {
+			fmt.Fprintf(&sb, "  partitions: %s on %s", p.Method, p.Key)
+			if p.Supported {
+				fmt.Fprintf(&sb, ", %s distribution\n", p.Distribution)
+			} else {
+				fmt.Fprintf(&sb, ", values not chosen per partition: %s\n", p.Reason)
+			}
+			for _, partition := range p.Partitions {
+				fmt.Fprintf(&sb, "    %s %s", partition.Name, partition.Bounds)
+				if p.Supported {
+					fmt.Fprintf(&sb, ": %.1f%%", partition.Share*100)
+				}
+				sb.WriteString("\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (p Plan) writeDot(w io.Writer) error {
+	var sb strings.Builder
+
+	sb.WriteString("digraph plan {\n\trankdir=LR;\n")
+	for _, table := range p.Tables {
+		if table.Pass > 1 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\t%q [shape=box, label=\"%s\\n%d rows\"];\n", table.Schema+"."+table.Name, table.Name, table.Rows)
+	}
+	for _, table := range p.Tables {
+		for _, c := range table.Constraints {
+			style := "solid"
+			if c.Virtual {
+				style = "dashed"
+			}
+			fmt.Fprintf(&sb, "\t%q -> %q [label=%q, style=%s];\n", table.Schema+"."+table.Name, c.ReferencedSchema+"."+c.ReferencedTable, edgeLabel(c), style)
+		}
+	}
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (p Plan) writeMermaid(w io.Writer) error {
+	var sb strings.Builder
+
+	// mermaid node ids cannot contain dots, labels are kept readable
+	nodeID := func(schema, table string) string {
+		return strings.NewReplacer(".", "_", " ", "_", "-", "_").Replace(schema + "_" + table)
+	}
+
+	sb.WriteString("flowchart LR\n")
+	for _, table := range p.Tables {
+		if table.Pass > 1 {
+			continue
+		}
+		fmt.Fprintf(&sb, "    %s[\"%s<br/>%d rows\"]\n", nodeID(table.Schema, table.Name), table.Name, table.Rows)
+	}
+	for _, table := range p.Tables {
+		for _, c := range table.Constraints {
+			arrow := "-->"
+			if c.Virtual {
+				arrow = "-.->"
+			}
+			fmt.Fprintf(&sb, "    %s %s|\"%s\"| %s\n", nodeID(table.Schema, table.Name), arrow, edgeLabel(c), nodeID(c.ReferencedSchema, c.ReferencedTable))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func edgeLabel(c ConstraintPlan) string {
+	label := strings.Join(c.Columns, ",") + " = " + strings.Join(c.ReferencedColumns, ",")
+	if c.Sampler != "" {
+		label += " (" + c.Sampler + ")"
+	}
+	return label
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+// newTestPlan plans t1 referencing itself, t2 referencing t1, and t2 joined to t3 by the query only
+func newTestPlan(t *testing.T) Plan {
+	run, d := newTestRun(t, 30)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "parent_id", DataType: "int", IsNullable: true},
+		{ColumnName: "name", DataType: "varchar", CharacterMaximumLength: nullInt(20)},
+	}, db.Constraint{ConstraintName: "fk_parent", ColumnsName: []string{"parent_id"}, ReferencedTableName: "t1", ReferencedColumnsName: []string{"id"}})
+	d.CreateTable("", "t2", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "t1_id", DataType: "int"},
+		{ColumnName: "t3_id", DataType: "int"},
+	}, db.Constraint{ConstraintName: "fk_t1", ColumnsName: []string{"t1_id"}, ReferencedTableName: "t1", ReferencedColumnsName: []string{"id"}})
+	d.CreateTable("", "t3", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "code", DataType: "varchar", CharacterMaximumLength: nullInt(10)},
+	})
+	run.Query = "select * from t1 join t2 on t1.id = t2.t1_id join t3 on t3.id = t2.t3_id"
+
+	cmd := &PlanCmd{RunCmd: *run}
+	tables, err := cmd.prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd.buildPlan(tables)
+}
+
+func TestPlan(t *testing.T) {
+	plan := newTestPlan(t)
+
+	expectedOrder := []string{"public.t1", "public.t3", "public.t1", "public.t2"}
+	if !reflect.DeepEqual(plan.InsertionOrder, expectedOrder) {
+		t.Fatalf("expected the insertion order %v, got %v", expectedOrder, plan.InsertionOrder)
+	}
+	// the self-referencing foreign key is only sampled on the second pass
+	first, second := plan.Tables[0], plan.Tables[2]
+	if first.Pass != 1 || len(first.Constraints) != 0 || first.Columns[1].Action != columnActionSkip {
+		t.Errorf("expected the first pass of t1 to leave parent_id NULL, got %+v", first)
+	}
+	if second.Pass != 2 || len(second.Constraints) != 1 || second.Columns[1].Action != columnActionSample {
+		t.Errorf("expected the second pass of t1 to sample parent_id, got %+v", second)
+	}
+	if c := plan.Tables[3].Constraints; len(c) != 2 || c[0].Virtual || !c[1].Virtual || c[1].ReferencedTable != "t3" || !c[1].InsertedDuringRun {
+		t.Errorf("expected t2 to reference t1, and t3 by a virtual foreign key, got %+v", c)
+	}
+}
+
+func TestPlanJSON(t *testing.T) {
+	plan := newTestPlan(t)
+	var b bytes.Buffer
+	if err := plan.writeJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	decoded := Plan{}
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, plan) {
+		t.Errorf("expected the plan to round-trip, got %+v", decoded)
+	}
+}
+
+func TestPlanText(t *testing.T) {
+	plan := newTestPlan(t)
+	var b bytes.Buffer
+	if err := plan.writeText(&b); err != nil {
+		t.Fatal(err)
+	}
+	text := b.String()
+	if !strings.HasPrefix(text, "Insertion order:\n  1. public.t1\n  2. public.t3\n  3. public.t1\n  4. public.t2\n") {
+		t.Errorf("expected the insertion order first, got\n%s", text)
+	}
+	// the tables follow in insertion order
+	sections := []string{
+		"\npublic.t1: 15 rows\n",
+		"    parent_id int: skip (self-referencing foreign key fk_parent, left NULL on the first pass)\n",
+		"\npublic.t3: 30 rows\n",
+		"\npublic.t1 (pass 2): 15 rows\n",
+		"    parent_id int: sample (sampled from t1 by fk_parent)\n",
+		"    fk fk_parent: (parent_id) -> public.t1(id), binomial sampler\n",
+		"\npublic.t2: 30 rows\n",
+		"    t3_id int: sample (sampled from t3 by VirtualFK_t3_id",
+		"    fk fk_t1: (t1_id) -> public.t1(id), binomial sampler\n",
+		"    virtual fk VirtualFK_t3_id",
+		"(t3_id) -> public.t3(id), binomial sampler\n",
+	}
+	rest := text
+	for _, section := range sections {
+		i := strings.Index(rest, section)
+		if i < 0 {
+			t.Fatalf("expected %q after the previous sections, got\n%s", section, text)
+		}
+		rest = rest[i+len(section):]
+	}
+}
+
+func TestPlanGraphs(t *testing.T) {
+	plan := newTestPlan(t)
+	tests := []struct {
+		name     string
+		write    func(Plan, *bytes.Buffer) error
+		expected []string
+	}{
+		{
+			name:  "dot",
+			write: func(p Plan, b *bytes.Buffer) error { return p.writeDot(b) },
+			expected: []string{
+				"digraph plan {",
+				"\t\"public.t1\" [shape=box, label=\"t1\\n15 rows\"];",
+				"\t\"public.t2\" [shape=box, label=\"t2\\n30 rows\"];",
+				"\t\"public.t3\" [shape=box, label=\"t3\\n30 rows\"];",
+				"\t\"public.t1\" -> \"public.t1\" [label=\"parent_id = id (binomial)\", style=solid];",
+				"\t\"public.t2\" -> \"public.t1\" [label=\"t1_id = id (binomial)\", style=solid];",
+				"\t\"public.t2\" -> \"public.t3\" [label=\"t3_id = id (binomial)\", style=dashed];",
+				"}",
+			},
+		},
+		{
+			name:  "mermaid",
+			write: func(p Plan, b *bytes.Buffer) error { return p.writeMermaid(b) },
+			expected: []string{
+				"flowchart LR",
+				"    public_t1[\"t1<br/>15 rows\"]",
+				"    public_t2[\"t2<br/>30 rows\"]",
+				"    public_t3[\"t3<br/>30 rows\"]",
+				"    public_t1 -->|\"parent_id = id (binomial)\"| public_t1",
+				"    public_t2 -->|\"t1_id = id (binomial)\"| public_t1",
+				"    public_t2 -.->|\"t3_id = id (binomial)\"| public_t3",
+			},
+		},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := test.write(plan, &b); err != nil {
+			t.Fatal(err)
+		}
+		// nodes are written once, even for tables inserted twice
+		for _, line := range test.expected {
+			if n := strings.Count(b.String(), line+"\n"); n != 1 {
+				t.Errorf("%s: expected the line %q once, got it %d times in\n%s", test.name, line, n, b.String())
+			}
+		}
+	}
+}
@@ -1,31 +1,44 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 
+	"github.com/ylacancellera/random-data-load/db"
 	"github.com/ylacancellera/random-data-load/query"
 )
 
 type QueryCmd struct {
 	Query  string `required:""`
-	Engine string `enum:"mysql,pg" required:""`
+	Engine string `enum:"${engines}" required:"" help:"${engines}"`
+	Format string `name:"format" help:"text or json. json also lists the parameters operators and the aliases" enum:"text,json" default:"text"`
 }
 
 func (cmd *QueryCmd) Run() error {
-	var (
-		tables, identifiers map[string]struct{}
-		//joins               map[string]string
-		joins       []query.VirtualJoin
-		queryParams map[string][]string
-		err         error
-	)
-	tables, identifiers, joins, queryParams, err = query.ParseQuery(cmd.Query, cmd.Engine, false)
+	dialect, err := db.Dialect(cmd.Engine)
 	if err != nil {
 		return err
 	}
-	fmt.Println("tables", tables)
-	fmt.Println("joins", joins)
-	fmt.Println("identifiers", identifiers)
-	fmt.Println("queryParams", queryParams)
+	analysis, err := query.Analyze(cmd.Query, dialect, false)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(analysis)
+	}
+
+	fmt.Println("tables", analysis.Tables)
+	fmt.Println("joins", analysis.Joins)
+	fmt.Println("identifiers", analysis.Identifiers)
+	fmt.Println("queryParams", analysis.QueryParams())
+	fmt.Println("jsonPaths", analysis.JSONColumns())
+	for _, d := range analysis.Diagnostics {
+		fmt.Printf("skipped %s (%s): %s\n", d.Kind, d.Reason, d.Clause)
+	}
 	return nil
 }
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
 	"slices"
@@ -21,7 +22,7 @@ type RunCmd struct {
 
 	Table        string           `help:"Table to insert to. When using --query, --table will be used to restrict the tables to insert to."`
 	Rows         int64            `name:"rows" required:"true" help:"Number of rows to insert"`
-	RowsPerTable map[string]int64 `name:"rows-per-table" help:"Number of rows to insert per-table. Will have priority over --rows. Format is \"{table}=X\"" default:""`
+	RowsPerTable map[string]int64 `name:"rows-per-table" help:"Number of rows to insert per-table. Will have priority over --rows. Format is \"{table}=X\", the table can be qualified by its schema" default:""`
 	BulkSize     int64            `name:"bulk-size" help:"Number of rows per insert statement" default:"1000"`
 	DryRun       bool             `name:"dry-run" help:"Print queries to the standard output instead of inserting them into the db"`
 	Quiet        bool             `name:"quiet" help:"Do not print progress bar"`
@@ -38,15 +39,208 @@ type RunCmd struct {
 	NullFreqMap     frequency.FrequencyNullParameter        `name:"null-freq-map" help:"Define how frequent nullable fields should be NULL for a given column. Will have priority over --null-freq. The format is \"--null-freq-map=t1.c1=73;t1.c2=4\" to set 73%% or 4%% of NULL for respective columns" default:""`
 	ValuesFreqMap   frequency.FrequencyIndexValuesParameter `name:"values-freq-map" help:"Inject arbitrary values at fixed frequencies. The format is \"--values-freq-map=t1.c1=val1:0.75,val2:0.23;t1.c2=10:0.99\" so that val1 will be on 75%% of rows and val2 on 23%% for column c1" default:""` // TODO we're not checking if the total freq is above 1
 	QueryParamsFreq float64                                 `name:"query-param-freq" help:"Frequency at which to insert arbitrary values guessed from the query parameters. = and IN operators are handled. Can be disabled when set to 0.0." default:"0.1"`
+
+	Hierarchy   map[string]string              `name:"hierarchy" help:"Shape the tree built by a self-referencing foreign key. Roots are inserted first, then each level picks its parents among the previous level only. Options are depth (levels, roots included), roots (percentage of the rows) and branching (children per parent: n, poisson(mean) or uniform(min,max), default poisson(2)). The referenced column must be auto-incremented. Format: --hierarchy=\"table.parent_id=depth:6,branching:poisson(4)\"" default:""`
+	hierarchies map[string]*generate.Hierarchy `kong:"-"`
+	sharedNames map[string]bool                `kong:"-"`
+
+	ExplicitKeys bool `name:"explicit-keys" help:"Generate the auto-increment primary keys instead of leaving them to the database. PostgreSQL GENERATED ALWAYS identity columns are inserted with OVERRIDING SYSTEM VALUE. Sequences are moved past the inserted keys after the load"`
+
+	Triggers string `name:"triggers" help:"What to do with the triggers firing on INSERT or UPDATE of the loaded tables. keep: let them fire and count the rows they write to other tables, disable: turn them off for the sessions of the tool (PostgreSQL only, using session_replication_role=replica), fail: refuse to load tables having triggers" enum:"keep,disable,fail" default:"keep"`
+
+	BulkLoadMode bool `name:"bulk-load-mode" help:"Session settings speeding up the load. MySQL: unique_checks=0, foreign_key_checks=0 and sql_log_bin=0 when allowed, PostgreSQL: synchronous_commit=off, SQLite: synchronous=OFF"`
+	Unlogged     bool `name:"unlogged" help:"PostgreSQL only, make the tables UNLOGGED during the load and logged again after it. The tables are emptied if the server crashes in between"`
+	DeferIndexes bool `name:"defer-indexes" help:"Drop the secondary indexes, neither unique nor backing a constraint, before the load and create them again after it, using --workers connections"`
+
+	ArrayLength         string  `name:"array-length" help:"Number of elements of the generated PostgreSQL arrays: n, poisson(mean) or uniform(min,max)" default:"uniform(0,5)"`
+	ArrayNullFreq       float64 `name:"array-null-freq" help:"Define how frequent the elements of the generated arrays should be NULL" default:"0"`
+	ArrayUniqueElements bool    `name:"array-unique-elements" help:"Do not repeat an element within a generated array. Arrays of small types, like enums or booleans, can then be shorter than --array-length"`
+
+	GeoBox             string `name:"geo-bbox" help:"Bounding box of the generated spatial columns: minx,miny,maxx,maxy. Columns of a geographic SRID keep within the longitudes and latitudes" default:"-180,-90,180,90"`
+	GeoDistribution    string `name:"geo-distribution" help:"How the points, and the centers of the linestrings and polygons, spread over the bounding box. uniform, or clustered(hotspots,radius): around hotspots picked in the box, radius being the standard deviation in the unit of the coordinates. clustered alone is 5 hotspots with a radius of 1%% of the box" default:"uniform"`
+	GeoPolygonVertices string `name:"geo-polygon-vertices" help:"Number of vertices of the generated polygons, and of points of the linestrings: n, poisson(mean) or uniform(min,max)" default:"uniform(3,8)"`
+
+	JSONSchema map[string]string `name:"json-schema" help:"Shape the documents of a JSON column with a JSON Schema file. type, properties, required, items, enum, const, anyOf, oneOf, format, minimum, maximum, minLength, maxLength, minItems and maxItems are supported. Without it, the documents of the JSON columns read by the --query hold the keys it reads. Format: --json-schema=\"table.column=schema.json\"" default:""`
+
+	PartitionDistribution string `name:"partition-distribution" help:"How rows are spread over the partitions of range and list partitioned tables. even: same number of rows per partition, proportional: proportional to the rows each partition already holds, recent: skewed toward the last range partitions" enum:"even,proportional,recent" default:"even"`
 }
 
 // Run starts inserting data.
-func (cmd *RunCmd) Run() error {
+func (cmd *RunCmd) Run() (err error) {
+
+	tablesSorted, err := cmd.prepare()
+	if err != nil {
+		return err
+	}
+	triggerCounts, err := cmd.countTriggerTargets(tablesSorted, nil)
+	if err != nil {
+		return err
+	}
+
+	bulk, err := cmd.startBulkLoad(tablesSorted)
+	if err != nil {
+		return err
+	}
+	// the tables are restored even when the load fails
+	defer func() {
+		if restoreErr := bulk.restore(cmd.WorkersCount); restoreErr != nil {
+			if err != nil {
+				log.Error().Err(restoreErr).Msg("cannot restore the tables")
+				return
+			}
+			err = restoreErr
+		}
+	}()
+
+	// one at a time.
+	// Parallelizing here will complexify the foreign links, for probably not so much gain
+	for _, table := range tablesSorted {
+		err = cmd.run(table)
+		if err != nil {
+			// if FK fails on mysql, it could be due to an extra foreign keys even though the referenced table do not exist
+			if dialect, _ := db.Dialect(cmd.DB.Engine); dialect == query.DialectMySQL && strings.Contains(err.Error(), "Error 1452") {
+				helperForMySQLFKChecks(tablesSorted, err)
+			}
+			return errors.Wrapf(err, "failed to insert on %s.%s", table.Schema, table.Name)
+		}
+	}
+
+	if err := cmd.fillDeferredConstraints(tablesSorted); err != nil {
+		return err
+	}
+	if err := cmd.syncSequences(tablesSorted); err != nil {
+		return err
+	}
+	_, err = cmd.countTriggerTargets(tablesSorted, triggerCounts)
+	return err
+}
+
+// syncSequences moves the sequences and auto-increment counters past the keys inserted, so that the next insert of the application does not collide
+func (cmd *RunCmd) syncSequences(tablesSorted []*db.Table) error {
+	if cmd.DryRun {
+		return nil
+	}
+	synced := map[string]bool{}
+	for _, table := range tablesSorted {
+		if synced[table.FullName()] {
+			continue
+		}
+		synced[table.FullName()] = true
+		if err := db.SyncSequences(table); err != nil {
+			return errors.Wrapf(err, "failed to sync the sequences of %s.%s", table.Schema, table.Name)
+		}
+	}
+	return nil
+}
+
+// countTriggerTargets counts the rows of the tables written by triggers outside of the run.
+// Given the counts taken before the inserts, it reports how many rows the triggers wrote
+func (cmd *RunCmd) countTriggerTargets(tablesSorted []*db.Table, before map[string]int64) (map[string]int64, error) {
+	counts := map[string]int64{}
+	if cmd.Triggers != db.TriggersKeep || cmd.DryRun {
+		return counts, nil
+	}
+	for _, target := range db.TriggerTargets(tablesSorted, cmd.DB.Database) {
+		n, err := db.CountRows(target.Table)
+		if err != nil {
+			// the trigger could write to a table we misread from its body
+			log.Warn().Err(err).Str("table", target.Table.FullName()).Msg("cannot count the rows of a table written by triggers")
+			continue
+		}
+		counts[target.Table.FullName()] = n
+		if previous, ok := before[target.Table.FullName()]; ok {
+			log.Info().Str("table", target.Table.FullName()).Strs("triggers", target.Triggers).Int64("rows", n-previous).Msg("rows written by triggers")
+		}
+	}
+	return counts, nil
+}
+
+// checkTriggers applies --triggers to the loaded tables
+func (cmd *RunCmd) checkTriggers(tables []*db.Table) error {
+	for _, table := range tables {
+		if len(table.Triggers) == 0 {
+			continue
+		}
+		names := []string{}
+		for _, trigger := range table.Triggers {
+			names = append(names, trigger.Name)
+		}
+		switch cmd.Triggers {
+		case db.TriggersFail:
+			return errors.Errorf("table %s.%s has triggers %s, drop them or use --triggers=keep or --triggers=disable", table.Schema, table.Name, strings.Join(names, ", "))
+		case db.TriggersDisable:
+			log.Info().Str("table", table.Name).Strs("triggers", names).Msg("triggers are disabled for this run")
+		default:
+			log.Warn().Str("table", table.Name).Strs("triggers", names).Msg("table has triggers firing on INSERT or UPDATE, they will slow the load down and can write to other tables")
+		}
+	}
+	return nil
+}
+
+// fillDeferredConstraints updates the foreign keys left NULL to break loops between tables, now that every table is loaded
+func (cmd *RunCmd) fillDeferredConstraints(tablesSorted []*db.Table) error {
+	// a table inserted twice shares its constraints
+	rowsPerTable := cmd.totalRows(tablesSorted)
+	filled := map[*db.Constraint]bool{}
+	for _, table := range tablesSorted {
+		for _, constraint := range table.DeferredConstraints() {
+			if filled[constraint] {
+				continue
+			}
+			filled[constraint] = true
+			if cmd.DryRun {
+				log.Info().Str("table", table.Name).Str("constraint", constraint.ConstraintName).Msg("dry run, the foreign key left NULL to break a loop is not updated")
+				continue
+			}
+			n, err := generate.FillDeferredConstraint(table, constraint, cmd.ForeignKeyLinks, rowsPerTable[table.FullName()], cmd.BulkSize)
+			if err != nil {
+				return errors.Wrapf(err, "failed to update %s on %s.%s", constraint.ConstraintName, table.Schema, table.Name)
+			}
+			log.Info().Str("table", table.Name).Str("constraint", constraint.ConstraintName).Int64("rows", n).Msg("foreign key left NULL to break a loop updated")
+		}
+	}
+	return nil
+}
+
+// expandViews replaces the views read by the query with the tables of their definition, nested views included
+func expandViews(analysis *query.Analysis, dialect, database string, skipJoins bool, expanding map[string]struct{}) error {
+	for _, name := range slices.Sorted(maps.Keys(analysis.Tables)) {
+		definition, err := db.ViewDefinition(database, name)
+		if err != nil {
+			return err
+		}
+		if definition == "" {
+			continue
+		}
+		if _, ok := expanding[name]; ok {
+			return errors.Errorf("view %s is defined using itself", name)
+		}
+		viewAnalysis, err := query.Analyze(definition, dialect, skipJoins)
+		if err != nil {
+			return errors.Wrapf(err, "cannot parse the definition of view %s", name)
+		}
+		expanding[name] = struct{}{}
+		err = expandViews(viewAnalysis, dialect, database, skipJoins, expanding)
+		delete(expanding, name)
+		if err != nil {
+			return err
+		}
+		log.Info().Str("view", name).Interface("tables", slices.Sorted(maps.Keys(viewAnalysis.Tables))).Msg("the query reads a view, inserting into its tables instead")
+		analysis.ExpandView(name, viewAnalysis)
+	}
+	return nil
+}
+
+// prepare connects, parses the query and loads every table involved, then returns them in the order they should be inserted
+func (cmd *RunCmd) prepare() ([]*db.Table, error) {
+
+	cmd.DB.DisableTriggers = cmd.Triggers == db.TriggersDisable
+	cmd.DB.BulkLoad = cmd.BulkLoadMode
 
 	// Quick check to confirm database connection
 	_, err := db.Connect(cmd.DB)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if (float64(cmd.Rows) * cmd.CoinFlipPercent) < (float64(cmd.BulkSize) / 2) {
@@ -58,17 +252,26 @@ func (cmd *RunCmd) Run() error {
 	identifiers := map[string]struct{}{}
 	joins := []query.VirtualJoin{}
 	queryParams := map[string][]string{}
+	jsonPaths := map[string][]query.JSONPath{}
 
 	if cmd.Query == "" && cmd.Table == "" {
-		return errors.New("Need either a --query or a --table")
+		return nil, errors.New("Need either a --query or a --table")
 	}
 
 	if cmd.Query != "" {
-		tablesNames, identifiers, joins, queryParams, err = query.ParseQuery(cmd.Query, cmd.DB.Engine, cmd.NoFKGuess)
+		dialect, err := db.Dialect(cmd.DB.Engine)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		log.Debug().Interface("identifiers", identifiers).Interface("joins", joins).Interface("queryParams", queryParams).Msg("query parsed")
+		analysis, err := query.Analyze(cmd.Query, dialect, cmd.NoFKGuess)
+		if err != nil {
+			return nil, err
+		}
+		if err := expandViews(analysis, dialect, cmd.DB.Database, cmd.NoFKGuess, map[string]struct{}{}); err != nil {
+			return nil, err
+		}
+		tablesNames, identifiers, joins, queryParams, jsonPaths = analysis.Tables, analysis.Identifiers, analysis.Joins, analysis.QueryParams(), analysis.JSONColumns()
+		log.Debug().Interface("identifiers", identifiers).Interface("joins", joins).Interface("queryParams", queryParams).Interface("jsonPaths", jsonPaths).Msg("query parsed")
 	}
 	// if --table is given, we will restrict inserts to this table only
 	// we will still skip some columns and potentially have virtual FKs
@@ -77,6 +280,20 @@ func (cmd *RunCmd) Run() error {
 	}
 
 	frequency.DefaultNullFrequency = cmd.NullFreq
+	generate.PartitionDistribution = cmd.PartitionDistribution
+	if err := generate.SetArrayLength(cmd.ArrayLength); err != nil {
+		return nil, errors.Wrap(err, "--array-length")
+	}
+	generate.ArrayNullFrequency, generate.ArrayUniqueElements = cmd.ArrayNullFreq, cmd.ArrayUniqueElements
+	if err := generate.SetGeoBox(cmd.GeoBox); err != nil {
+		return nil, errors.Wrap(err, "--geo-bbox")
+	}
+	if err := generate.SetGeoDistribution(cmd.GeoDistribution); err != nil {
+		return nil, errors.Wrap(err, "--geo-distribution")
+	}
+	if err := generate.SetGeoPolygonVertices(cmd.GeoPolygonVertices); err != nil {
+		return nil, errors.Wrap(err, "--geo-polygon-vertices")
+	}
 	log.Debug().Interface("freq-map", frequency.SharedTableFrequency).Msg("frequency maps parsed")
 	frequency.MergeQueryParameters(queryParams, cmd.QueryParamsFreq)
 	log.Debug().Interface("freq-map", frequency.SharedTableFrequency).Msg("merged query params into frequency map")
@@ -86,8 +303,13 @@ func (cmd *RunCmd) Run() error {
 	for tableKey := range tablesNames {
 		table, err := db.LoadTable(cmd.DB.Database, tableKey)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		// the query can name a table with and without its schema
+		if slices.Contains(tables, table) {
+			continue
+		}
+		table.ExplicitKeys = cmd.ExplicitKeys
 
 		if cmd.Query != "" && !cmd.NoSkipFields {
 			table.SkipBasedOnIdentifiers(identifiers)
@@ -95,23 +317,35 @@ func (cmd *RunCmd) Run() error {
 
 		tables = append(tables, table)
 	}
+	// the per-table settings name a table by schema.table, or by table when no other schema of the run has it
+	cmd.sharedNames = sharedTableNames(tables)
+	for _, table := range tables {
+		cmd.applyNullHints(table)
+	}
+	if err := cmd.checkTriggers(tables); err != nil {
+		return nil, err
+	}
+	if err := cmd.parseHierarchies(tables); err != nil {
+		return nil, err
+	}
+	if err := cmd.buildJSONSchemas(tables, jsonPaths); err != nil {
+		return nil, err
+	}
 	// now we have the full table list, we check for any loops
 	for _, table := range tables {
 		copiedTable, err := table.IdentifyAndResolveSelfReferencingConstraintLoop()
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if copiedTable != nil {
-			rows, ok := cmd.RowsPerTable[table.Name]
-			if !ok {
-				rows = cmd.Rows
+			rows := valueForTable(cmd.Rows, cmd.RowsPerTable, cmd.tableKeys(table))
+			if h := cmd.hierarchyFor(table); h != nil {
+				log.Info().Str("table", table.Name).Str("hierarchy", h.String()).Int64("roots", h.RootCount(rows)).Msg("table has a self-referencing foreign key. Inserting the roots first, then the hierarchy level by level.")
+			} else {
+				log.Info().Str("table", table.Name).Int64("rows", rows/2).Msg("table has a self-referencing foreign key. Setting --rows to half for this table since we will insert twice to it to resolve the dependency.")
+				cmd.RowsPerTable[table.FullName()] = rows / 2
 			}
-			log.Info().Str("table", table.Name).Int64("rows", rows/2).Msg("table has a self-referencing foreign key. Setting --rows to half for this table since we will insert twice to it to resolve the dependency.")
-			cmd.RowsPerTable[table.Name] = rows / 2
 			tables = append([]*db.Table{copiedTable}, tables...)
-
-		} else if table.HasAnyConstraintLoop() {
-			return errors.Errorf("table %s has a foreign key loop", table.Name)
 		}
 	}
 
@@ -124,6 +358,10 @@ func (cmd *RunCmd) Run() error {
 	for _, table := range tables {
 		table.FlagConstraintThatArePartsOfThisRun(tables)
 	}
+	// loops between tables are broken by leaving a foreign key NULL, it is updated after the inserts
+	if err := db.BreakConstraintLoops(tables); err != nil {
+		return nil, err
+	}
 	// so that we can sort based on the dependencies we need to satisfy
 	tablesSorted := db.SortTables(tables)
 
@@ -131,27 +369,75 @@ func (cmd *RunCmd) Run() error {
 		log.Debug().Str("table", table.Name).Int("number of constraint", len(table.Constraints)).Msg("tables sorted")
 	}
 
-	// one at a time.
-	// Parallelizing here will complexify the foreign links, for probably not so much gain
+	// unique indexes are checked before inserting anything, a table inserted twice shares its unique values
+	rowsPerTable := cmd.totalRows(tablesSorted)
 	for _, table := range tablesSorted {
-		err = cmd.run(table)
-		if err != nil {
-			// if FK fails on mysql, it could be due to an extra foreign keys even though the referenced table do not exist
-			if cmd.DB.Engine == "mysql" && strings.Contains(err.Error(), "Error 1452") {
-				helperForMySQLFKChecks(tablesSorted, err)
+		if rows, ok := rowsPerTable[table.FullName()]; ok {
+			if err := generate.PrepareUniqueness(table, rows, cmd.MaxTextSize); err != nil {
+				return nil, err
 			}
-			return errors.Wrapf(err, "failed to insert on %s.%s", table.Schema, table.Name)
+			delete(rowsPerTable, table.FullName())
 		}
 	}
 
-	return err
+	// so are the CHECK constraints the generators cannot honour, the database will likely reject some rows
+	warned := map[string]bool{}
+	for _, table := range tablesSorted {
+		if warned[table.FullName()] {
+			continue
+		}
+		warned[table.FullName()] = true
+		ins := generate.New(table, cmd.ForeignKeyLinks, cmd.WorkersCount, cmd.MaxTextSize, cmd.UUIDVersion, frequency.ForTable(cmd.tableKeys(table)...))
+		for _, status := range ins.CheckStatuses() {
+			if !status.Enforced {
+				log.Warn().Str("table", table.FullName()).Str("check", status.Name).Str("clause", status.Clause).Str("reason", status.Reason).Msg("CHECK constraint will not be enforced when generating values")
+			}
+		}
+		if status := ins.PartitionStatus(); status != nil && !status.Supported && status.Method != db.PartitionHash {
+			log.Warn().Str("table", table.FullName()).Str("partition key", status.Expression).Str("reason", status.Reason).Msg("partition key values are not chosen to fit the partitions, inserts could fail")
+		}
+		for _, status := range ins.HintStatuses() {
+			if !status.Applied {
+				log.Warn().Str("table", table.FullName()).Str("column", status.Column).Str("annotation", status.Annotation).Str("reason", status.Reason).Msg("hint of the column comment is ignored")
+			}
+		}
+	}
+
+	return tablesSorted, nil
+}
+
+// applyNullHints merges the null() hints of the comments into the frequencies, a column hint has priority over the table one
+func (cmd *RunCmd) applyNullHints(table *db.Table) {
+	for _, field := range table.Fields {
+		if !field.IsNullable {
+			continue
+		}
+		switch {
+		case field.Hints != nil && field.Hints.HasNull:
+			frequency.MergeNullHint(cmd.tableKeys(table), field.ColumnName, field.Hints.Null)
+		case table.Hints != nil && table.Hints.HasNull:
+			frequency.MergeNullHint(cmd.tableKeys(table), field.ColumnName, table.Hints.Null)
+		}
+	}
 }
 
 func (cmd *RunCmd) run(table *db.Table) error {
-	rows := valueForTable(cmd.Rows, cmd.RowsPerTable, table.Name)
-	colNullFreqs := frequency.SharedTableFrequency[table.Name]
+	rows := valueForTable(cmd.Rows, cmd.RowsPerTable, cmd.tableKeys(table))
+	colNullFreqs := frequency.ForTable(cmd.tableKeys(table)...)
 	ins := generate.New(table, cmd.ForeignKeyLinks, cmd.WorkersCount, cmd.MaxTextSize, cmd.UUIDVersion, colNullFreqs)
 
+	h := cmd.hierarchyFor(table)
+	roots := int64(0)
+	if h != nil {
+		roots = h.RootCount(rows)
+		// the first pass, without the self-referencing foreign key, inserts the roots
+		if !isSelfReferencing(table) {
+			rows, h = roots, nil
+		} else {
+			rows -= roots
+		}
+	}
+
 	if !cmd.Quiet && !cmd.DryRun {
 		go startProgressBar(table.Name, rows, ins.NotifyChan)
 	}
@@ -160,11 +446,122 @@ func (cmd *RunCmd) run(table *db.Table) error {
 		return ins.DryRun(rows, cmd.BulkSize)
 	}
 
-	err := ins.Run(rows, cmd.BulkSize)
+	var err error
+	if h != nil {
+		err = ins.RunLevels(h, roots, rows, cmd.BulkSize)
+	} else {
+		err = ins.Run(rows, cmd.BulkSize)
+	}
 	close(ins.NotifyChan)
 	return err
 }
 
+// parseHierarchies reads --hierarchy, every table must be part of the run with a self-referencing foreign key on the column
+func (cmd *RunCmd) parseHierarchies(tables []*db.Table) error {
+	cmd.hierarchies = map[string]*generate.Hierarchy{}
+	for key, spec := range cmd.Hierarchy {
+		idx := strings.LastIndex(key, ".")
+		if idx < 0 {
+			return errors.Errorf("--hierarchy key %s should be table.column", key)
+		}
+		table, err := cmd.findTable(tables, key[:idx])
+		if err != nil {
+			return errors.Wrap(err, "--hierarchy")
+		}
+		column := key[idx+1:]
+		if _, err := generate.HierarchyConstraint(table, column); err != nil {
+			return errors.Wrap(err, "--hierarchy")
+		}
+		h, err := generate.ParseHierarchy(column, spec)
+		if err != nil {
+			return errors.Wrapf(err, "--hierarchy %s", key)
+		}
+		cmd.hierarchies[strings.ToLower(table.FullName())] = h
+	}
+	return nil
+}
+
+func (cmd *RunCmd) hierarchyFor(table *db.Table) *generate.Hierarchy {
+	return cmd.hierarchies[strings.ToLower(table.FullName())]
+}
+
+// buildJSONSchemas reads --json-schema, the other JSON columns read by the query get a schema holding the keys it reads
+func (cmd *RunCmd) buildJSONSchemas(tables []*db.Table, jsonPaths map[string][]query.JSONPath) error {
+	generate.JSONSchemas = map[string]*generate.JSONSchema{}
+	for key, filename := range cmd.JSONSchema {
+		table, field, err := cmd.jsonColumn(tables, key)
+		if err != nil {
+			return errors.Wrap(err, "--json-schema")
+		}
+		schema, err := generate.LoadJSONSchema(filename)
+		if err != nil {
+			return errors.Wrap(err, "--json-schema")
+		}
+		schema.Source = "--json-schema " + filename
+		generate.SetJSONSchema(table, field.ColumnName, schema)
+	}
+
+	for key, paths := range jsonPaths {
+		table, field, err := cmd.jsonColumn(tables, key)
+		if err != nil {
+			log.Warn().Err(err).Msg("the keys read by the query are ignored")
+			continue
+		}
+		if generate.ColumnJSONSchema(table, field.ColumnName) != nil {
+			// the file has the full control
+			continue
+		}
+		schema := &generate.JSONSchema{}
+		read := []string{}
+		for _, path := range paths {
+			if err := schema.AddPath(path.Path, path.Type); err != nil {
+				log.Warn().Err(err).Str("column", key).Msg("the key read by the query is ignored")
+				continue
+			}
+			if !slices.Contains(read, path.Path) {
+				read = append(read, path.Path)
+			}
+		}
+		schema.Source = "the query keys " + strings.Join(read, " ")
+		generate.SetJSONSchema(table, field.ColumnName, schema)
+	}
+	return nil
+}
+
+// jsonColumn finds the JSON column of table.column, the table being part of the run
+func (cmd *RunCmd) jsonColumn(tables []*db.Table, key string) (*db.Table, db.Field, error) {
+	idx := strings.LastIndex(key, ".")
+	if idx < 0 {
+		return nil, db.Field{}, errors.Errorf("key %s should be table.column", key)
+	}
+	table, err := cmd.findTable(tables, key[:idx])
+	if err != nil {
+		return nil, db.Field{}, err
+	}
+	column := key[idx+1:]
+	fieldIdx := slices.IndexFunc(table.Fields, func(f db.Field) bool { return strings.EqualFold(f.ColumnName, column) })
+	if fieldIdx < 0 || table.Fields[fieldIdx].DataType != "json" {
+		return nil, db.Field{}, errors.Errorf("%s is not a JSON column", key)
+	}
+	return table, table.Fields[fieldIdx], nil
+}
+
+func isSelfReferencing(table *db.Table) bool {
+	return slices.ContainsFunc(table.Constraints, func(c *db.Constraint) bool { return c.References(table) })
+}
+
+// totalRows returns the rows inserted per table. A self-referencing table inserted twice counts both passes, or its rows once when they are split into a hierarchy
+func (cmd *RunCmd) totalRows(tables []*db.Table) map[string]int64 {
+	rowsPerTable := map[string]int64{}
+	for _, table := range tables {
+		if _, ok := rowsPerTable[table.FullName()]; ok && cmd.hierarchyFor(table) != nil {
+			continue
+		}
+		rowsPerTable[table.FullName()] += valueForTable(cmd.Rows, cmd.RowsPerTable, cmd.tableKeys(table))
+	}
+	return rowsPerTable
+}
+
 func startProgressBar(tablename string, total int64, c chan int64) {
 	writer := goterminal.New(os.Stdout)
 	var count int64
@@ -177,13 +574,56 @@ func startProgressBar(tablename string, total int64, c chan int64) {
 	writer.Reset()
 }
 
-func valueForTable[E any](val E, valPerTable map[string]E, table string) E {
-	if v, ok := valPerTable[table]; ok {
-		return v
+// valueForTable returns the value set for the first key of the table found in valPerTable, val otherwise
+func valueForTable[E any](val E, valPerTable map[string]E, keys []string) E {
+	for _, key := range keys {
+		if v, ok := valPerTable[key]; ok {
+			return v
+		}
+		for table, v := range valPerTable {
+			if strings.EqualFold(table, key) {
+				return v
+			}
+		}
 	}
 	return val
 }
 
+// sharedTableNames returns the names of the tables found in several schemas of the run
+func sharedTableNames(tables []*db.Table) map[string]bool {
+	schemas := map[string]string{}
+	shared := map[string]bool{}
+	for _, t := range tables {
+		name := strings.ToLower(t.Name)
+		if schema, ok := schemas[name]; ok && !strings.EqualFold(schema, t.Schema) {
+			shared[name] = true
+		}
+		schemas[name] = t.Schema
+	}
+	return shared
+}
+
+// tableKeys returns the keys naming the table in the per-table settings: schema.table, then table unless another schema of the run has it
+func (cmd *RunCmd) tableKeys(table *db.Table) []string {
+	if cmd.sharedNames[strings.ToLower(table.Name)] {
+		return []string{table.FullName()}
+	}
+	return []string{table.FullName(), table.Name}
+}
+
+// findTable returns the table of the run named schema.table, or table when no other schema of the run has it
+func (cmd *RunCmd) findTable(tables []*db.Table, name string) (*db.Table, error) {
+	if cmd.sharedNames[strings.ToLower(name)] {
+		return nil, errors.Errorf("table %s is found in several schemas of this run, qualify it with its schema", name)
+	}
+	for _, t := range tables {
+		if strings.EqualFold(t.FullName(), name) || strings.EqualFold(t.Name, name) {
+			return t, nil
+		}
+	}
+	return nil, errors.Errorf("table %s is not part of this run", name)
+}
+
 func helperForMySQLFKChecks(tablesSorted []*db.Table, err error) {
 
 	// getting the table provoking the issue from the deepest error
@@ -0,0 +1,895 @@
+package cmd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ylacancellera/random-data-load/db"
+	"github.com/ylacancellera/random-data-load/db/memdb"
+	"github.com/ylacancellera/random-data-load/frequency"
+	"github.com/ylacancellera/random-data-load/generate"
+	"github.com/ylacancellera/random-data-load/query"
+)
+
+func newTestRun(t *testing.T, rows int64) (*RunCmd, *memdb.Database) {
+	d := memdb.New(t.Name())
+	db.Register(db.EngineInfo{Name: "memory", Dialect: query.DialectPostgres, Engine: d})
+	frequency.SharedTableFrequency = map[string]frequency.ColumnFrequency{}
+
+	return &RunCmd{
+		DB:                 db.Config{Engine: "memory", Database: t.Name()},
+		Rows:               rows,
+		RowsPerTable:       map[string]int64{},
+		BulkSize:           10,
+		Quiet:              true,
+		WorkersCount:       2,
+		Triggers:           db.TriggersKeep,
+		MaxTextSize:        20,
+		UUIDVersion:        4,
+		ArrayLength:        generate.DefaultArrayLength,
+		GeoBox:             "-180,-90,180,90",
+		GeoDistribution:    "uniform",
+		GeoPolygonVertices: generate.DefaultGeoPolygonVertices,
+		ForeignKeyLinks: generate.ForeignKeyLinks{
+			DefaultRelationship: generate.BinomialFlag,
+			CoinFlipPercent:     50,
+		},
+	}, d
+}
+
+func parentChild(d *memdb.Database, childFK bool) {
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "name", DataType: "varchar", IsNullable: true, CharacterMaximumLength: nullInt(20)},
+	})
+	constraints := []db.Constraint{}
+	if childFK {
+		constraints = append(constraints, db.Constraint{ConstraintName: "fk_t1", ColumnsName: []string{"t1_id"}, ReferencedTableName: "t1", ReferencedColumnsName: []string{"id"}})
+	}
+	d.CreateTable("", "t2", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "t1_id", DataType: "int"},
+	}, constraints...)
+}
+
+func nullInt(i int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: i, Valid: true}
+}
+
+func mustRows(t *testing.T, d *memdb.Database, table string) []memdb.Row {
+	rows, err := d.Rows("", table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func assertReferences(t *testing.T, d *memdb.Database) {
+	parents := map[any]struct{}{}
+	for _, row := range mustRows(t, d, "t1") {
+		parents[row["id"]] = struct{}{}
+	}
+	children := mustRows(t, d, "t2")
+	if len(children) == 0 {
+		t.Fatal("expected rows in t2")
+	}
+	for _, row := range children {
+		if _, ok := parents[row["t1_id"]]; !ok {
+			t.Fatalf("t2.t1_id=%v does not exist in t1", row["t1_id"])
+		}
+	}
+}
+
+func TestRunForeignKey(t *testing.T) {
+	cmd, d := newTestRun(t, 50)
+	parentChild(d, true)
+	cmd.Query = "select * from t1 join t2 on t1.id = t2.t1_id"
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(mustRows(t, d, "t1")); n != 50 {
+		t.Fatalf("expected 50 rows in t1, got %d", n)
+	}
+	assertReferences(t, d)
+}
+
+func TestRunVirtualForeignKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		addFK query.VirtualJoins
+	}{
+		{name: "guessed from the query", query: "select t1.name from t1 join t2 on t1.id = t2.t1_id"},
+		{name: "added with --add-fk", query: "select t1.name from t1, t2", addFK: query.VirtualJoins{{
+			Left:  query.VirtualJoinPart{Table: "t1", Columns: []string{"id"}},
+			Right: query.VirtualJoinPart{Table: "t2", Columns: []string{"t1_id"}},
+		}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, d := newTestRun(t, 30)
+			parentChild(d, false)
+			cmd.Query = test.query
+			cmd.AddForeignKeys = test.addFK
+			if test.addFK != nil {
+				cmd.NoFKGuess = true
+			}
+
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+			assertReferences(t, d)
+		})
+	}
+}
+
+func TestRunView(t *testing.T) {
+	cmd, d := newTestRun(t, 30)
+	parentChild(d, false)
+	d.CreateView("", "v", "select t2.id, t2.t1_id, t1.name as parent_name from t1 join t2 on t1.id = t2.t1_id")
+	d.CreateView("", "v2", "select v.id, v.parent_name from v")
+	cmd.Query = "select id from v2 where parent_name = 'fixed'"
+	cmd.QueryParamsFreq = 1
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	assertReferences(t, d)
+	for _, row := range mustRows(t, d, "t1") {
+		if row["name"] != "fixed" {
+			t.Fatalf("expected the parameter on the view to be injected in t1.name, got %v", row["name"])
+		}
+	}
+}
+
+func TestRunSchemaQualified(t *testing.T) {
+	cmd, d := newTestRun(t, 30)
+	parentChild(d, false)
+	d.CreateTable("crm", "t1", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+	})
+	cmd.Query = "select * from crm.t1 join t2 on t1.id = t2.t1_id"
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	crm, err := d.Rows("crm", "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parents := map[any]struct{}{}
+	for _, row := range crm {
+		parents[row["id"]] = struct{}{}
+	}
+	for _, row := range mustRows(t, d, "t2") {
+		if _, ok := parents[row["t1_id"]]; !ok {
+			t.Fatalf("t2.t1_id=%v does not exist in crm.t1", row["t1_id"])
+		}
+	}
+	if n := len(mustRows(t, d, "t1")); n != 0 {
+		t.Fatalf("expected public.t1 to be left empty, got %d rows", n)
+	}
+}
+
+func TestRunSameNameInTwoSchemas(t *testing.T) {
+	cmd, d := newTestRun(t, 30)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "name", DataType: "varchar", CharacterMaximumLength: nullInt(20)},
+	})
+	// not a self-referencing foreign key, the non-nullable column would be refused
+	d.CreateTable("crm", "t1", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "name", DataType: "varchar", CharacterMaximumLength: nullInt(20)},
+		{ColumnName: "t1_id", DataType: "int"},
+	}, db.Constraint{ConstraintName: "fk_public", ColumnsName: []string{"t1_id"}, ReferencedTableSchema: "public", ReferencedTableName: "t1", ReferencedColumnsName: []string{"id"}})
+	cmd.Query = "select * from crm.t1 c join public.t1 p on p.id = c.t1_id where c.name = 'crm' and p.name = 'public'"
+	cmd.QueryParamsFreq = 1
+	cmd.RowsPerTable = map[string]int64{"crm.t1": 10}
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	parents := map[any]struct{}{}
+	for _, row := range mustRows(t, d, "t1") {
+		if row["name"] != "public" {
+			t.Fatalf("expected public.t1.name to be 'public', got %v", row["name"])
+		}
+		parents[row["id"]] = struct{}{}
+	}
+	if len(parents) != 30 {
+		t.Fatalf("expected 30 rows in public.t1, got %d", len(parents))
+	}
+	crm, err := d.Rows("crm", "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crm) != 10 {
+		t.Fatalf("expected 10 rows in crm.t1, got %d", len(crm))
+	}
+	for _, row := range crm {
+		if row["name"] != "crm" {
+			t.Fatalf("expected crm.t1.name to be 'crm', got %v", row["name"])
+		}
+		if _, ok := parents[row["t1_id"]]; !ok {
+			t.Fatalf("crm.t1.t1_id=%v does not exist in public.t1", row["t1_id"])
+		}
+	}
+
+	// the bare name matches both tables
+	cmd.Hierarchy = map[string]string{"t1.t1_id": "depth:2"}
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "several schemas") {
+		t.Fatalf("expected an ambiguous table error, got %v", err)
+	}
+}
+
+func TestRunVirtualForeignKeyAfterSelfReference(t *testing.T) {
+	cmd, d := newTestRun(t, 20)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "parent_id", DataType: "int", IsNullable: true},
+	}, db.Constraint{ConstraintName: "fk_parent", ColumnsName: []string{"parent_id"}, ReferencedTableName: "t1", ReferencedColumnsName: []string{"id"}})
+	d.CreateTable("", "t2", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "t1_id", DataType: "int"},
+	}, db.Constraint{ConstraintName: "fk_t1", ColumnsName: []string{"t1_id"}, ReferencedTableName: "t1", ReferencedColumnsName: []string{"id"}})
+	d.CreateTable("", "t3", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "t2_id", DataType: "int"},
+	})
+	// the self-referencing t1 upstream of t2 is not a loop, the virtual foreign key stays t3.t2_id -> t2.id
+	cmd.Query = "select * from t1 join t2 on t1.id = t2.t1_id join t3 on t2.id = t3.t2_id"
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	parents := map[any]struct{}{}
+	for _, row := range mustRows(t, d, "t2") {
+		parents[row["id"]] = struct{}{}
+	}
+	children := mustRows(t, d, "t3")
+	if len(children) != 20 {
+		t.Fatalf("expected 20 rows in t3, got %d", len(children))
+	}
+	for _, row := range children {
+		if _, ok := parents[row["t2_id"]]; !ok {
+			t.Fatalf("t3.t2_id=%v does not exist in t2", row["t2_id"])
+		}
+	}
+}
+
+func TestCheckTriggers(t *testing.T) {
+	tables := []*db.Table{
+		{Schema: "public", Name: "t1"},
+		{Schema: "public", Name: "t2", Triggers: []db.Trigger{{Name: "t2_audit", Timing: "AFTER", Events: []string{"INSERT"}, Writes: []string{"audit"}}}},
+	}
+	tests := []struct {
+		triggers string
+		err      bool
+	}{
+		{triggers: db.TriggersKeep},
+		{triggers: db.TriggersDisable},
+		{triggers: db.TriggersFail, err: true},
+	}
+	for _, test := range tests {
+		cmd := &RunCmd{Triggers: test.triggers}
+		err := cmd.checkTriggers(tables)
+		if test.err != (err != nil) {
+			t.Errorf("%s: expected error: %v, got %v", test.triggers, test.err, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "t2_audit") {
+			t.Errorf("%s: expected the error to name the trigger, got %v", test.triggers, err)
+		}
+	}
+}
+
+func TestRunExplicitKeys(t *testing.T) {
+	cmd, d := newTestRun(t, 20)
+	parentChild(d, true)
+	cmd.Query = "select * from t1 join t2 on t1.id = t2.t1_id"
+	cmd.ExplicitKeys = true
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	// memdb rejects explicit keys inserted without OVERRIDING SYSTEM VALUE
+	assertReferences(t, d)
+}
+
+func TestRunFrequencies(t *testing.T) {
+	cmd, d := newTestRun(t, 40)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "always_null", DataType: "int", IsNullable: true},
+		{ColumnName: "injected", DataType: "varchar", CharacterMaximumLength: nullInt(10)},
+		{ColumnName: "never_null", DataType: "int", IsNullable: true},
+	})
+	cmd.Table = "t1"
+	cmd.NullFreq = 0
+	frequency.SharedTableFrequency["t1"] = frequency.ColumnFrequency{
+		"always_null": {Null: 1},
+		"injected":    {IndexValues: []string{"fixed"}, IndexFrequencies: []float64{1}},
+	}
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	rows := mustRows(t, d, "t1")
+	if len(rows) != 40 {
+		t.Fatalf("expected 40 rows, got %d", len(rows))
+	}
+	for _, row := range rows {
+		if row["always_null"] != nil {
+			t.Fatalf("expected always_null to be NULL, got %v", row["always_null"])
+		}
+		if row["injected"] != "fixed" {
+			t.Fatalf("expected injected to be 'fixed', got %v", row["injected"])
+		}
+		if row["never_null"] == nil {
+			t.Fatal("expected never_null to be generated with --null-freq=0")
+		}
+	}
+}
+
+func TestRunHints(t *testing.T) {
+	cmd, d := newTestRun(t, 200)
+	table := d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "status", DataType: "varchar", CharacterMaximumLength: nullInt(10), IsNullable: true, Comment: "status of the order rdl: values(new,'done, paid') null(0)"},
+		{ColumnName: "qty", DataType: "int", Comment: "rdl: zipf(2) range(10,20)"},
+		{ColumnName: "price", DataType: "decimal", NumericPrecision: nullInt(6), NumericScale: nullInt(2), Comment: "rdl: range(1,5)"},
+		{ColumnName: "email", DataType: "varchar", CharacterMaximumLength: nullInt(100), Comment: "rdl: gen(email) bogus(1)"},
+		{ColumnName: "note", DataType: "int", IsNullable: true},
+		{ColumnName: "cli", DataType: "int", IsNullable: true, Comment: "rdl: null(0)"},
+		{ColumnName: "label", DataType: "int", Comment: "rdl: gen(email)"},
+	})
+	table.Comment = "rdl: null(1)"
+	cmd.Table = "t1"
+	cmd.NullFreq = 0
+	cmd.MaxTextSize = 100
+	frequency.SharedTableFrequency["t1"] = frequency.ColumnFrequency{
+		"cli": {Null: 1, HasNull: true},
+	}
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	qtys := map[int]int{}
+	for _, row := range mustRows(t, d, "t1") {
+		qty, _ := strconv.Atoi(row["qty"].(string))
+		price, _ := strconv.ParseFloat(row["price"].(string), 64)
+		qtys[qty]++
+		switch {
+		case row["status"] != "new" && row["status"] != "done, paid":
+			t.Fatalf("status %v is not in values()", row["status"])
+		case qty < 10 || qty > 20:
+			t.Fatalf("qty %d is out of range()", qty)
+		case price < 1 || price > 5:
+			t.Fatalf("price %v is out of range()", row["price"])
+		case !strings.Contains(row["email"].(string), "@"):
+			t.Fatalf("email %v was not generated by gen(email)", row["email"])
+		case row["note"] != nil:
+			t.Fatalf("expected the null() of the table to apply to note, got %v", row["note"])
+		case row["cli"] != nil:
+			t.Fatalf("expected --null-freq-map to have priority over the hint, got %v", row["cli"])
+		}
+	}
+	if qtys[10] <= qtys[11] || qtys[11] <= qtys[15] {
+		t.Errorf("expected zipf() to favour the lowest values, got %v", qtys)
+	}
+
+	loaded, err := db.LoadTable(cmd.DB.Database, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := map[string]bool{}
+	for _, status := range generate.New(loaded, cmd.ForeignKeyLinks, 1, cmd.MaxTextSize, 4, nil).HintStatuses() {
+		applied[status.Column] = status.Applied
+	}
+	if !applied["status"] || !applied["qty"] || !applied["price"] || !applied["email"] || applied["label"] {
+		t.Errorf("unexpected hint statuses %v", applied)
+	}
+}
+
+func TestRunUserDefinedTypes(t *testing.T) {
+	cmd, d := newTestRun(t, 50)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "mood", DataType: "enum", SetEnumVals: []string{"sad", "ok", "happy"}},
+		{ColumnName: "item", DataType: "composite", Attributes: []db.Field{
+			{ColumnName: "price", DataType: "decimal", NumericPrecision: nullInt(5), NumericScale: nullInt(2)},
+			{ColumnName: "name", DataType: "varchar", CharacterMaximumLength: nullInt(10)},
+			{ColumnName: "mood", DataType: "enum", SetEnumVals: []string{"sad", "ok"}},
+			{ColumnName: "geom", DataType: "USER-DEFINED"},
+		}},
+	})
+	cmd.Table = "t1"
+	cmd.NullFreq = 0
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	itemRe := regexp.MustCompile(`^\("[0-9.]+","[^"]{0,10}","(sad|ok)",\)$`)
+	for _, row := range mustRows(t, d, "t1") {
+		if !slices.Contains([]string{"sad", "ok", "happy"}, row["mood"].(string)) {
+			t.Fatalf("mood %v is not a label of the enum", row["mood"])
+		}
+		if !itemRe.MatchString(row["item"].(string)) {
+			t.Fatalf("item %v is not a row literal of the composite type", row["item"])
+		}
+	}
+}
+
+func TestRunArrays(t *testing.T) {
+	cmd, d := newTestRun(t, 50)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "ids", DataType: "array", Element: &db.Field{ColumnName: "ids", DataType: "int", IsNullable: true}},
+		{ColumnName: "moods", DataType: "array", Element: &db.Field{ColumnName: "moods", DataType: "enum", IsNullable: true, SetEnumVals: []string{"sad", "ok"}}},
+		{ColumnName: "tags", DataType: "array", Element: &db.Field{ColumnName: "tags", DataType: "varchar", IsNullable: true, CharacterMaximumLength: nullInt(5)}},
+	})
+	cmd.Table = "t1"
+	cmd.NullFreq = 0
+	cmd.ArrayLength = "uniform(2,4)"
+	cmd.ArrayNullFreq = 0.2
+	cmd.ArrayUniqueElements = true
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	arrayRe := regexp.MustCompile(`^\{((NULL|"[^"]*")(,(NULL|"[^"]*"))*)?\}$`)
+	nulls := 0
+	for _, row := range mustRows(t, d, "t1") {
+		for _, column := range []string{"ids", "moods", "tags"} {
+			literal := row[column].(string)
+			if !arrayRe.MatchString(literal) {
+				t.Fatalf("%s %v is not an array literal", column, literal)
+			}
+			elements := strings.Split(strings.Trim(literal, "{}"), ",")
+			distinct := map[string]bool{}
+			for _, element := range elements {
+				distinct[element] = true
+				if element == "NULL" {
+					nulls++
+				}
+			}
+			switch {
+			case len(distinct) != len(elements):
+				t.Fatalf("%s %v repeats an element", column, literal)
+			case column != "moods" && (len(elements) < 2 || len(elements) > 4):
+				t.Fatalf("%s %v does not have 2 to 4 elements", column, literal)
+			case column == "tags" && len(literal) > 2+len(elements)*8:
+				t.Fatalf("tags %v has elements longer than varchar(5)", literal)
+			}
+		}
+	}
+	if nulls == 0 {
+		t.Error("expected NULL elements with --array-null-freq")
+	}
+}
+
+func TestRunPostgresTypes(t *testing.T) {
+	cmd, d := newTestRun(t, 60)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "net", DataType: "cidr"},
+		{ColumnName: "mac", DataType: "macaddr"},
+		{ColumnName: "wait", DataType: "interval"},
+		{ColumnName: "price", DataType: "money"},
+		{ColumnName: "raw", DataType: "bytea"},
+		{ColumnName: "flags", DataType: "bitstring", CharacterMaximumLength: nullInt(4)},
+		{ColumnName: "mask", DataType: "varbit", CharacterMaximumLength: nullInt(3)},
+		{ColumnName: "stay", DataType: "daterange"},
+		{ColumnName: "slot", DataType: "int4range"},
+		{ColumnName: "doc", DataType: "xml"},
+		{ColumnName: "words", DataType: "tsvector"},
+	})
+	cmd.Table = "t1"
+	cmd.MaxTextSize = 8
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	formats := map[string]*regexp.Regexp{
+		"net":   regexp.MustCompile(`^(\d+\.){3}\d+/\d+$`),
+		"mac":   regexp.MustCompile(`^([0-9a-f]{2}:){5}[0-9a-f]{2}$`),
+		"wait":  regexp.MustCompile(`^\d+ days \d\d:\d\d:\d\d$`),
+		"price": regexp.MustCompile(`^\d+\.\d\d$`),
+		"raw":   regexp.MustCompile(`^\\x([0-9a-f]{2}){1,8}$`),
+		"flags": regexp.MustCompile(`^[01]{4}$`),
+		"mask":  regexp.MustCompile(`^[01]{1,3}$`),
+		"stay":  regexp.MustCompile(`^\[\d{4}-\d\d-\d\d,\d{4}-\d\d-\d\d\)$`),
+		"doc":   regexp.MustCompile(`^<item><id>\d+</id><name>[^<]*</name></item>$`),
+		"words": regexp.MustCompile(`^[^']+$`),
+	}
+	for _, row := range mustRows(t, d, "t1") {
+		for column, format := range formats {
+			if !format.MatchString(row[column].(string)) {
+				t.Fatalf("%s %v does not match %s", column, row[column], format)
+			}
+		}
+		var slot [2]int
+		if _, err := fmt.Sscanf(row["slot"].(string), "[%d,%d)", &slot[0], &slot[1]); err != nil || slot[0] >= slot[1] {
+			t.Fatalf("slot %v is not a non-empty range", row["slot"])
+		}
+	}
+}
+
+func TestRunMySQLTypes(t *testing.T) {
+	cmd, d := newTestRun(t, 300)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "signed", DataType: "tinyint", FullIntRange: true},
+		{ColumnName: "unsigned", DataType: "tinyint", Unsigned: true, FullIntRange: true},
+		{ColumnName: "flag", DataType: "tinyint", DisplayWidth: 1, FullIntRange: true},
+		{ColumnName: "bits", DataType: "bit", NumericPrecision: nullInt(3)},
+		{ColumnName: "tags", DataType: "set", SetEnumVals: []string{"a", "b", "c"}},
+	})
+	cmd.Table = "t1"
+	cmd.NullFreq = 0
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	bitsRe := regexp.MustCompile(`^b'[01]{3}'$`)
+	tagsRe := regexp.MustCompile(`^a?,?b?,?c?$`)
+	negative, aboveSigned, multiMembers := false, false, false
+	for _, row := range mustRows(t, d, "t1") {
+		signed, _ := strconv.Atoi(row["signed"].(string))
+		unsigned, _ := strconv.Atoi(row["unsigned"].(string))
+		switch {
+		case signed < -128 || signed > 127:
+			t.Fatalf("signed %d is out of the tinyint range", signed)
+		case unsigned < 0 || unsigned > 255:
+			t.Fatalf("unsigned %d is out of the tinyint unsigned range", unsigned)
+		case row["flag"] != "0" && row["flag"] != "1":
+			t.Fatalf("flag %v is not a boolean", row["flag"])
+		case !bitsRe.MatchString(row["bits"].(string)):
+			t.Fatalf("bits %v is not a bit-value literal", row["bits"])
+		case row["tags"] == "" || !tagsRe.MatchString(row["tags"].(string)):
+			t.Fatalf("tags %v is not a set of a, b and c", row["tags"])
+		}
+		negative = negative || signed < 0
+		aboveSigned = aboveSigned || unsigned > 127
+		multiMembers = multiMembers || strings.Contains(row["tags"].(string), ",")
+	}
+	if !negative || !aboveSigned || !multiMembers {
+		t.Errorf("expected negative tinyints, unsigned ones above 127 and sets of many members, got %v, %v and %v", negative, aboveSigned, multiMembers)
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	cmd, d := newTestRun(t, 40)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "doc", DataType: "json"},
+		{ColumnName: "profile", DataType: "json"},
+	})
+	schema := filepath.Join(t.TempDir(), "profile.json")
+	err := os.WriteFile(schema, []byte(`{
+		"type": "object",
+		"required": ["age", "plan", "emails"],
+		"properties": {
+			"age": {"type": "integer", "minimum": 18, "maximum": 30},
+			"plan": {"enum": ["free", "pro"]},
+			"emails": {"type": "array", "minItems": 1, "maxItems": 2, "items": {"type": "string", "format": "email"}}
+		}
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd.Query = `select id, profile from t1 where doc->>'status' = 'paid' and doc #>> '{customer,id}' = '42' and doc @> '{"tags": ["web"]}' and (doc->'total')::numeric > 10`
+	cmd.JSONSchema = map[string]string{"t1.profile": schema}
+	cmd.QueryParamsFreq = 1
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range mustRows(t, d, "t1") {
+		var doc struct {
+			Status   string
+			Customer struct{ ID int }
+			Tags     []string
+			Total    *float64
+		}
+		if err := json.Unmarshal([]byte(row["doc"].(string)), &doc); err != nil {
+			t.Fatalf("doc %v is not a JSON document: %v", row["doc"], err)
+		}
+		if doc.Status != "paid" || doc.Customer.ID != 42 || len(doc.Tags) == 0 || doc.Tags[0] != "web" || doc.Total == nil {
+			t.Fatalf("doc %v does not hold the keys and values of the query", row["doc"])
+		}
+
+		var profile struct {
+			Age    int
+			Plan   string
+			Emails []string
+		}
+		if err := json.Unmarshal([]byte(row["profile"].(string)), &profile); err != nil {
+			t.Fatalf("profile %v is not a JSON document: %v", row["profile"], err)
+		}
+		if profile.Age < 18 || profile.Age > 30 || !slices.Contains([]string{"free", "pro"}, profile.Plan) || len(profile.Emails) < 1 || len(profile.Emails) > 2 || !strings.Contains(profile.Emails[0], "@") {
+			t.Fatalf("profile %v does not follow the JSON Schema", row["profile"])
+		}
+	}
+}
+
+func TestRunSkipFields(t *testing.T) {
+	cmd, d := newTestRun(t, 20)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "used", DataType: "int"},
+		{ColumnName: "unused_nullable", DataType: "int", IsNullable: true},
+		{ColumnName: "unused_default", DataType: "int", HasDefaultValue: true},
+		{ColumnName: "unused_required", DataType: "int"},
+	})
+	cmd.Query = "select used from t1"
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range mustRows(t, d, "t1") {
+		if row["used"] == nil || row["unused_required"] == nil {
+			t.Fatalf("expected used and unused_required to be generated, got %v", row)
+		}
+		if row["unused_nullable"] != nil {
+			t.Fatalf("expected unused_nullable to be skipped, got %v", row["unused_nullable"])
+		}
+	}
+}
+
+func uniqueTable(d *memdb.Database) {
+	t := d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "id", DataType: "tinyint", ColumnKey: "PRI"},
+		{ColumnName: "code", DataType: "varchar", CharacterMaximumLength: nullInt(1)},
+		{ColumnName: "a", DataType: "tinyint"},
+		{ColumnName: "b", DataType: "enum", SetEnumVals: []string{"x", "y"}},
+	})
+	t.Indexes = []db.Index{
+		{Name: "uk_code", Columns: []string{"code"}},
+		{Name: "uk_a_b", Columns: []string{"a", "b"}},
+	}
+}
+
+func TestRunUniqueIndexes(t *testing.T) {
+	cmd, d := newTestRun(t, 36)
+	uniqueTable(d)
+	cmd.Table = "t1"
+
+	// memdb rejects duplicates, 36 rows exhaust the domain of code
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	ids, pairs := map[any]struct{}{}, map[string]struct{}{}
+	for _, row := range mustRows(t, d, "t1") {
+		ids[row["id"]] = struct{}{}
+		pairs[row["a"].(string)+","+row["b"].(string)] = struct{}{}
+	}
+	if len(ids) != 36 || len(pairs) != 36 {
+		t.Fatalf("expected 36 distinct ids and (a, b) pairs, got %d and %d", len(ids), len(pairs))
+	}
+}
+
+func TestRunUniqueDomainExceeded(t *testing.T) {
+	cmd, d := newTestRun(t, 37)
+	uniqueTable(d)
+	cmd.Table = "t1"
+
+	err := cmd.Run()
+	expected := "unique index uk_code on (code) only allows 36 distinct values"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected an error containing %q, got %v", expected, err)
+	}
+	if n := len(mustRows(t, d, "t1")); n != 0 {
+		t.Fatalf("expected nothing to be inserted, got %d rows", n)
+	}
+}
+
+func TestRunUniqueAfterExistingRows(t *testing.T) {
+	cmd, d := newTestRun(t, 0)
+	d.CreateTable("", "t1", []db.Field{{ColumnName: "id", DataType: "tinyint", ColumnKey: "PRI"}})
+	cmd.Table = "t1"
+
+	// ids continue after the highest one, until the 127 values of a tinyint are used
+	for _, rows := range []int64{100, 27} {
+		cmd.Rows = rows
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd.Rows = 1
+	err := cmd.Run()
+	expected := "unique index PRIMARY on (id) only allows 0 more distinct values"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected an error containing %q, got %v", expected, err)
+	}
+}
+
+func TestRunCheckConstraints(t *testing.T) {
+	cmd, d := newTestRun(t, 200)
+	table := d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "price", DataType: "decimal", NumericPrecision: nullInt(6), NumericScale: nullInt(2)},
+		{ColumnName: "status", DataType: "varchar", CharacterMaximumLength: nullInt(10)},
+		{ColumnName: "code", DataType: "varchar", CharacterMaximumLength: nullInt(10)},
+		{ColumnName: "started", DataType: "int"},
+		{ColumnName: "ended", DataType: "int"},
+		{ColumnName: "a", DataType: "int"},
+	})
+	table.Checks = []db.Check{
+		{Name: "price_positive", Clause: "(price > (0)::numeric AND price <= 100)"},
+		{Name: "status_values", Clause: "(`status` in (_utf8mb4'new',_utf8mb4'done'))"},
+		{Name: "code_length", Clause: "char_length(code) = 3"},
+		{Name: "ended_after_started", Clause: "ended >= started"},
+		{Name: "a_or", Clause: "a > 10 OR a < 0"},
+	}
+	cmd.Table = "t1"
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range mustRows(t, d, "t1") {
+		price, _ := strconv.ParseFloat(row["price"].(string), 64)
+		started, _ := strconv.Atoi(row["started"].(string))
+		ended, _ := strconv.Atoi(row["ended"].(string))
+		switch {
+		case price <= 0 || price > 100:
+			t.Fatalf("price %v breaks price_positive", row["price"])
+		case row["status"] != "new" && row["status"] != "done":
+			t.Fatalf("status %v breaks status_values", row["status"])
+		case len(row["code"].(string)) != 3:
+			t.Fatalf("code %v breaks code_length", row["code"])
+		case ended < started:
+			t.Fatalf("ended %d < started %d", ended, started)
+		}
+	}
+
+	loaded, err := db.LoadTable(cmd.DB.Database, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range generate.New(loaded, cmd.ForeignKeyLinks, 1, cmd.MaxTextSize, 4, nil).CheckStatuses() {
+		if status.Enforced != (status.Name != "a_or") {
+			t.Errorf("check %s: enforced=%v, reason %q", status.Name, status.Enforced, status.Reason)
+		}
+	}
+}
+
+func TestRunGeneratedColumns(t *testing.T) {
+	cmd, d := newTestRun(t, 30)
+	d.CreateTable("", "t1", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "name", DataType: "varchar", CharacterMaximumLength: nullInt(20)},
+		{ColumnName: "code", DataType: "varchar", CharacterMaximumLength: nullInt(20), Generated: true, GeneratedBy: "generated column"},
+		{ColumnName: "secret", DataType: "int", Generated: true, GeneratedBy: "invisible column"},
+	})
+	d.CreateTable("", "t2", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "t1_code", DataType: "varchar", CharacterMaximumLength: nullInt(20)},
+		{ColumnName: "total", DataType: "int", Generated: true, GeneratedBy: "generated column"},
+	}, db.Constraint{ConstraintName: "fk_t1", ColumnsName: []string{"t1_code"}, ReferencedTableName: "t1", ReferencedColumnsName: []string{"code"}})
+	cmd.Query = "select t1.name, t2.total from t1 join t2 on t1.code = t2.t1_code"
+
+	// memdb rejects values for generated columns, and foreign keys pointing to missing parent rows
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	parents := map[any]struct{}{}
+	for _, row := range mustRows(t, d, "t1") {
+		parents[row["code"]] = struct{}{}
+	}
+	for _, row := range mustRows(t, d, "t2") {
+		if _, ok := parents[row["t1_code"]]; !ok {
+			t.Fatalf("t2.t1_code=%v does not exist in t1", row["t1_code"])
+		}
+	}
+}
+
+func loopTables(d *memdb.Database, nullable bool) {
+	d.CreateTable("", "a", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "c_id", DataType: "int", IsNullable: nullable},
+	}, db.Constraint{ConstraintName: "fk_c", ColumnsName: []string{"c_id"}, ReferencedTableName: "c", ReferencedColumnsName: []string{"id"}})
+	d.CreateTable("", "b", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "a_id", DataType: "int"},
+	}, db.Constraint{ConstraintName: "fk_a", ColumnsName: []string{"a_id"}, ReferencedTableName: "a", ReferencedColumnsName: []string{"id"}})
+	d.CreateTable("", "c", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "b_id", DataType: "int"},
+	}, db.Constraint{ConstraintName: "fk_b", ColumnsName: []string{"b_id"}, ReferencedTableName: "b", ReferencedColumnsName: []string{"id"}})
+}
+
+func TestRunForeignKeyLoop(t *testing.T) {
+	cmd, d := newTestRun(t, 25)
+	loopTables(d, true)
+	cmd.Query = "select * from a join b on a.id = b.a_id join c on b.id = c.b_id"
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, fk := range []struct{ child, column, parent string }{{"a", "c_id", "c"}, {"b", "a_id", "a"}, {"c", "b_id", "b"}} {
+		parents := map[any]struct{}{}
+		for _, row := range mustRows(t, d, fk.parent) {
+			parents[row["id"]] = struct{}{}
+		}
+		children := mustRows(t, d, fk.child)
+		if len(children) != 25 {
+			t.Fatalf("expected 25 rows in %s, got %d", fk.child, len(children))
+		}
+		for _, row := range children {
+			// the foreign key breaking the loop is updated after the inserts
+			if _, ok := parents[row[fk.column]]; !ok {
+				t.Fatalf("%s.%s=%v does not exist in %s", fk.child, fk.column, row[fk.column], fk.parent)
+			}
+		}
+	}
+}
+
+func TestRunForeignKeyLoopNotNullable(t *testing.T) {
+	cmd, d := newTestRun(t, 25)
+	loopTables(d, false)
+	cmd.Query = "select * from a join b on a.id = b.a_id join c on b.id = c.b_id"
+
+	err := cmd.Run()
+	if err == nil || !strings.Contains(err.Error(), "form a loop") {
+		t.Fatalf("expected a foreign key loop error, got %v", err)
+	}
+}
+
+func TestRunHierarchy(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   string
+		rows   int64
+		levels []int // rows per level, nil to only check the roots
+		roots  int
+	}{
+		{name: "fixed branching", spec: "depth:4,branching:3", rows: 40, levels: []int{1, 3, 9, 27}},
+		{name: "roots percentage", spec: "roots:10%,branching:poisson(3)", rows: 200, roots: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, d := newTestRun(t, tt.rows)
+			d.CreateTable("", "t", []db.Field{
+				{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+				{ColumnName: "parent_id", DataType: "int", IsNullable: true},
+				{ColumnName: "name", DataType: "varchar", CharacterMaximumLength: nullInt(20)},
+			}, db.Constraint{ConstraintName: "fk_parent", ColumnsName: []string{"parent_id"}, ReferencedTableName: "t", ReferencedColumnsName: []string{"id"}})
+			cmd.Table = "t"
+			cmd.Hierarchy = map[string]string{"t.parent_id": tt.spec}
+
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+			parents := map[any]any{}
+			for _, row := range mustRows(t, d, "t") {
+				parents[row["id"]] = row["parent_id"]
+			}
+			var depth func(id any) int
+			depth = func(id any) int {
+				if parents[id] == nil {
+					return 0
+				}
+				return depth(parents[id]) + 1
+			}
+			levels := []int{}
+			for id := range parents {
+				for len(levels) <= depth(id) {
+					levels = append(levels, 0)
+				}
+				levels[depth(id)]++
+			}
+
+			if tt.levels != nil && !slices.Equal(levels, tt.levels) {
+				t.Fatalf("expected %v rows per level, got %v", tt.levels, levels)
+			}
+			if tt.roots != 0 && levels[0] != tt.roots {
+				t.Fatalf("expected %d roots, got %d", tt.roots, levels[0])
+			}
+			if len(levels) < 3 {
+				t.Fatalf("expected a hierarchy deeper than two levels, got %v", levels)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"errors"
+	"maps"
+	"slices"
+)
+
+// SecondaryIndex is an index enforcing no constraint, it can be dropped during the load and created again after it
+type SecondaryIndex struct {
+	Name       string
+	Definition string // statement creating the index again
+}
+
+// IndexManager lets the secondary indexes be dropped before a bulk load and created again after it
+type IndexManager interface {
+	GetSecondaryIndexes(string, string) ([]SecondaryIndex, error)
+	DropIndex(*Table, SecondaryIndex) error
+	CreateIndex(*Table, SecondaryIndex) error
+}
+
+// UnloggedTables is implemented by the engines skipping the write-ahead log of UNLOGGED tables
+type UnloggedTables interface {
+	SetLogged(*Table, bool) error
+}
+
+// GetSecondaryIndexes returns no index when the engine cannot drop them
+func GetSecondaryIndexes(schema, table string) ([]SecondaryIndex, error) {
+	if manager, ok := engine.(IndexManager); ok {
+		return manager.GetSecondaryIndexes(schema, table)
+	}
+	return nil, nil
+}
+
+func DropIndex(table *Table, index SecondaryIndex) error {
+	return engine.(IndexManager).DropIndex(table, index)
+}
+
+func CreateIndex(table *Table, index SecondaryIndex) error {
+	return engine.(IndexManager).CreateIndex(table, index)
+}
+
+// SetLogged switches a table between logged and UNLOGGED, PostgreSQL only
+func SetLogged(table *Table, logged bool) error {
+	if unlogged, ok := engine.(UnloggedTables); ok {
+		return unlogged.SetLogged(table, logged)
+	}
+	return ErrNoUnloggedTables
+}
+
+// SessionSettings returns the variables to set on every connection: the speedups of the engine for bulk loads, then --session-var
+func (c Config) SessionSettings(bulkLoad [][2]string) [][2]string {
+	vars := [][2]string{}
+	if c.BulkLoad {
+		vars = append(vars, bulkLoad...)
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.SessionVars)) {
+		vars = append(vars, [2]string{name, c.SessionVars[name]})
+	}
+	return vars
+}
+
+var ErrNoUnloggedTables = errors.New("UNLOGGED tables are PostgreSQL only")
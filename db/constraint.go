@@ -22,15 +22,22 @@ type Constraint struct {
 	ReferencedFields            []Field
 	ReferencedTable             *Table
 	willBeInsertedDuringThisRun bool
+	deferred                    bool
 }
 
 type Constraints []*Constraint
 
+// NewConstraintFromVirtualFK adds a foreign key from table, the right side of the join, to the left side.
+// An unqualified left side is looked for in the schema of the table
 func NewConstraintFromVirtualFK(table *Table, left query.VirtualJoinPart, right query.VirtualJoinPart) (*Constraint, error) {
 
+	schema := left.Schema
+	if schema == "" {
+		schema = table.Schema
+	}
 	constraint := &Constraint{
-		ConstraintName:        "VirtualFK_" + strings.Join(right.Columns, "_") + gofakeit.ID(), // an ID to prevent collisions
-		ReferencedTableSchema: table.Schema,                                                    // assuming the schema is the same, good enough for now
+		ConstraintName:        virtualFKPrefix + strings.Join(right.Columns, "_") + gofakeit.ID(), // an ID to prevent collisions
+		ReferencedTableSchema: schema,
 		ReferencedTableName:   left.Table,
 		ColumnsName:           right.Columns,
 		ReferencedColumnsName: left.Columns,
@@ -40,16 +47,61 @@ func NewConstraintFromVirtualFK(table *Table, left query.VirtualJoinPart, right
 	return constraint, errors.Wrap(err, "NewConstraintFromVirtualFK")
 }
 
+const virtualFKPrefix = "VirtualFK_"
+
+// IsVirtual reports whether the constraint was guessed from the query or added with --add-fk, instead of existing in the schema
+func (c *Constraint) IsVirtual() bool {
+	return strings.HasPrefix(c.ConstraintName, virtualFKPrefix)
+}
+
+// WillBeInsertedDuringThisRun reports whether the referenced table is part of the tables being inserted to
+func (c *Constraint) WillBeInsertedDuringThisRun() bool {
+	return c.willBeInsertedDuringThisRun
+}
+
+// HasGeneratedFields reports whether a column of the constraint is filled by the database, so it cannot be sampled
+func (c *Constraint) HasGeneratedFields() bool {
+	return slices.ContainsFunc(c.Fields, func(f Field) bool { return f.Generated })
+}
+
+// IsDeferred reports whether the constraint breaks a loop between tables: its columns are left NULL by the inserts and updated once every table is loaded
+func (c *Constraint) IsDeferred() bool {
+	return c.deferred
+}
+
+// isSampled reports whether values have to be picked from the referenced table, deferred or not
+func (c *Constraint) isSampled() bool {
+	// the database computes the value, it will reference whatever it wants
+	if c.HasGeneratedFields() {
+		return false
+	}
+	// if only 1 field is needed, all fields from this constraint will be needed too
+	return slices.ContainsFunc(c.Fields, func(f Field) bool { return !f.Skip })
+}
+
+// References reports whether the constraint points to the table, in its schema
+func (c *Constraint) References(t *Table) bool {
+	return strings.EqualFold(c.ReferencedTableSchema, t.Schema) && strings.EqualFold(c.ReferencedTableName, t.Name)
+}
+
 func (c *Constraint) IsLooping() bool {
 	return c.constraintLoopTraverser([]string{})
 }
 
 func (c *Constraint) constraintLoopTraverser(traversedTables []string) bool {
-	if slices.Contains(traversedTables, c.ReferencedTable.Name) {
+	if c.deferred {
+		return false
+	}
+	name := strings.ToLower(c.ReferencedTable.FullName())
+	if slices.Contains(traversedTables, name) {
 		return true
 	}
 	for _, childConstraints := range c.ReferencedTable.Constraints {
-		isLooping := childConstraints.constraintLoopTraverser(append(traversedTables, c.ReferencedTable.Name))
+		// self-referencing tables are inserted twice, they do not loop
+		if childConstraints.References(c.ReferencedTable) {
+			continue
+		}
+		isLooping := childConstraints.constraintLoopTraverser(append(traversedTables, name))
 		if isLooping {
 			return true
 		}
@@ -81,7 +133,7 @@ func (c *Constraint) populateFields(targetTable *Table) error {
 func (c *Constraint) loadReferencedTable() error {
 
 	var err error
-	c.ReferencedTable, err = LoadTable(c.ReferencedTableSchema, c.ReferencedTableName)
+	c.ReferencedTable, err = LoadTable(c.ReferencedTableSchema, c.ReferencedTableSchema+"."+c.ReferencedTableName)
 	if err != nil {
 		return errors.Wrapf(err, "using schema %s, table %s", c.ReferencedTableSchema, c.ReferencedTableName)
 	}
@@ -112,15 +164,15 @@ func shouldSkipVirtualFK(tables []*Table, vfk query.VirtualJoin) bool {
 
 			switch {
 			// TODO: we could "supplement" existing FKs with virtual ones, I'm not sure if that's a real use case yet
-			case strings.ToLower(vfk.Left.Table) == strings.ToLower(table.Name) &&
-				strings.ToLower(vfk.Right.Table) == strings.ToLower(constraint.ReferencedTableName) &&
+			case isJoinPartOf(vfk.Left, table.Schema, table.Name) &&
+				isJoinPartOf(vfk.Right, constraint.ReferencedTableSchema, constraint.ReferencedTableName) &&
 				isSliceSimilar(constraint.ColumnsName, vfk.Left.Columns) &&
 				isSliceSimilar(constraint.ReferencedColumnsName, vfk.Right.Columns):
 				return true
 
 				// flipped
-			case strings.ToLower(vfk.Right.Table) == strings.ToLower(table.Name) &&
-				strings.ToLower(vfk.Left.Table) == strings.ToLower(constraint.ReferencedTableName) &&
+			case isJoinPartOf(vfk.Right, table.Schema, table.Name) &&
+				isJoinPartOf(vfk.Left, constraint.ReferencedTableSchema, constraint.ReferencedTableName) &&
 				isSliceSimilar(constraint.ColumnsName, vfk.Right.Columns) &&
 				isSliceSimilar(constraint.ReferencedColumnsName, vfk.Left.Columns):
 
@@ -132,6 +184,25 @@ func shouldSkipVirtualFK(tables []*Table, vfk query.VirtualJoin) bool {
 	return false
 }
 
+// isJoinPartOf reports whether the join part names the table. Unqualified parts match the table in any schema
+func isJoinPartOf(part query.VirtualJoinPart, schema, name string) bool {
+	return strings.EqualFold(part.Table, name) && (part.Schema == "" || strings.EqualFold(part.Schema, schema))
+}
+
+// findJoinTable returns the table of the run named by the join part, the one in schema first when the part is not qualified
+func findJoinTable(tables []*Table, part query.VirtualJoinPart, schema string) *Table {
+	var found *Table
+	for _, t := range tables {
+		if !isJoinPartOf(part, t.Schema, t.Name) {
+			continue
+		}
+		if found == nil || strings.EqualFold(t.Schema, schema) {
+			found = t
+		}
+	}
+	return found
+}
+
 func AddVirtualFKs(tables []*Table, fkeys []query.VirtualJoin) error {
 	log.Debug().Interface("fkeys", fkeys).Str("func", "AddVirtualFKs2").Msg("adding virtual foreign keys")
 
@@ -143,12 +214,20 @@ func AddVirtualFKs(tables []*Table, fkeys []query.VirtualJoin) error {
 		}
 
 		// left is parent, right is child. Constraints are on child side
-		tableIdx := slices.IndexFunc(tables, func(t *Table) bool { return strings.ToLower(t.Name) == strings.ToLower(virtualJoin.Right.Table) })
-		if tableIdx == -1 {
+		table := findJoinTable(tables, virtualJoin.Right, "")
+		if table == nil {
 			log.Debug().Str("left", virtualJoin.Left.Table).Str("right", virtualJoin.Right.Table).Str("func", "AddVirtualFKs").Msg("table not loaded")
 			continue
 		}
-		table := tables[tableIdx]
+		// an unqualified parent is the table of the run with this name, the one of the same schema as the child first
+		if virtualJoin.Left.Schema == "" {
+			if parent := findJoinTable(tables, virtualJoin.Left, table.Schema); parent != nil {
+				virtualJoin.Left.Schema = parent.Schema
+			}
+		}
+		if virtualJoin.Right.Schema == "" {
+			virtualJoin.Right.Schema = table.Schema
+		}
 
 		constraint, err := NewConstraintFromVirtualFK(table, virtualJoin.Left, virtualJoin.Right)
 		if err != nil {
@@ -157,6 +236,12 @@ func AddVirtualFKs(tables []*Table, fkeys []query.VirtualJoin) error {
 		}
 
 		if constraint.IsLooping() {
+			// flipped, the foreign key goes on the left side
+			table = findJoinTable(tables, virtualJoin.Left, table.Schema)
+			if table == nil {
+				log.Debug().Str("left", virtualJoin.Left.Table).Str("right", virtualJoin.Right.Table).Str("func", "AddVirtualFKs").Msg("could not add a virtual foreign key without creating a loop, skipping")
+				continue
+			}
 			constraint, err = NewConstraintFromVirtualFK(table, virtualJoin.Right, virtualJoin.Left)
 			if err != nil {
 				log.Error().Str("left", virtualJoin.Right.Table).Str("right", virtualJoin.Left.Table).Str("func", "AddVirtualFKs").Err(err).Msg("could not add a (flipped) virtual foreign key, skipping")
@@ -164,6 +249,7 @@ func AddVirtualFKs(tables []*Table, fkeys []query.VirtualJoin) error {
 			}
 			if constraint.IsLooping() {
 				log.Debug().Str("left", virtualJoin.Left.Table).Str("right", virtualJoin.Right.Table).Str("func", "AddVirtualFKs").Msg("could not add a virtual foreign key without creating a loop, skipping")
+				continue
 			}
 		}
 
@@ -13,11 +13,31 @@ import (
 
 // Table holds the table definition with all fields, indexes and triggers
 type Table struct {
-	Schema string
+	Schema       string
+	Name         string
+	Fields       []Field
+	Indexes      map[string]Index
+	Constraints  []*Constraint
+	Checks       []Check
+	Partitioning *Partitioning // nil when the table is not partitioned
+	Triggers     []Trigger     // firing on INSERT or UPDATE
+	Comment      string
+	Hints        *Hints // from the rdl: annotation of the comment, only null() applies to every nullable column
+	// ExplicitKeys generates the auto-increment primary key instead of leaving it to the database
+	ExplicitKeys bool
+}
+
+// Check is a CHECK constraint, the clause is kept as the database prints it
+type Check struct {
 	Name   string
-	Fields []Field
-	//Indexes     map[string]Index
-	Constraints []*Constraint
+	Clause string
+}
+
+// Index is a unique index or the primary key of a table. Other indexes are not loaded
+type Index struct {
+	Name    string
+	Primary bool
+	Columns []string
 }
 
 type Field struct {
@@ -27,11 +47,25 @@ type Field struct {
 	CharacterMaximumLength sql.NullInt64
 	NumericPrecision       sql.NullInt64
 	NumericScale           sql.NullInt64
+	Unsigned               bool
+	DisplayWidth           int64 // of MySQL integers, tinyint(1) holds booleans
+	FullIntRange           bool  // integers span the whole range of their type: the MySQL column type was read, or the PostgreSQL integer has a fixed size
 	AutoIncrement          bool
 	ColumnKey              string
-	SetEnumVals            []string
+	SetEnumVals            []string // quotes are doubled, like in the quoted literal
+	Attributes             []Field  // attributes of a PostgreSQL composite type, in order
+	Element                *Field   // element of a PostgreSQL array
+	NoOverlap              bool     // range column of a PostgreSQL exclusion constraint using &&
+	GeometryType           string   // point, linestring or polygon of a spatial column, empty for any of them
+	SRID                   int64    // spatial reference system of a spatial column, 0 when it has none
+	LatitudeFirst          bool     // MySQL geographic spatial reference systems read the latitude first in WKT
 	HasDefaultValue        bool
+	Generated              bool   // filled by the database: generated columns, invisible columns, system versioning periods
+	GeneratedBy            string // what fills a Generated column, for reporting
 	Skip                   bool
+	SkipReason             string
+	Comment                string
+	Hints                  *Hints // from the rdl: annotation of the comment
 }
 
 func isSupportedType(fieldType string) bool {
@@ -64,7 +98,32 @@ func isSupportedType(fieldType string) bool {
 		"varbinary":  true,
 		"enum":       true,
 		"set":        true,
+		"bit":        true,
+		"composite":  true,
+		"array":      true,
+		"inet":       true,
+		"cidr":       true,
+		"macaddr":    true,
+		"interval":   true,
+		"money":      true,
+		"bytea":      true,
+		"bitstring":  true,
+		"varbit":     true,
+		"int4range":  true,
+		"int8range":  true,
+		"numrange":   true,
+		"daterange":  true,
+		"tsrange":    true,
+		"tstzrange":  true,
+		"citext":     true,
+		"xml":        true,
+		"tsvector":   true,
 		"uuid":       true,
+		"inet4":      true,
+		"inet6":      true,
+		"json":       true,
+		"geometry":   true,
+		"geography":  true,
 		"bool":       true,
 		"boolean":    true,
 	}
@@ -72,16 +131,30 @@ func isSupportedType(fieldType string) bool {
 	return ok
 }
 
+func scanChecks(rows *sql.Rows) ([]Check, error) {
+	defer rows.Close()
+	checks := []Check{}
+	for rows.Next() {
+		var c Check
+		if err := rows.Scan(&c.Name, &c.Clause); err != nil {
+			return nil, errors.Wrap(err, "cannot read checks")
+		}
+		checks = append(checks, c)
+	}
+	return checks, rows.Err()
+}
+
 var loadedTableCache = map[string]*Table{}
 
+// LoadTable reads the definition of the table, tablename can be qualified by its schema.
+// Tables are cached by schema and name, whichever way they were named
 func LoadTable(database, tablename string) (*Table, error) {
-	if table, ok := loadedTableCache[database+"."+tablename]; ok {
-		return table, nil
-	}
-
 	var err error
 	table := &Table{}
 	engine.SetTableMetadata(table, database, tablename)
+	if cached, ok := loadedTableCache[table.FullName()]; ok {
+		return cached, nil
+	}
 
 	table.Fields, err = GetFields(table.Schema, table.Name)
 	if err != nil {
@@ -93,6 +166,33 @@ func LoadTable(database, tablename string) (*Table, error) {
 		return nil, errors.Wrapf(err, "LoadTable %s.%s", database, tablename)
 	}
 
+	table.Indexes, err = GetIndexes(table.Schema, table.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "LoadTable %s.%s", database, tablename)
+	}
+	table.addPrimaryKeyFromFields()
+
+	table.Checks, err = GetChecks(table.Schema, table.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "LoadTable %s.%s", database, tablename)
+	}
+
+	table.Partitioning, err = GetPartitions(table.Schema, table.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "LoadTable %s.%s", database, tablename)
+	}
+
+	table.Triggers, err = GetTriggers(table.Schema, table.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "LoadTable %s.%s", database, tablename)
+	}
+
+	table.Comment, err = GetTableComment(table.Schema, table.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "LoadTable %s.%s", database, tablename)
+	}
+	table.parseHints()
+
 	loadedTableCache[table.FullName()] = table
 
 	for constraintIdx := range table.Constraints {
@@ -107,6 +207,24 @@ func LoadTable(database, tablename string) (*Table, error) {
 	return table, nil
 }
 
+// parseHints reads the rdl: annotations of the table and column comments, warning about the hints not understood
+func (t *Table) parseHints() {
+	var warnings []string
+	t.Hints, warnings = ParseHints(t.Comment)
+	if t.Hints != nil && (t.Hints.Generator != "" || t.Hints.Zipf != 0 || t.Hints.Min != "" || len(t.Hints.Values) > 0) {
+		warnings = append(warnings, "only null() applies to a table, the other hints belong to column comments")
+	}
+	for _, warning := range warnings {
+		log.Warn().Str("table", t.FullName()).Str("annotation", t.Hints.Annotation).Msg("ignoring hint: " + warning)
+	}
+	for i := range t.Fields {
+		t.Fields[i].Hints, warnings = ParseHints(t.Fields[i].Comment)
+		for _, warning := range warnings {
+			log.Warn().Str("table", t.FullName()).Str("column", t.Fields[i].ColumnName).Str("annotation", t.Fields[i].Hints.Annotation).Msg("ignoring hint: " + warning)
+		}
+	}
+}
+
 // FieldNames returns an string array with the table's field names
 func (t *Table) FieldNames() []string {
 	fields := []string{}
@@ -116,15 +234,48 @@ func (t *Table) FieldNames() []string {
 	return fields
 }
 
+// FieldByName points into t.Fields, so that engines can complete the fields they read
 func (t *Table) FieldByName(name string) *Field {
-	for _, field := range t.Fields {
-		if strings.ToLower(field.ColumnName) == strings.ToLower(name) {
-			return &field
+	for i := range t.Fields {
+		if strings.EqualFold(t.Fields[i].ColumnName, name) {
+			return &t.Fields[i]
 		}
 	}
 	return nil
 }
 
+// UniqueIndexes returns the indexes sorted by name, so that generation is reproducible
+func (t *Table) UniqueIndexes() []Index {
+	indexes := []Index{}
+	for _, index := range t.Indexes {
+		indexes = append(indexes, index)
+	}
+	slices.SortFunc(indexes, func(a, b Index) int { return strings.Compare(a.Name, b.Name) })
+	return indexes
+}
+
+// addPrimaryKeyFromFields covers engines not exposing the primary key as an index, like a sqlite rowid alias
+func (t *Table) addPrimaryKeyFromFields() {
+	for _, index := range t.Indexes {
+		if index.Primary {
+			return
+		}
+	}
+	pk := Index{Name: "PRIMARY", Primary: true}
+	for _, field := range t.Fields {
+		if field.ColumnKey == "PRI" {
+			pk.Columns = append(pk.Columns, field.ColumnName)
+		}
+	}
+	if len(pk.Columns) == 0 {
+		return
+	}
+	if t.Indexes == nil {
+		t.Indexes = map[string]Index{}
+	}
+	t.Indexes[pk.Name] = pk
+}
+
 func (t *Table) FullName() string {
 	return t.Schema + "." + t.Name
 }
@@ -152,13 +303,13 @@ func (t *Table) FieldsToGenerate() []Field {
 	fields := []Field{}
 
 	for _, field := range t.Fields {
-		if field.Skip {
+		if field.Skip || field.Generated {
 			continue
 		}
 		if !isSupportedType(field.DataType) {
 			continue
 		}
-		if !field.IsNullable && field.ColumnKey == "PRI" && field.AutoIncrement {
+		if !field.IsNullable && field.ColumnKey == "PRI" && field.AutoIncrement && !t.ExplicitKeys {
 			continue
 		}
 		if t.IsFieldInAnyConstraints(field) {
@@ -183,24 +334,38 @@ func (t *Table) IsFieldInAnyConstraints(field Field) bool {
 // should not happen since it does not make sense for FKs
 func (t *Table) ConstraintsToSample() Constraints {
 	cs := []*Constraint{}
-CONSTRAINTS:
 	for _, constraint := range t.Constraints {
-		for _, field := range constraint.Fields {
-			// if only 1 field is needed, all fields from this constraint will be needed too
-			if !field.Skip {
-				cs = append(cs, constraint)
-				continue CONSTRAINTS
-			}
+		if constraint.isSampled() && !constraint.deferred {
+			cs = append(cs, constraint)
 		}
 	}
 	return cs
 }
 
+// DeferredConstraints returns the constraints left NULL to break a loop, that must be sampled once every table is loaded
+func (t *Table) DeferredConstraints() Constraints {
+	cs := []*Constraint{}
+	for _, constraint := range t.Constraints {
+		if constraint.isSampled() && constraint.deferred {
+			cs = append(cs, constraint)
+		}
+	}
+	return cs
+}
+
+// PrimaryKey returns the primary key of the table, nil if it has none
+func (t *Table) PrimaryKey() *Index {
+	for _, index := range t.Indexes {
+		if index.Primary {
+			return &index
+		}
+	}
+	return nil
+}
+
 func (t *Table) FlagConstraintThatArePartsOfThisRun(tables []*Table) {
 	for _, constraint := range t.Constraints {
-		if slices.ContainsFunc(tables, func(t2 *Table) bool {
-			return strings.ToLower(t2.Name) == strings.ToLower(constraint.ReferencedTableName)
-		}) {
+		if slices.ContainsFunc(tables, constraint.References) {
 			constraint.willBeInsertedDuringThisRun = true
 		}
 		log.Debug().Bool("willBeInsertedDuringThisRun", constraint.willBeInsertedDuringThisRun).Str("constraint", constraint.ConstraintName).Str("tablename", t.Name).Str("table schema", t.Schema).Str("func", "FlagConstraintThatArePartsOfThisRun").Msg("will constraint be resolved during this run")
@@ -217,6 +382,12 @@ func (t *Table) SkipBasedOnIdentifiers(identifiers map[string]struct{}) {
 		log.Debug().Str("field", field.ColumnName).Str("tablename", t.Name).Str("table schema", t.Schema).Str("func", "skipBasedOnIdentifiers").Bool("fieldSkippeable", field.skippeable()).Bool("foundInIdentifiers", ok).Bool("will be skipped", !ok && field.skippeable()).Msg("will field be skipped")
 		if !ok && field.skippeable() {
 			field.Skip = true
+			field.SkipReason = "not used in the query"
+			if field.HasDefaultValue {
+				field.SkipReason += ", has a default value"
+			} else {
+				field.SkipReason += ", nullable"
+			}
 			t.Fields[i] = field
 			continue
 		}
@@ -230,9 +401,11 @@ func (t *Table) AreAllDependenciesContained(tables []*Table) bool {
 		if !constraint.willBeInsertedDuringThisRun {
 			continue
 		}
-		if !slices.ContainsFunc(tables, func(t2 *Table) bool {
-			return strings.ToLower(t2.Name) == strings.ToLower(constraint.ReferencedTableName)
-		}) {
+		// filled after every table is loaded
+		if constraint.deferred {
+			continue
+		}
+		if !slices.ContainsFunc(tables, constraint.References) {
 			return false
 		}
 	}
@@ -250,7 +423,7 @@ func (t *Table) HasAnyConstraintLoop() bool {
 
 func (t *Table) IdentifyAndResolveSelfReferencingConstraintLoop() (*Table, error) {
 	for cidx, c := range t.Constraints {
-		if c.ReferencedTable.Name == t.Name {
+		if c.References(t) {
 			for _, field := range c.Fields {
 				if !field.IsNullable {
 					return nil, errors.Errorf("table %s is self referencing and one of fields (%s) is non-nullable. Consider dropping the foreign key named %s", t.Name, field.ColumnName, c.ConstraintName)
@@ -264,6 +437,7 @@ func (t *Table) IdentifyAndResolveSelfReferencingConstraintLoop() (*Table, error
 					continue
 				}
 				field.Skip = true
+				field.SkipReason = "self-referencing foreign key " + c.ConstraintName + ", left NULL on the first pass"
 				copiedTable.Fields[fidx] = field
 			}
 
@@ -277,6 +451,11 @@ func (t *Table) IdentifyAndResolveSelfReferencingConstraintLoop() (*Table, error
 	return nil, nil
 }
 
+// IsSupportedType reports whether values can be generated for this field
+func (f *Field) IsSupportedType() bool {
+	return isSupportedType(f.DataType)
+}
+
 func (f *Field) skippeable() bool {
 	if !f.IsNullable && !f.HasDefaultValue {
 		return false
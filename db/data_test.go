@@ -0,0 +1,73 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSkipBasedOnIdentifiers(t *testing.T) {
+	table := &Table{Name: "t1", Fields: []Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "used", DataType: "int"},
+		{ColumnName: "unused_nullable", DataType: "int", IsNullable: true},
+		{ColumnName: "unused_default", DataType: "int", HasDefaultValue: true},
+		{ColumnName: "unused_required", DataType: "int"},
+	}}
+	table.SkipBasedOnIdentifiers(map[string]struct{}{"used": {}})
+
+	reasons := map[string]string{
+		"unused_nullable": "not used in the query, nullable",
+		"unused_default":  "not used in the query, has a default value",
+	}
+	for _, field := range table.Fields {
+		if field.Skip != (reasons[field.ColumnName] != "") || field.SkipReason != reasons[field.ColumnName] {
+			t.Errorf("%s: expected skip reason %q, got skip %v, %q", field.ColumnName, reasons[field.ColumnName], field.Skip, field.SkipReason)
+		}
+	}
+
+	names := func() []string {
+		names := []string{}
+		for _, field := range table.FieldsToGenerate() {
+			names = append(names, field.ColumnName)
+		}
+		return names
+	}
+	if expected := []string{"used", "unused_required"}; !slices.Equal(names(), expected) {
+		t.Errorf("expected to generate %v, got %v", expected, names())
+	}
+	table.ExplicitKeys = true
+	if expected := []string{"id", "used", "unused_required"}; !slices.Equal(names(), expected) {
+		t.Errorf("with explicit keys, expected to generate %v, got %v", expected, names())
+	}
+}
+
+func TestConstraintsAcrossSchemas(t *testing.T) {
+	public := &Table{Schema: "public", Name: "t1"}
+	crm := &Table{Schema: "crm", Name: "t1", Constraints: []*Constraint{
+		{ConstraintName: "fk_public", ReferencedTableSchema: "public", ReferencedTableName: "t1"},
+	}}
+	crm.Constraints[0].ReferencedTable = public
+
+	// only crm.t1 is loaded, its foreign key points outside of the run
+	crm.FlagConstraintThatArePartsOfThisRun([]*Table{crm})
+	if crm.Constraints[0].WillBeInsertedDuringThisRun() {
+		t.Error("expected public.t1 not to be part of the run")
+	}
+	if copied, err := crm.IdentifyAndResolveSelfReferencingConstraintLoop(); err != nil || copied != nil {
+		t.Errorf("expected crm.t1 not to be self-referencing, got %v, %v", copied, err)
+	}
+
+	crm.FlagConstraintThatArePartsOfThisRun([]*Table{crm, public})
+	if !crm.Constraints[0].WillBeInsertedDuringThisRun() {
+		t.Error("expected public.t1 to be part of the run")
+	}
+	if crm.AreAllDependenciesContained([]*Table{crm}) {
+		t.Error("expected crm.t1 to wait for public.t1")
+	}
+	if !crm.AreAllDependenciesContained([]*Table{public}) {
+		t.Error("expected crm.t1 to be ready once public.t1 is")
+	}
+	if loop := findConstraintLoop([]*Table{crm, public}); loop != nil {
+		t.Errorf("expected no loop, got %v", loop)
+	}
+}
@@ -3,15 +3,30 @@ package db
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 )
 
 type Config struct {
-	Engine   string `enum:"mysql,pg" required:"" help:"mysql,pg"`
-	Database string
-	Host     string
-	User     string
-	Password string
-	Port     int
+	Engine       string `enum:"${engines}," default:"" help:"${engines}. Can be omitted when --dsn is given"`
+	DSN          string `name:"dsn" help:"Connection URL, e.g mysql://user@host:3306/db?tls-mode=verify-full or postgres://user@host/db?sslmode=require. Other connection flags have priority over its parts"`
+	Database     string `help:"Database name. For sqlite, the path to the database file"`
+	Host         string
+	Port         int
+	Socket       string `help:"Unix socket path. For pg, the socket file or the directory containing it"`
+	User         string
+	Password     string            `help:"Prefer --password-file, the environment (MYSQL_PWD, PGPASSWORD) or the option files (~/.my.cnf, ~/.pgpass) to keep passwords out of the process list"`
+	PasswordFile string            `name:"password-file" help:"File containing the password"`
+	TLSMode      string            `name:"tls-mode" enum:",disable,prefer,require,verify-ca,verify-full" default:"" help:"disable (default), prefer, require, verify-ca or verify-full. prefer falls back to plaintext when the server has no TLS, prefer and require encrypt without checking the server certificate"`
+	TLSCA        string            `name:"tls-ca" help:"CA certificate file to verify the server certificate"`
+	TLSCert      string            `name:"tls-cert" help:"Client certificate file"`
+	TLSKey       string            `name:"tls-key" help:"Client private key file"`
+	SearchPath   string            `name:"search-path" help:"PostgreSQL schemas where unqualified tables are looked for, e.g. sales,public. Defaults to the search_path of the user"`
+	SessionVars  map[string]string `name:"session-var" help:"Session variable set on every connection, e.g. --session-var=work_mem=256MB. Can be repeated. Values are used as written on MySQL, quote strings. SQLite sets them as PRAGMAs" default:""`
+
+	// DisableTriggers turns triggers off for every session, when the engine can
+	DisableTriggers bool `kong:"-"`
+	// BulkLoad sets the session variables of the engine speeding up inserts, before SessionVars
+	BulkLoad bool `kong:"-"`
 }
 
 var (
@@ -23,37 +38,62 @@ type Engine interface {
 	Connect(Config) (*sql.DB, error)
 	GetFields(string, string) ([]Field, error)
 	GetConstraints(string, string) ([]*Constraint, error)
+	GetIndexes(string, string) (map[string]Index, error)
 	InsertTemplate() string
+	DefaultKeyword() string
 	Escape(string) string
 	SetTableMetadata(*Table, string, string)
 	BinomialWhereClause(float64) string
 	ErrShouldRetryTx(error) bool
 }
 
+// The capabilities below are optional, an engine implements the ones it supports.
+// Without them, the tables have no checks, partitions, triggers or comments, and nothing is a view
+
+type CheckReader interface {
+	GetChecks(string, string) ([]Check, error)
+}
+
+type PartitionReader interface {
+	GetPartitions(string, string) (*Partitioning, error)
+}
+
+type ViewReader interface {
+	GetViewDefinition(string, string) (string, error)
+}
+
+type TriggerReader interface {
+	GetTriggers(string, string) ([]Trigger, error)
+}
+
+type CommentReader interface {
+	GetTableComment(string, string) (string, error)
+}
+
+// IdentityOverrider is needed by the engines refusing explicit values in identity columns
+type IdentityOverrider interface {
+	OverridingSystemValue() string
+}
+
+// SequenceSyncer is needed by the engines whose sequences stay behind explicit keys
+type SequenceSyncer interface {
+	SyncSequences(*Table) error
+}
+
 var ErrFieldsNotFound = errors.New("fields not found")
 
 func Connect(config Config) (*sql.DB, error) {
-	err := setEngine(config)
+	info, err := config.resolve()
 	if err != nil {
 		return nil, err
 	}
+	engine = info.Engine
+	// tables are cached per connection, a new connection could point to another database
+	loadedTableCache = map[string]*Table{}
 	DB, err = engine.Connect(config)
 	return DB, err
 }
 
-func setEngine(config Config) error {
-	switch config.Engine {
-	case "mysql":
-		engine = MySQL{}
-		return nil
-	case "pg":
-		engine = Postgres{}
-		return nil
-	default:
-		return errors.New("unsupported engine")
-	}
-}
-
 func GetFields(schema, table string) ([]Field, error) {
 	return engine.GetFields(schema, table)
 }
@@ -62,10 +102,102 @@ func GetConstraints(schema, table string) ([]*Constraint, error) {
 	return engine.GetConstraints(schema, table)
 }
 
+func GetIndexes(schema, table string) (map[string]Index, error) {
+	return engine.GetIndexes(schema, table)
+}
+
+func GetChecks(schema, table string) ([]Check, error) {
+	if reader, ok := engine.(CheckReader); ok {
+		return reader.GetChecks(schema, table)
+	}
+	return nil, nil
+}
+
+func GetPartitions(schema, table string) (*Partitioning, error) {
+	if reader, ok := engine.(PartitionReader); ok {
+		return reader.GetPartitions(schema, table)
+	}
+	return nil, nil
+}
+
+func GetTriggers(schema, table string) ([]Trigger, error) {
+	if reader, ok := engine.(TriggerReader); ok {
+		return reader.GetTriggers(schema, table)
+	}
+	return nil, nil
+}
+
+func GetTableComment(schema, table string) (string, error) {
+	if reader, ok := engine.(CommentReader); ok {
+		return reader.GetTableComment(schema, table)
+	}
+	return "", nil
+}
+
+// ViewDefinition returns the SELECT defining the view, an empty string when tablename is not a view
+func ViewDefinition(database, tablename string) (string, error) {
+	reader, ok := engine.(ViewReader)
+	if !ok {
+		return "", nil
+	}
+	table := &Table{}
+	engine.SetTableMetadata(table, database, tablename)
+	return reader.GetViewDefinition(table.Schema, table.Name)
+}
+
+// MaxInt returns the highest value of an integer column, 0 when the table is empty
+func MaxInt(table *Table, column string) (int64, error) {
+	var max sql.NullInt64
+	query := fmt.Sprintf("SELECT MAX(%s) FROM %s.%s", Escape(column), Escape(table.Schema), Escape(table.Name))
+	err := DB.QueryRow(query).Scan(&max)
+	return max.Int64, err
+}
+
+// MaxRangeUpper returns the highest upper bound of a PostgreSQL range column, in seconds since the epoch for dates and timestamps.
+// found is false when the table has no bounded range
+func MaxRangeUpper(table *Table, column string, epoch bool) (upper float64, found bool, err error) {
+	var max sql.NullFloat64
+	expr := fmt.Sprintf("MAX(UPPER(%s))", Escape(column))
+	if epoch {
+		expr = "EXTRACT(EPOCH FROM " + expr + ")"
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s.%s", expr, Escape(table.Schema), Escape(table.Name))
+	err = DB.QueryRow(query).Scan(&max)
+	return max.Float64, max.Valid, err
+}
+
+// CountRows returns the number of rows of the table
+func CountRows(table *Table) (int64, error) {
+	var count int64
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s.%s", Escape(table.Schema), Escape(table.Name))
+	err := DB.QueryRow(query).Scan(&count)
+	return count, err
+}
+
 func InsertTemplate() string {
 	return engine.InsertTemplate()
 }
 
+func DefaultKeyword() string {
+	return engine.DefaultKeyword()
+}
+
+// OverridingSystemValue returns the clause inserting explicit values into identity columns, empty when the engine does not need one
+func OverridingSystemValue() string {
+	if overrider, ok := engine.(IdentityOverrider); ok {
+		return overrider.OverridingSystemValue()
+	}
+	return ""
+}
+
+// SyncSequences moves the sequences and auto-increment counters of the table past the highest key, explicit keys could have been inserted
+func SyncSequences(table *Table) error {
+	if syncer, ok := engine.(SequenceSyncer); ok {
+		return syncer.SyncSequences(table)
+	}
+	return nil
+}
+
 func Escape(s string) string {
 	return engine.Escape(s)
 }
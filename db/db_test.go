@@ -0,0 +1,39 @@
+package db
+
+import "testing"
+
+// the optional capabilities are found by type assertion, a drifting signature would silently turn them off
+func TestEngineCapabilities(t *testing.T) {
+	tests := []struct {
+		engine  Engine
+		missing []string
+	}{
+		{engine: Postgres{}},
+		{engine: MySQL{}, missing: []string{"IdentityOverrider", "UnloggedTables"}},
+		{engine: MariaDB{}, missing: []string{"IdentityOverrider", "UnloggedTables"}},
+		{engine: SQLite{}, missing: []string{"PartitionReader", "IdentityOverrider", "SequenceSyncer", "UnloggedTables"}},
+	}
+	for _, test := range tests {
+		capabilities := map[string]bool{}
+		_, capabilities["CheckReader"] = test.engine.(CheckReader)
+		_, capabilities["PartitionReader"] = test.engine.(PartitionReader)
+		_, capabilities["ViewReader"] = test.engine.(ViewReader)
+		_, capabilities["TriggerReader"] = test.engine.(TriggerReader)
+		_, capabilities["CommentReader"] = test.engine.(CommentReader)
+		_, capabilities["IdentityOverrider"] = test.engine.(IdentityOverrider)
+		_, capabilities["SequenceSyncer"] = test.engine.(SequenceSyncer)
+		_, capabilities["IndexManager"] = test.engine.(IndexManager)
+		_, capabilities["UnloggedTables"] = test.engine.(UnloggedTables)
+		for _, name := range test.missing {
+			if capabilities[name] {
+				t.Errorf("%T: expected no %s", test.engine, name)
+			}
+			delete(capabilities, name)
+		}
+		for name, ok := range capabilities {
+			if !ok {
+				t.Errorf("%T: expected %s", test.engine, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Hints are read from the rdl: annotation of a column or table comment, e.g. "rdl: zipf(1.1) range(1,5000) null(0.3)".
+// They set how the values of the column are generated, CLI settings have priority over them
+type Hints struct {
+	Annotation string   // as written after rdl:, for reporting
+	Generator  string   // gen(email): string generator, named like the columns it would be picked for
+	Zipf       float64  // zipf(s) skews the values toward the first ones, s > 1. 0 means uniform
+	Min, Max   string   // range(min,max): numbers, dates or times
+	Values     []string // values(a,'b',c): the only values of the column
+	Null       float64  // null(0.3): frequency of NULLs
+	HasNull    bool
+}
+
+var (
+	// the annotation runs until the end of the line
+	hintAnnotationRe = regexp.MustCompile(`(?i)\brdl:([^\n]*)`)
+	hintRe           = regexp.MustCompile(`^(\w+)\s*(?:\(([^)]*)\))?`)
+)
+
+// ParseHints reads the rdl: annotation of a comment, nil when there is none.
+// The second value lists the hints that were not understood, they are ignored
+func ParseHints(comment string) (*Hints, []string) {
+	matches := hintAnnotationRe.FindStringSubmatch(comment)
+	if matches == nil {
+		return nil, nil
+	}
+	hints := &Hints{Annotation: strings.TrimSpace(matches[1])}
+	warnings := []string{}
+
+	rest := hints.Annotation
+	for {
+		rest = strings.TrimLeft(rest, " \t\r,;")
+		if rest == "" {
+			break
+		}
+		hint := hintRe.FindStringSubmatch(rest)
+		if hint == nil {
+			unknown, _, _ := strings.Cut(rest, " ")
+			warnings = append(warnings, "unrecognised annotation "+unknown)
+			rest = rest[len(unknown):]
+			continue
+		}
+		rest = rest[len(hint[0]):]
+		if err := hints.set(strings.ToLower(hint[1]), hint[2]); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", strings.TrimSpace(hint[0]), err))
+		}
+	}
+	return hints, warnings
+}
+
+func (h *Hints) set(name, args string) error {
+	values, _ := splitPartitionValues(args)
+	if strings.TrimSpace(args) == "" {
+		values = nil
+	}
+	switch name {
+	case "null":
+		if len(values) != 1 {
+			return errors.New("expected a frequency, e.g. null(0.3)")
+		}
+		freq, err := strconv.ParseFloat(values[0], 64)
+		if err != nil || freq < 0 || freq > 1 {
+			return errors.New("the frequency should be between 0 and 1")
+		}
+		h.Null, h.HasNull = freq, true
+	case "zipf":
+		if len(values) != 1 {
+			return errors.New("expected an exponent, e.g. zipf(1.1)")
+		}
+		s, err := strconv.ParseFloat(values[0], 64)
+		if err != nil || s <= 1 {
+			return errors.New("the exponent should be greater than 1")
+		}
+		h.Zipf = s
+	case "uniform":
+		h.Zipf = 0
+	case "range":
+		if len(values) != 2 || values[0] == "" || values[1] == "" {
+			return errors.New("expected 2 bounds, e.g. range(1,5000)")
+		}
+		h.Min, h.Max = values[0], values[1]
+	case "values":
+		if len(values) == 0 {
+			return errors.New("expected at least a value")
+		}
+		h.Values = values
+	case "gen":
+		if len(values) != 1 || values[0] == "" {
+			return errors.New("expected a generator name, e.g. gen(email)")
+		}
+		h.Generator = strings.ToLower(values[0])
+	default:
+		return errors.New("unrecognised annotation")
+	}
+	return nil
+}
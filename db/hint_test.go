@@ -0,0 +1,43 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHints(t *testing.T) {
+	tests := []struct {
+		comment  string
+		hints    *Hints
+		warnings int
+	}{
+		{comment: "just a comment"},
+		{
+			comment: "quantity ordered. rdl: zipf(1.1) range(1,5000) null(0.3)",
+			hints:   &Hints{Annotation: "zipf(1.1) range(1,5000) null(0.3)", Zipf: 1.1, Min: "1", Max: "5000", Null: 0.3, HasNull: true},
+		},
+		{
+			comment: "RDL: values(new, 'done, paid'); gen(Email)\nsecond line",
+			hints:   &Hints{Annotation: "values(new, 'done, paid'); gen(Email)", Values: []string{"new", "done, paid"}, Generator: "email"},
+		},
+		{
+			comment:  "rdl: zipf(0.5) normal(3) null(2) uniform",
+			hints:    &Hints{Annotation: "zipf(0.5) normal(3) null(2) uniform"},
+			warnings: 3,
+		},
+		{
+			comment:  "rdl: range(1) #",
+			hints:    &Hints{Annotation: "range(1) #"},
+			warnings: 2,
+		},
+	}
+	for _, test := range tests {
+		hints, warnings := ParseHints(test.comment)
+		if !reflect.DeepEqual(hints, test.hints) {
+			t.Errorf("%q: expected %#v, got %#v", test.comment, test.hints, hints)
+		}
+		if len(warnings) != test.warnings {
+			t.Errorf("%q: expected %d warnings, got %v", test.comment, test.warnings, warnings)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/ylacancellera/random-data-load/query"
+)
+
+// MariaDB shares the protocol and most of information_schema with MySQL
+// it only differs on a few datatypes and on columns the database fills by itself
+type MariaDB struct {
+	MySQL
+}
+
+func init() {
+	Register(EngineInfo{Name: "mariadb", DefaultPort: 3306, Dialect: query.DialectMySQL, Engine: MariaDB{}})
+}
+
+// JSON is an alias of LONGTEXT on MariaDB, the only trace left is the json_valid() check constraint
+var mariadbJSONCheckRe = regexp.MustCompile("(?i)^\\s*json_valid\\(\\s*`?([^`)]+)`?\\s*\\)\\s*$")
+
+func (m MariaDB) GetFields(schema, tablename string) ([]Field, error) {
+	fields, err := m.MySQL.GetFields(schema, tablename)
+	if err != nil {
+		return fields, err
+	}
+
+	systemVersioned, err := m.isSystemVersioned(schema, tablename)
+	if err != nil {
+		return []Field{}, errors.Wrapf(err, "mariadb.GetFields: schema: %s, table: %s", schema, tablename)
+	}
+
+	query := "SELECT COLUMN_NAME, IS_GENERATED = 'ALWAYS', EXTRA, COLUMN_DEFAULT " +
+		"FROM `information_schema`.`COLUMNS` " +
+		"WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+
+	rows, err := DB.Query(query, schema, tablename)
+	if err != nil {
+		return []Field{}, errors.Wrapf(err, "mariadb.GetFields: query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	defer rows.Close()
+
+	table := &Table{Fields: fields}
+	for rows.Next() {
+		var (
+			column, extra string
+			generated     bool
+			columnDefault sql.NullString
+		)
+		if err := rows.Scan(&column, &generated, &extra, &columnDefault); err != nil {
+			return []Field{}, errors.Wrap(err, "mariadb.GetFields: cannot read columns")
+		}
+		f := table.FieldByName(column)
+		if f == nil {
+			continue
+		}
+		extra = strings.ToUpper(extra)
+
+		switch {
+		// virtual, persistent and stored columns
+		case generated, strings.Contains(extra, "VIRTUAL GENERATED"), strings.Contains(extra, "STORED GENERATED"), strings.Contains(extra, "PERSISTENT GENERATED"):
+			f.Generated = true
+			f.GeneratedBy = "generated column"
+
+		// ROW_START and ROW_END of system-versioned tables
+		case strings.Contains(extra, "ROW START"), strings.Contains(extra, "ROW END"),
+			systemVersioned && (strings.EqualFold(column, "row_start") || strings.EqualFold(column, "row_end")):
+			f.Generated = true
+			f.GeneratedBy = "system versioning"
+
+		// DEFAULT NEXT VALUE FOR some_sequence, the column is filled like an auto-increment
+		case columnDefault.Valid && isSequenceDefault(columnDefault.String):
+			f.AutoIncrement = true
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return []Field{}, err
+	}
+	rows.Close()
+
+	jsonColumns, err := m.jsonColumns(schema, tablename)
+	if err != nil {
+		return []Field{}, errors.Wrapf(err, "mariadb.GetFields: schema: %s, table: %s", schema, tablename)
+	}
+	for _, column := range jsonColumns {
+		if f := table.FieldByName(column); f != nil && f.DataType == "longtext" {
+			f.DataType = "json"
+		}
+	}
+
+	return fields, nil
+}
+
+func (_ MariaDB) isSystemVersioned(schema, tablename string) (bool, error) {
+	var tableType string
+	err := DB.QueryRow("SELECT TABLE_TYPE FROM `information_schema`.`TABLES` WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?", schema, tablename).Scan(&tableType)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return tableType == "SYSTEM VERSIONED", err
+}
+
+// GetChecks returns table and column CHECK constraints, the table name is part of CHECK_CONSTRAINTS on MariaDB
+func (_ MariaDB) GetChecks(schema, tablename string) ([]Check, error) {
+	rows, err := DB.Query("SELECT CONSTRAINT_NAME, CHECK_CLAUSE FROM `information_schema`.`CHECK_CONSTRAINTS` WHERE CONSTRAINT_SCHEMA = ? AND TABLE_NAME = ? ORDER BY 1", schema, tablename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get checks, schema: %s, table: %s", schema, tablename)
+	}
+	return scanChecks(rows)
+}
+
+func (m MariaDB) jsonColumns(schema, tablename string) ([]string, error) {
+	checks, err := m.GetChecks(schema, tablename)
+	if err != nil {
+		return nil, err
+	}
+	columns := []string{}
+	for _, check := range checks {
+		if matches := mariadbJSONCheckRe.FindStringSubmatch(check.Clause); matches != nil {
+			columns = append(columns, matches[1])
+		}
+	}
+	return columns, nil
+}
+
+// COLUMN_DEFAULT shows "nextval(`db`.`seq`)" for DEFAULT NEXT VALUE FOR db.seq
+func isSequenceDefault(columnDefault string) bool {
+	columnDefault = strings.ToLower(strings.TrimSpace(columnDefault))
+	return strings.HasPrefix(columnDefault, "nextval(") || strings.HasPrefix(columnDefault, "next value for")
+}
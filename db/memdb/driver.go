@@ -0,0 +1,726 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+const driverName = "memdb"
+
+func init() {
+	sql.Register(driverName, Driver{})
+}
+
+var (
+	insertRe = regexp.MustCompile(`(?is)^\s*INSERT INTO\s+(\S+?)\.(\S+?)\s*\((.*?)\)\s*(OVERRIDING SYSTEM VALUE\s+)?VALUES\s*(.*)$`)
+	selectRe = regexp.MustCompile(`(?is)^\s*SELECT\s+(.+?)\s+FROM\s+(\S+?)\.(\S+?)\s+(?:WHERE\s+(.+?)\s+)?ORDER BY 1(\s+DESC)?\s+LIMIT\s+(\d+)(?:\s+OFFSET\s+(\d+))?\s*;?\s*$`)
+	updateRe = regexp.MustCompile(`(?is)^\s*UPDATE\s+(\S+?)\.(\S+?)\s+SET\s+(.+?)\s+WHERE\s+(.+?)\s*;?\s*$`)
+	maxRe    = regexp.MustCompile(`(?is)^\s*SELECT\s+MAX\((\S+?)\)\s+FROM\s+(\S+?)\.(\S+?)\s*;?\s*$`)
+	countRe  = regexp.MustCompile(`(?is)^\s*SELECT\s+COUNT\(\*\)\s+FROM\s+(\S+?)\.(\S+?)\s*;?\s*$`)
+
+	isNotNullRe = regexp.MustCompile(`(?i)^(\S+)\s+IS NOT NULL$`)
+	isNullRe    = regexp.MustCompile(`(?i)^(\S+)\s+IS NULL$`)
+	randomRe    = regexp.MustCompile(`(?i)^random\(\)\s*<\s*([0-9.]+)$`)
+	andRe       = regexp.MustCompile(`(?i)\s+AND\s+`)
+)
+
+// defaultValue marks a column left to its DEFAULT
+type defaultValue struct{}
+
+// the generated values are not escaped, a DEFAULT column is stored as this literal
+const defaultLiteral = "DEFAULT"
+
+// Driver opens connections to databases created with New, the DSN is the database name
+type Driver struct{}
+
+func (Driver) Open(name string) (driver.Conn, error) {
+	d, err := lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{d}, nil
+}
+
+type conn struct {
+	db *Database
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c, query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+// Begin returns a transaction for statements to run as they would, but they are applied immediately: Rollback does not undo them
+func (c *conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error {
+	return nil
+}
+
+func (tx) Rollback() error {
+	return nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if len(args) != 0 {
+		return nil, errors.New("memdb: placeholders are not supported")
+	}
+	if matches := updateRe.FindStringSubmatch(query); matches != nil {
+		n, err := c.db.update(unquote(matches[1]), unquote(matches[2]), matches[3], matches[4])
+		return driver.RowsAffected(n), err
+	}
+	matches := insertRe.FindStringSubmatch(query)
+	if matches == nil {
+		return nil, errors.Errorf("memdb: unsupported statement: %s", query)
+	}
+	n, err := c.db.insert(unquote(matches[1]), unquote(matches[2]), splitIdentifiers(matches[3]), matches[5], matches[4] != "")
+	return driver.RowsAffected(n), err
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) != 0 {
+		return nil, errors.New("memdb: placeholders are not supported")
+	}
+	if matches := countRe.FindStringSubmatch(query); matches != nil {
+		return c.db.selectCount(unquote(matches[1]), unquote(matches[2]))
+	}
+	if matches := maxRe.FindStringSubmatch(query); matches != nil {
+		return c.db.selectMax(unquote(matches[2]), unquote(matches[3]), unquote(matches[1]))
+	}
+	matches := selectRe.FindStringSubmatch(query)
+	if matches == nil {
+		return nil, errors.Errorf("memdb: unsupported query: %s", query)
+	}
+	limit, _ := strconv.Atoi(matches[6])
+	offset, _ := strconv.Atoi(matches[7])
+	return c.db.selectRows(unquote(matches[2]), unquote(matches[3]), splitIdentifiers(matches[1]), matches[4], matches[5] != "", limit, offset)
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return 0
+}
+
+func (s *stmt) Exec(_ []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, nil)
+}
+
+func (s *stmt) Query(_ []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, nil)
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	idx     int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.idx])
+	r.idx++
+	return nil
+}
+
+// insert rejects explicit values into auto-increment columns, like GENERATED ALWAYS identities, unless overriding is set
+func (d *Database) insert(schema, tablename string, columns []string, valuesClause string, overriding bool) (int64, error) {
+	tuples, err := parseTuples(valuesClause)
+	if err != nil {
+		return 0, err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, tablename)]
+	if !ok {
+		return 0, errors.Errorf("relation \"%s.%s\" does not exist", schema, tablename)
+	}
+	colIdx := make([]int, len(columns))
+	for i, column := range columns {
+		colIdx[i] = t.fieldIndex(column)
+		if colIdx[i] < 0 {
+			return 0, errors.Errorf("column \"%s\" of relation \"%s\" does not exist", column, tablename)
+		}
+		if t.Fields[colIdx[i]].Generated {
+			return 0, errors.Errorf("cannot insert a non-DEFAULT value into column \"%s\"", column)
+		}
+	}
+
+	// the statement is atomic, rows are only stored once all of them are valid
+	autoIncrement := t.autoIncrement
+	newRows := [][]any{}
+	for _, tuple := range tuples {
+		if len(tuple) != len(columns) {
+			return 0, errors.Errorf("INSERT has %d expressions for %d target columns", len(tuple), len(columns))
+		}
+		row := make([]any, len(t.Fields))
+		provided := make([]bool, len(t.Fields))
+		for i, value := range tuple {
+			if _, isDefault := value.(defaultValue); !isDefault && t.Fields[colIdx[i]].AutoIncrement && !overriding {
+				return 0, errors.Errorf("cannot insert a non-DEFAULT value into column \"%s\", use OVERRIDING SYSTEM VALUE", columns[i])
+			}
+			row[colIdx[i]] = value
+			provided[colIdx[i]] = true
+		}
+		for i := range t.Fields {
+			if _, isDefault := row[i].(defaultValue); !isDefault && provided[i] {
+				continue
+			}
+			row[i] = t.defaultValue(i, &autoIncrement)
+		}
+		for i, field := range t.Fields {
+			if row[i] == nil && !field.IsNullable {
+				return 0, errors.Errorf("null value in column \"%s\" of relation \"%s\" violates not-null constraint", field.ColumnName, tablename)
+			}
+		}
+		newRows = append(newRows, row)
+	}
+
+	if err := t.checkUniqueKeys(newRows); err != nil {
+		return 0, err
+	}
+	for _, c := range t.Constraints {
+		if err := d.checkForeignKey(t, c.ConstraintName, c.ColumnsName, c.ReferencedTableSchema, c.ReferencedTableName, c.ReferencedColumnsName, newRows); err != nil {
+			return 0, err
+		}
+	}
+
+	t.autoIncrement = autoIncrement
+	t.rows = append(t.rows, newRows...)
+	return int64(len(newRows)), nil
+}
+
+// defaultValue returns the value of a column missing from an INSERT
+func (t *Table) defaultValue(i int, autoIncrement *int64) any {
+	field := t.Fields[i]
+	switch {
+	case field.AutoIncrement:
+		*autoIncrement++
+		return strconv.FormatInt(*autoIncrement, 10)
+	case field.HasDefaultValue || field.Generated:
+		return defaultLiteral
+	}
+	return nil
+}
+
+func (t *Table) checkUniqueKeys(newRows [][]any) error {
+	pk := db.Index{Name: t.Name + "_pkey", Primary: true}
+	for _, field := range t.Fields {
+		if field.ColumnKey == "PRI" {
+			pk.Columns = append(pk.Columns, field.ColumnName)
+		}
+	}
+	for _, index := range append([]db.Index{pk}, t.Indexes...) {
+		if len(index.Columns) == 0 {
+			continue
+		}
+		if err := t.checkUniqueKey(index, newRows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Table) checkUniqueKey(index db.Index, newRows [][]any) error {
+	// a key with a NULL is never a duplicate
+	key := func(row []any) (string, bool) {
+		parts := []string{}
+		for _, column := range index.Columns {
+			value := row[t.fieldIndex(column)]
+			if value == nil {
+				return "", false
+			}
+			parts = append(parts, normalize(value))
+		}
+		return strings.Join(parts, "\x00"), true
+	}
+	seen := map[string]struct{}{}
+	for _, row := range t.rows {
+		if k, ok := key(row); ok {
+			seen[k] = struct{}{}
+		}
+	}
+	for _, row := range newRows {
+		k, ok := key(row)
+		if !ok {
+			continue
+		}
+		if _, ok := seen[k]; ok {
+			return errors.Errorf("duplicate key value violates unique constraint \"%s\"", index.Name)
+		}
+		seen[k] = struct{}{}
+	}
+	return nil
+}
+
+func (d *Database) checkForeignKey(t *Table, name string, columns []string, refSchema, refTablename string, refColumns []string, newRows [][]any) error {
+	refTable, ok := d.tables[tableKey(refSchema, refTablename)]
+	if !ok {
+		return errors.Errorf("foreign key %s references a missing table %s.%s", name, refSchema, refTablename)
+	}
+
+	refKeys := map[string]struct{}{}
+	addKeys := func(table *Table, rows [][]any) {
+		for _, row := range rows {
+			parts := []string{}
+			for _, column := range refColumns {
+				parts = append(parts, normalize(row[table.fieldIndex(column)]))
+			}
+			refKeys[strings.Join(parts, "\x00")] = struct{}{}
+		}
+	}
+	addKeys(refTable, refTable.rows)
+	// self-referencing rows can point to rows of the same statement
+	if refTable == t {
+		addKeys(t, newRows)
+	}
+
+ROWS:
+	for _, row := range newRows {
+		parts := []string{}
+		for _, column := range columns {
+			value := row[t.fieldIndex(column)]
+			// MATCH SIMPLE: any NULL column satisfies the constraint
+			if value == nil {
+				continue ROWS
+			}
+			parts = append(parts, normalize(value))
+		}
+		if _, ok := refKeys[strings.Join(parts, "\x00")]; !ok {
+			return errors.Errorf("insert or update on table \"%s\" violates foreign key constraint \"%s\"", t.Name, name)
+		}
+	}
+	return nil
+}
+
+func (d *Database) selectRows(schema, tablename string, columns []string, where string, desc bool, limit, offset int) (driver.Rows, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, tablename)]
+	if !ok {
+		return nil, errors.Errorf("relation \"%s.%s\" does not exist", schema, tablename)
+	}
+	colIdx := make([]int, len(columns))
+	for i, column := range columns {
+		colIdx[i] = t.fieldIndex(column)
+		if colIdx[i] < 0 {
+			return nil, errors.Errorf("column \"%s\" does not exist", column)
+		}
+	}
+
+	notNullIdx, nullIdx := []int{}, []int{}
+	sampleRatio := 1.0
+	if where != "" {
+		for _, cond := range andRe.Split(where, -1) {
+			cond = strings.TrimSpace(cond)
+			if matches := isNotNullRe.FindStringSubmatch(cond); matches != nil {
+				idx := t.fieldIndex(unquote(matches[1]))
+				if idx < 0 {
+					return nil, errors.Errorf("column \"%s\" does not exist", matches[1])
+				}
+				notNullIdx = append(notNullIdx, idx)
+				continue
+			}
+			if matches := isNullRe.FindStringSubmatch(cond); matches != nil {
+				idx := t.fieldIndex(unquote(matches[1]))
+				if idx < 0 {
+					return nil, errors.Errorf("column \"%s\" does not exist", matches[1])
+				}
+				nullIdx = append(nullIdx, idx)
+				continue
+			}
+			if matches := randomRe.FindStringSubmatch(cond); matches != nil {
+				sampleRatio, _ = strconv.ParseFloat(matches[1], 64)
+				continue
+			}
+			return nil, errors.Errorf("memdb: unsupported condition: %s", cond)
+		}
+	}
+
+	selected := [][]any{}
+ROWS:
+	for _, row := range t.rows {
+		for _, idx := range notNullIdx {
+			if row[idx] == nil {
+				continue ROWS
+			}
+		}
+		for _, idx := range nullIdx {
+			if row[idx] != nil {
+				continue ROWS
+			}
+		}
+		if sampleRatio < 1 && rand.Float64() >= sampleRatio {
+			continue
+		}
+		selected = append(selected, row)
+	}
+
+	slices.SortStableFunc(selected, func(a, b []any) int {
+		if desc {
+			return compare(b[colIdx[0]], a[colIdx[0]])
+		}
+		return compare(a[colIdx[0]], b[colIdx[0]])
+	})
+
+	if offset > len(selected) {
+		offset = len(selected)
+	}
+	selected = selected[offset:]
+	if limit < len(selected) {
+		selected = selected[:limit]
+	}
+
+	r := &rows{columns: columns}
+	for _, row := range selected {
+		values := make([]driver.Value, len(columns))
+		for i, idx := range colIdx {
+			values[i] = toDriverValue(t.Fields[idx].DataType, row[idx])
+		}
+		r.values = append(r.values, values)
+	}
+	return r, nil
+}
+
+// update only handles equality conditions, as written to fill the foreign keys breaking a loop.
+// Unique keys are not checked
+func (d *Database) update(schema, tablename, setClause, whereClause string) (int64, error) {
+	setColumns, setValues, err := parseAssignments(setClause, ",")
+	if err != nil {
+		return 0, err
+	}
+	whereColumns, whereValues, err := parseAssignments(whereClause, "AND")
+	if err != nil {
+		return 0, err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, tablename)]
+	if !ok {
+		return 0, errors.Errorf("relation \"%s.%s\" does not exist", schema, tablename)
+	}
+	setIdx, whereIdx := make([]int, len(setColumns)), make([]int, len(whereColumns))
+	for i, column := range setColumns {
+		setIdx[i] = t.fieldIndex(column)
+		if setIdx[i] < 0 {
+			return 0, errors.Errorf("column \"%s\" of relation \"%s\" does not exist", column, tablename)
+		}
+		field := t.Fields[setIdx[i]]
+		if field.Generated {
+			return 0, errors.Errorf("column \"%s\" can only be updated to DEFAULT", column)
+		}
+		if setValues[i] == nil && !field.IsNullable {
+			return 0, errors.Errorf("null value in column \"%s\" of relation \"%s\" violates not-null constraint", field.ColumnName, tablename)
+		}
+	}
+	for i, column := range whereColumns {
+		whereIdx[i] = t.fieldIndex(column)
+		if whereIdx[i] < 0 {
+			return 0, errors.Errorf("column \"%s\" does not exist", column)
+		}
+	}
+
+	// rows are only changed once all of them are valid
+	matched, updated := []int{}, [][]any{}
+ROWS:
+	for rowIdx, row := range t.rows {
+		for i, idx := range whereIdx {
+			if row[idx] == nil || whereValues[i] == nil || normalize(row[idx]) != normalize(whereValues[i]) {
+				continue ROWS
+			}
+		}
+		newRow := slices.Clone(row)
+		for i, idx := range setIdx {
+			newRow[idx] = setValues[i]
+		}
+		matched = append(matched, rowIdx)
+		updated = append(updated, newRow)
+	}
+
+	for _, c := range t.Constraints {
+		if err := d.checkForeignKey(t, c.ConstraintName, c.ColumnsName, c.ReferencedTableSchema, c.ReferencedTableName, c.ReferencedColumnsName, updated); err != nil {
+			return 0, err
+		}
+	}
+	for i, rowIdx := range matched {
+		t.rows[rowIdx] = updated[i]
+	}
+	return int64(len(matched)), nil
+}
+
+func (d *Database) selectCount(schema, tablename string) (driver.Rows, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, tablename)]
+	if !ok {
+		return nil, errors.Errorf("relation \"%s.%s\" does not exist", schema, tablename)
+	}
+	return &rows{columns: []string{"count"}, values: [][]driver.Value{{int64(len(t.rows))}}}, nil
+}
+
+func (d *Database) selectMax(schema, tablename, column string) (driver.Rows, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, tablename)]
+	if !ok {
+		return nil, errors.Errorf("relation \"%s.%s\" does not exist", schema, tablename)
+	}
+	idx := t.fieldIndex(column)
+	if idx < 0 {
+		return nil, errors.Errorf("column \"%s\" does not exist", column)
+	}
+	var max any
+	for _, row := range t.rows {
+		if row[idx] != nil && (max == nil || compare(row[idx], max) > 0) {
+			max = row[idx]
+		}
+	}
+	return &rows{columns: []string{"max"}, values: [][]driver.Value{{toDriverValue(t.Fields[idx].DataType, max)}}}, nil
+}
+
+// parseTuples reads "(v1, v2),(v3, v4)" as written by the generator
+func parseTuples(s string) ([][]any, error) {
+	tuples := [][]any{}
+	i := 0
+	skipSpaces := func() {
+		for i < len(s) && strings.ContainsRune(" \t\r\n", rune(s[i])) {
+			i++
+		}
+	}
+
+	for {
+		skipSpaces()
+		if i >= len(s) || s[i] == ';' {
+			return tuples, nil
+		}
+		if s[i] != '(' {
+			return nil, errors.Errorf("memdb: expected ( at position %d of %q", i, s)
+		}
+		i++
+
+		tuple := []any{}
+		for {
+			skipSpaces()
+			if i < len(s) && s[i] == ')' && len(tuple) == 0 {
+				i++
+				break
+			}
+			value, next, err := parseValue(s, i)
+			if err != nil {
+				return nil, err
+			}
+			tuple = append(tuple, value)
+			i = next
+			skipSpaces()
+			if i >= len(s) {
+				return nil, errors.Errorf("memdb: unterminated tuple in %q", s)
+			}
+			if s[i] == ',' {
+				i++
+				continue
+			}
+			if s[i] == ')' {
+				i++
+				break
+			}
+			return nil, errors.Errorf("memdb: unexpected %q at position %d of %q", s[i], i, s)
+		}
+		tuples = append(tuples, tuple)
+
+		skipSpaces()
+		if i < len(s) && s[i] == ',' {
+			i++
+		}
+	}
+}
+
+func parseValue(s string, i int) (any, int, error) {
+	if s[i] == '\'' {
+		var sb strings.Builder
+		for i++; i < len(s); i++ {
+			if s[i] != '\'' {
+				sb.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				sb.WriteByte('\'')
+				i++
+				continue
+			}
+			return sb.String(), i + 1, nil
+		}
+		return nil, i, errors.Errorf("memdb: unterminated string in %q", s)
+	}
+
+	start := i
+	for i < len(s) && s[i] != ',' && s[i] != ')' {
+		i++
+	}
+	token := strings.TrimSpace(s[start:i])
+	switch strings.ToUpper(token) {
+	case "":
+		return nil, i, errors.Errorf("memdb: empty value at position %d of %q", start, s)
+	case "NULL":
+		return nil, i, nil
+	case "DEFAULT":
+		return defaultValue{}, i, nil
+	}
+	return token, i, nil
+}
+
+// parseAssignments reads "a = 1, b = 'x'" or "a = 1 AND b = 'x'" depending on the separator
+func parseAssignments(s, separator string) ([]string, []any, error) {
+	columns, values := []string{}, []any{}
+	i := 0
+	for {
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, nil, errors.Errorf("memdb: expected = at position %d of %q", i, s)
+		}
+		columns = append(columns, unquote(strings.TrimSpace(s[i:i+eq])))
+		i += eq + 1
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i >= len(s) {
+			return nil, nil, errors.Errorf("memdb: missing value in %q", s)
+		}
+
+		var value any
+		if s[i] == '\'' {
+			var err error
+			if value, i, err = parseValue(s, i); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			start := i
+			for i < len(s) && s[i] != ' ' && s[i] != ',' {
+				i++
+			}
+			value = s[start:i]
+			if strings.EqualFold(s[start:i], "NULL") {
+				value = nil
+			}
+		}
+		values = append(values, value)
+
+		rest := strings.TrimSpace(s[i:])
+		if rest == "" {
+			return columns, values, nil
+		}
+		if len(rest) < len(separator) || !strings.EqualFold(rest[:len(separator)], separator) {
+			return nil, nil, errors.Errorf("memdb: expected %s at position %d of %q", separator, i, s)
+		}
+		i = len(s) - len(rest) + len(separator)
+	}
+}
+
+func splitIdentifiers(s string) []string {
+	identifiers := []string{}
+	for _, ident := range strings.Split(s, ",") {
+		ident = strings.TrimSpace(ident)
+		if ident != "" {
+			identifiers = append(identifiers, unquote(ident))
+		}
+	}
+	return identifiers
+}
+
+func unquote(ident string) string {
+	return strings.Trim(ident, "\"`")
+}
+
+// numbers are compared by value: sampled decimals are written back as 1.000000
+func normalize(value any) string {
+	s, _ := value.(string)
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return s
+}
+
+func compare(a, b any) int {
+	sa, _ := a.(string)
+	sb, _ := b.(string)
+	fa, errA := strconv.ParseFloat(sa, 64)
+	fb, errB := strconv.ParseFloat(sb, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(sa, sb)
+}
+
+var timeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02", "15:04:05", time.RFC3339Nano}
+
+// toDriverValue returns what a real driver would: samplers scan integers, floats and times
+func toDriverValue(dataType string, value any) driver.Value {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	switch dataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case "float", "decimal", "double", "numeric":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "date", "time", "datetime", "timestamp":
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,252 @@
+// Package memdb is an in-memory stand-in for a database, to test the generation pipeline without docker.
+//
+// It implements db.Engine and a database/sql driver named "memdb".
+// The driver only understands the statements the tool issues: the generated INSERTs and the sampling SELECTs.
+// Inserted rows are checked for NOT NULL, primary keys, unique indexes and foreign keys, like a real database would.
+package memdb
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/ylacancellera/random-data-load/db"
+	"github.com/ylacancellera/random-data-load/query"
+)
+
+const defaultSchema = "public"
+
+// Database holds table definitions and their rows.
+type Database struct {
+	name   string
+	mutex  sync.Mutex
+	tables map[string]*Table
+	views  map[string]string
+}
+
+// Table is a table definition with its rows. NULLs are nil, every other value is kept as its SQL literal.
+type Table struct {
+	Schema        string
+	Name          string
+	Fields        []db.Field
+	Constraints   []db.Constraint
+	Indexes       []db.Index // unique indexes, the primary key is made of the PRI fields
+	Checks        []db.Check // returned to the tool, not enforced
+	Comment       string
+	rows          [][]any
+	autoIncrement int64
+}
+
+// Row maps column names to values. NULLs are nil, every other value is a string.
+type Row map[string]any
+
+var (
+	databases      = map[string]*Database{}
+	databasesMutex sync.Mutex
+)
+
+// New creates an empty database. The name is used to connect to it with --database or sql.Open("memdb", name).
+func New(name string) *Database {
+	d := &Database{name: name, tables: map[string]*Table{}, views: map[string]string{}}
+
+	databasesMutex.Lock()
+	defer databasesMutex.Unlock()
+	databases[name] = d
+	return d
+}
+
+func lookup(name string) (*Database, error) {
+	databasesMutex.Lock()
+	defer databasesMutex.Unlock()
+	d, ok := databases[name]
+	if !ok {
+		return nil, errors.Errorf("memdb: database %s does not exist", name)
+	}
+	return d, nil
+}
+
+func tableKey(schema, name string) string {
+	return strings.ToLower(schema + "." + name)
+}
+
+// CreateTable adds a table definition. An empty schema means "public".
+// Constraints only need their name, columns and referenced table/columns.
+func (d *Database) CreateTable(schema, name string, fields []db.Field, constraints ...db.Constraint) *Table {
+	if schema == "" {
+		schema = defaultSchema
+	}
+	for i := range constraints {
+		if constraints[i].ReferencedTableSchema == "" {
+			constraints[i].ReferencedTableSchema = schema
+		}
+	}
+	t := &Table{Schema: schema, Name: name, Fields: fields, Constraints: constraints}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.tables[tableKey(schema, name)] = t
+	return t
+}
+
+// CreateView adds a view defined by a SELECT. An empty schema means "public".
+// Views are only read by the query parser, they cannot be queried.
+func (d *Database) CreateView(schema, name, definition string) {
+	if schema == "" {
+		schema = defaultSchema
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.views[tableKey(schema, name)] = definition
+}
+
+// Rows returns a copy of every row of the table, in insertion order.
+func (d *Database) Rows(schema, name string) ([]Row, error) {
+	if schema == "" {
+		schema = defaultSchema
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, name)]
+	if !ok {
+		return nil, errors.Errorf("memdb: table %s.%s does not exist", schema, name)
+	}
+	rows := make([]Row, 0, len(t.rows))
+	for _, values := range t.rows {
+		row := Row{}
+		for i, field := range t.Fields {
+			row[field.ColumnName] = values[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (t *Table) fieldIndex(column string) int {
+	for i, field := range t.Fields {
+		if strings.EqualFold(field.ColumnName, column) {
+			return i
+		}
+	}
+	return -1
+}
+
+// db.Engine implementation, the syntax mimics postgres
+
+func (d *Database) Connect(_ db.Config) (*sql.DB, error) {
+	return sql.Open(driverName, d.name)
+}
+
+func (d *Database) GetFields(schema, tablename string) ([]db.Field, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, tablename)]
+	if !ok || len(t.Fields) == 0 {
+		return []db.Field{}, errors.Wrapf(db.ErrFieldsNotFound, "memdb: table %s.%s", schema, tablename)
+	}
+	fields := make([]db.Field, len(t.Fields))
+	copy(fields, t.Fields)
+	return fields, nil
+}
+
+func (d *Database) GetConstraints(schema, tablename string) ([]*db.Constraint, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, tablename)]
+	if !ok {
+		return nil, errors.Errorf("memdb: table %s.%s does not exist", schema, tablename)
+	}
+	// constraints get mutated when loading tables, each load gets its own copy
+	constraints := []*db.Constraint{}
+	for _, c := range t.Constraints {
+		constraints = append(constraints, &db.Constraint{
+			ConstraintName:        c.ConstraintName,
+			ReferencedTableSchema: c.ReferencedTableSchema,
+			ReferencedTableName:   c.ReferencedTableName,
+			ColumnsName:           c.ColumnsName,
+			ReferencedColumnsName: c.ReferencedColumnsName,
+		})
+	}
+	return constraints, nil
+}
+
+func (d *Database) GetIndexes(schema, tablename string) (map[string]db.Index, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, tablename)]
+	if !ok {
+		return nil, errors.Errorf("memdb: table %s.%s does not exist", schema, tablename)
+	}
+	indexes := map[string]db.Index{}
+	for _, index := range t.Indexes {
+		indexes[index.Name] = index
+	}
+	return indexes, nil
+}
+
+func (d *Database) GetChecks(schema, tablename string) ([]db.Check, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, tablename)]
+	if !ok {
+		return nil, errors.Errorf("memdb: table %s.%s does not exist", schema, tablename)
+	}
+	return slices.Clone(t.Checks), nil
+}
+
+func (d *Database) GetTableComment(schema, tablename string) (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	t, ok := d.tables[tableKey(schema, tablename)]
+	if !ok {
+		return "", errors.Errorf("memdb: table %s.%s does not exist", schema, tablename)
+	}
+	return t.Comment, nil
+}
+
+func (d *Database) GetViewDefinition(schema, tablename string) (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.views[tableKey(schema, tablename)], nil
+}
+
+func (_ *Database) InsertTemplate() string {
+	return "INSERT INTO %s.%s (%s)%s VALUES \n"
+}
+
+func (_ *Database) OverridingSystemValue() string {
+	return "OVERRIDING SYSTEM VALUE"
+}
+
+func (_ *Database) DefaultKeyword() string {
+	return "DEFAULT"
+}
+
+func (_ *Database) Escape(s string) string {
+	return "\"" + s + "\""
+}
+
+func (_ *Database) SetTableMetadata(table *db.Table, _, tablename string) {
+	schema, name := query.SplitTableName(tablename)
+	if schema == "" {
+		schema = defaultSchema
+	}
+	table.Schema = schema
+	table.Name = name
+}
+
+func (_ *Database) BinomialWhereClause(freqPercent float64) string {
+	return fmt.Sprintf("WHERE random() < %.10f", freqPercent/100)
+}
+
+func (_ *Database) ErrShouldRetryTx(err error) bool {
+	return strings.Contains(err.Error(), "duplicate key value violates unique constraint")
+}
@@ -0,0 +1,144 @@
+package memdb
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+func testDatabase(t *testing.T) (*Database, *sql.DB) {
+	d := New(t.Name())
+	d.CreateTable("", "parent", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI", AutoIncrement: true},
+		{ColumnName: "name", DataType: "varchar", IsNullable: true},
+	})
+	d.CreateTable("", "child", []db.Field{
+		{ColumnName: "id", DataType: "int", ColumnKey: "PRI"},
+		{ColumnName: "parent_id", DataType: "int", IsNullable: true},
+		{ColumnName: "label", DataType: "varchar", HasDefaultValue: true},
+	}, db.Constraint{ConstraintName: "fk_parent", ColumnsName: []string{"parent_id"}, ReferencedTableName: "parent", ReferencedColumnsName: []string{"id"}})
+
+	conn, err := d.Connect(db.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return d, conn
+}
+
+func TestInsert(t *testing.T) {
+	d, conn := testDatabase(t)
+
+	res, err := conn.Exec(`INSERT INTO "public"."parent" ("name") VALUES ('a'),('it''s, (b)'), (NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 3 {
+		t.Fatalf("expected 3 rows affected, got %d", n)
+	}
+
+	rows, err := d.Rows("", "parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Row{{"id": "1", "name": "a"}, {"id": "2", "name": "it's, (b)"}, {"id": "3", "name": nil}}
+	for i, row := range rows {
+		if row["id"] != expected[i]["id"] || row["name"] != expected[i]["name"] {
+			t.Errorf("row %d: expected %v, got %v", i, expected[i], row)
+		}
+	}
+
+	_, err = conn.Exec(`INSERT INTO "public"."child" ("id","parent_id","label") VALUES (1, 2, DEFAULT), (2, NULL, 'x')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _ = d.Rows("", "child")
+	if rows[0]["label"] != defaultLiteral {
+		t.Errorf("expected the default keyword to be stored, got %v", rows[0]["label"])
+	}
+}
+
+func TestInsertViolations(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		errPart string
+	}{
+		{
+			name:    "not null",
+			query:   `INSERT INTO "public"."child" ("id","parent_id") VALUES (NULL, NULL)`,
+			errPart: "violates not-null constraint",
+		},
+		{
+			name:    "foreign key",
+			query:   `INSERT INTO "public"."child" ("id","parent_id") VALUES (1, 42)`,
+			errPart: "violates foreign key constraint \"fk_parent\"",
+		},
+		{
+			name:    "primary key in the same statement",
+			query:   `INSERT INTO "public"."child" ("id") VALUES (1), (1)`,
+			errPart: "duplicate key value violates unique constraint",
+		},
+		{
+			name:    "unknown column",
+			query:   `INSERT INTO "public"."child" ("nope") VALUES (1)`,
+			errPart: "does not exist",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, conn := testDatabase(t)
+			_, err := conn.Exec(test.query)
+			if err == nil || !strings.Contains(err.Error(), test.errPart) {
+				t.Fatalf("expected an error containing %q, got %v", test.errPart, err)
+			}
+			// statements are atomic
+			if rows, _ := d.Rows("", "child"); len(rows) != 0 {
+				t.Fatalf("expected no rows after a failed insert, got %d", len(rows))
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	_, conn := testDatabase(t)
+
+	_, err := conn.Exec(`INSERT INTO "public"."parent" ("name") VALUES ('c'), (NULL), ('a'), ('b')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := conn.Query(`SELECT "id","name" FROM "public"."parent" WHERE "name" IS NOT NULL ORDER BY 1 LIMIT 2 OFFSET 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 4 {
+		t.Fatalf("expected ids [3 4], got %v", ids)
+	}
+
+	var count int
+	rows, err = conn.Query(`SELECT "id" FROM "public"."parent" WHERE random() < 0.0000000000 AND "id" IS NOT NULL ORDER BY 1 LIMIT 10`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		count++
+	}
+	if count != 0 {
+		t.Fatalf("expected a 0%% sample to be empty, got %d rows", count)
+	}
+}
@@ -4,38 +4,137 @@ import (
 	"database/sql"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
+	"github.com/ylacancellera/random-data-load/query"
 )
 
 type MySQL struct{}
 
+func init() {
+	Register(EngineInfo{Name: "mysql", DefaultPort: 3306, Dialect: query.DialectMySQL, Engine: MySQL{}})
+}
+
 func (_ MySQL) Connect(dbInfo Config) (*sql.DB, error) {
-	netType := "tcp"
-	address := net.JoinHostPort(dbInfo.Host, fmt.Sprintf("%d", dbInfo.Port))
+	cfg := mysql.NewConfig()
+	cfg.User = dbInfo.User
+	cfg.Passwd = dbInfo.Password
+	cfg.DBName = dbInfo.Database
+	cfg.AllowCleartextPasswords = true
+	cfg.AllowNativePasswords = true
+	cfg.AllowOldPasswords = true
+	cfg.CheckConnLiveness = true
+	cfg.ParseTime = true
+
+	if dbInfo.DisableTriggers {
+		return nil, errors.New("MySQL and MariaDB cannot disable triggers for a session, drop them or use --triggers=keep")
+	}
 
-	if dbInfo.Host == "localhost" {
-		netType = "unix"
-		address = dbInfo.Host
+	if dbInfo.Socket != "" {
+		cfg.Net = "unix"
+		cfg.Addr = dbInfo.Socket
+	} else {
+		host := dbInfo.Host
+		if host == "" {
+			host = "localhost"
+		}
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(host, fmt.Sprintf("%d", dbInfo.Port))
+
+		// TLS is pointless on a unix socket
+		tlsConfig, err := dbInfo.tlsClientConfig(host)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLS = tlsConfig
+		cfg.AllowFallbackToPlaintext = dbInfo.TLSMode == "prefer"
+	}
+
+	bulkLoad := [][2]string{{"unique_checks", "0"}, {"foreign_key_checks", "0"}}
+	if dbInfo.BulkLoad {
+		// needs SUPER or SYSTEM_VARIABLES_ADMIN, a failing SET would fail every connection
+		if err := mysqlTrySet(cfg, "sql_log_bin", "0"); err != nil {
+			log.Warn().Err(err).Msg("cannot disable the binary log for the load, the inserts will be replicated")
+		} else {
+			bulkLoad = append(bulkLoad, [2]string{"sql_log_bin", "0"})
+		}
+	}
+	if vars := dbInfo.SessionSettings(bulkLoad); len(vars) > 0 {
+		// the driver sends them in a single SET statement on every new connection
+		cfg.Params = map[string]string{}
+		for _, v := range vars {
+			cfg.Params[v[0]] = v[1]
+		}
 	}
 
-	cfg := &mysql.Config{
-		User:                    dbInfo.User,
-		Passwd:                  dbInfo.Password,
-		Net:                     netType,
-		Addr:                    address,
-		DBName:                  dbInfo.Database,
-		AllowCleartextPasswords: true,
-		AllowNativePasswords:    true,
-		AllowOldPasswords:       true,
-		CheckConnLiveness:       true,
-		ParseTime:               true,
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
 	}
+	return sql.OpenDB(connector), nil
+}
 
-	return sql.Open("mysql", cfg.FormatDSN())
+// mysqlTrySet checks on a separate connection that the session variable can be set
+func mysqlTrySet(cfg *mysql.Config, name, value string) error {
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return err
+	}
+	conn := sql.OpenDB(connector)
+	defer conn.Close()
+	_, err = conn.Exec(fmt.Sprintf("SET SESSION %s = %s", name, value))
+	return err
+}
+
+// loadCredentials reads MYSQL_PWD, then the [client] and [mysql] sections of ~/.my.cnf, like the mysql client
+func (_ MySQL) loadCredentials(dbInfo *Config) error {
+	if dbInfo.Password == "" {
+		dbInfo.Password = os.Getenv("MYSQL_PWD")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	options, err := readOptionFile(filepath.Join(home, ".my.cnf"), "client", "mysql", "client-mariadb", "mariadb-client")
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "cannot read ~/.my.cnf")
+	}
+
+	setIfEmpty := func(field *string, key string) {
+		if v, ok := options[key]; ok && *field == "" {
+			*field = v
+		}
+	}
+	setIfEmpty(&dbInfo.User, "user")
+	setIfEmpty(&dbInfo.Password, "password")
+	setIfEmpty(&dbInfo.Host, "host")
+	setIfEmpty(&dbInfo.Socket, "socket")
+	setIfEmpty(&dbInfo.Database, "database")
+	setIfEmpty(&dbInfo.TLSCA, "ssl-ca")
+	setIfEmpty(&dbInfo.TLSCert, "ssl-cert")
+	setIfEmpty(&dbInfo.TLSKey, "ssl-key")
+	if mode, ok := options["ssl-mode"]; ok && dbInfo.TLSMode == "" {
+		dbInfo.TLSMode = normalizeTLSMode(mode)
+	}
+	if port, ok := options["port"]; ok && dbInfo.Port == 0 {
+		dbInfo.Port, err = strconv.Atoi(port)
+		if err != nil {
+			return errors.Wrapf(err, "invalid port in ~/.my.cnf")
+		}
+	}
+	return nil
 }
 
 // indexField holds raw index information as defined in INFORMATION_SCHEMA table
@@ -69,6 +168,8 @@ func (mysql MySQL) GetFields(schema, tablename string) ([]Field, error) {
 		"COLUMN_KEY",
 		"extra like '%auto_increment%'",
 		"COLUMN_DEFAULT IS NOT NULL",
+		"EXTRA",
+		"COLUMN_COMMENT",
 	}
 
 	query := "SELECT " + strings.Join(selectValues, ",") +
@@ -94,35 +195,22 @@ func (mysql MySQL) GetFields(schema, tablename string) ([]Field, error) {
 		found = true
 
 		var f Field
-		var columnType string
-		scanRecipients := mysql.makeScanRecipients(&f, &columnType, cols)
+		var columnType, extra string
+		scanRecipients := mysql.makeScanRecipients(&f, &columnType, &extra, cols)
 		err := rows.Scan(scanRecipients...)
 		if err != nil {
 			log.Error().Err(err).Msg("cannot get fields")
 			continue
 		}
 
-		allowedValues := []string{}
-		if f.DataType == "enum" || f.DataType == "set" {
-			columnType, ok := strings.CutSuffix(columnType, ")")
-			if !ok {
-				log.Error().Str("columnType", columnType).Msg("unexpected columnType, suffix ) not found")
-				continue
-			}
-			columnType, ok = strings.CutPrefix(columnType, f.DataType+"(")
-			if !ok {
-				log.Error().Str("columnType", columnType).Str("prefix", f.DataType+"(").Msg("unexpected columnType, prefix not found")
-				continue
-			}
-			vals := strings.Split(columnType, ",")
-			for _, val := range vals {
-				val = strings.TrimPrefix(val, "'")
-				val = strings.TrimSuffix(val, "'")
-				allowedValues = append(allowedValues, val)
-			}
+		if err := parseMySQLColumnType(&f, columnType); err != nil {
+			log.Error().Err(err).Str("column", f.ColumnName).Msg("cannot parse the column type")
+			continue
+		}
+		mysqlGeneratedColumn(&f, extra)
+		if slices.Contains(mysqlSpatialTypes, f.DataType) {
+			setGeometryType(&f, "geometry", f.DataType)
 		}
-
-		f.SetEnumVals = allowedValues
 
 		fields = append(fields, f)
 
@@ -131,6 +219,11 @@ func (mysql MySQL) GetFields(schema, tablename string) ([]Field, error) {
 	if rows.Err() != nil {
 		return []Field{}, rows.Err()
 	}
+	if slices.ContainsFunc(fields, func(f Field) bool { return f.DataType == "geometry" }) {
+		if err := mysql.spatialReferences(schema, tablename, fields); err != nil {
+			return []Field{}, err
+		}
+	}
 
 	if !found {
 		return []Field{}, errors.Wrapf(ErrFieldsNotFound, "query: %s", query)
@@ -138,7 +231,136 @@ func (mysql MySQL) GetFields(schema, tablename string) ([]Field, error) {
 	return fields, nil
 }
 
-func (_ MySQL) makeScanRecipients(f *Field, columnType *string, cols []string) []interface{} {
+var mysqlSpatialTypes = []string{"geometry", "point", "linestring", "polygon"}
+
+// spatialReferences sets the SRID of the spatial columns, and whether their spatial reference system puts the latitude first.
+// SRS_ID only exists since MySQL 8.0, older versions and MariaDB have no SRID constraint on the columns
+func (_ MySQL) spatialReferences(schema, tablename string, fields []Field) error {
+	query := `SELECT c.COLUMN_NAME, c.SRS_ID,
+			coalesce(s.DEFINITION LIKE 'GEOGCS%' AND LOCATE('NORTH]', s.DEFINITION) < LOCATE('EAST]', s.DEFINITION), false)
+		FROM information_schema.COLUMNS c
+		LEFT JOIN information_schema.ST_SPATIAL_REFERENCE_SYSTEMS s ON s.SRS_ID = c.SRS_ID
+		WHERE c.TABLE_SCHEMA = ? AND c.TABLE_NAME = ? AND c.SRS_ID IS NOT NULL`
+
+	rows, err := DB.Query(query, schema, tablename)
+	var mysqlErr *mysql.MySQLError
+	// unknown table or column in information_schema
+	if errors.As(err, &mysqlErr) && (mysqlErr.Number == 1109 || mysqlErr.Number == 1054) {
+		log.Debug().Err(err).Msg("SRID of the columns are not supported by this version")
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "get spatial reference systems, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	defer rows.Close()
+	table := &Table{Fields: fields}
+	for rows.Next() {
+		var column string
+		var srid int64
+		var latitudeFirst bool
+		if err := rows.Scan(&column, &srid, &latitudeFirst); err != nil {
+			return errors.Wrap(err, "cannot read spatial reference systems")
+		}
+		if f := table.FieldByName(column); f != nil {
+			f.SRID, f.LatitudeFirst = srid, latitudeFirst
+		}
+	}
+	return rows.Err()
+}
+
+var mysqlColumnTypeRe = regexp.MustCompile(`(?is)^(\w+)(?:\((.*)\))?((?:\s+\w+)*)\s*$`)
+
+// parseMySQLColumnType reads COLUMN_TYPE: the members of enum and set, the width of bit and of integers, unsigned and zerofill
+func parseMySQLColumnType(f *Field, columnType string) error {
+	matches := mysqlColumnTypeRe.FindStringSubmatch(columnType)
+	if matches == nil {
+		return errors.Errorf("unexpected column type %s", columnType)
+	}
+	f.FullIntRange = true
+	args := matches[2]
+	for _, modifier := range strings.Fields(strings.ToLower(matches[3])) {
+		// zerofill implies unsigned
+		if modifier == "unsigned" || modifier == "zerofill" {
+			f.Unsigned = true
+		}
+	}
+
+	switch f.DataType {
+	case "enum", "set":
+		members, err := splitMySQLMembers(args)
+		if err != nil {
+			return errors.Wrapf(err, "column type %s", columnType)
+		}
+		f.SetEnumVals = members
+	case "bit":
+		if width, err := strconv.ParseInt(args, 10, 64); err == nil && !f.NumericPrecision.Valid {
+			f.NumericPrecision = sql.NullInt64{Int64: width, Valid: true}
+		}
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		// only tinyint(1) keeps its display width since MySQL 8.0.19
+		if width, err := strconv.ParseInt(args, 10, 64); err == nil {
+			f.DisplayWidth = width
+		}
+	}
+	return nil
+}
+
+// splitMySQLMembers splits the members of an enum or a set, written back with their quotes doubled and their backslashes escaped:
+//
+//	'a','it''s','b,c' splits into a, it''s and b,c
+func splitMySQLMembers(args string) ([]string, error) {
+	members := []string{}
+	var member strings.Builder
+	quoted := false
+	for i := 0; i < len(args); i++ {
+		c := args[i]
+		switch {
+		case !quoted && c == '\'':
+			quoted = true
+		case !quoted && c == ',':
+		case !quoted:
+			if c != ' ' {
+				return nil, errors.Errorf("unexpected %q outside of the quotes", c)
+			}
+		case c == '\\' && i+1 < len(args):
+			i++
+			member.WriteByte(args[i])
+		case c == '\'' && i+1 < len(args) && args[i+1] == '\'':
+			i++
+			member.WriteByte(c)
+		case c == '\'':
+			quoted = false
+			members = append(members, strings.NewReplacer(`\`, `\\`, "'", "''").Replace(member.String()))
+			member.Reset()
+		default:
+			member.WriteByte(c)
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated member")
+	}
+	return members, nil
+}
+
+// mysqlGeneratedColumn flags the columns we must not insert to.
+// DEFAULT_GENERATED only means the default is an expression, the column can still be inserted
+func mysqlGeneratedColumn(f *Field, extra string) {
+	extra = strings.ToUpper(extra)
+	switch {
+	case strings.Contains(extra, "VIRTUAL GENERATED"), strings.Contains(extra, "STORED GENERATED"):
+		f.Generated = true
+		f.GeneratedBy = "generated column"
+	// generated invisible primary keys, sql_generate_invisible_primary_key=ON
+	case strings.Contains(extra, "INVISIBLE") && f.AutoIncrement && strings.EqualFold(f.ColumnName, "my_row_id"):
+		f.Generated = true
+		f.GeneratedBy = "generated invisible primary key"
+	case strings.Contains(extra, "INVISIBLE"):
+		f.Generated = true
+		f.GeneratedBy = "invisible column"
+	}
+}
+
+func (_ MySQL) makeScanRecipients(f *Field, columnType, extra *string, cols []string) []interface{} {
 	fields := []interface{}{
 		&f.ColumnName,
 		&f.IsNullable,
@@ -151,10 +373,146 @@ func (_ MySQL) makeScanRecipients(f *Field, columnType *string, cols []string) [
 		&f.ColumnKey,
 		&f.AutoIncrement,
 		&f.HasDefaultValue,
+		extra,
+		&f.Comment,
 	}
 
 	return fields
 }
+
+// GetIndexes returns the primary key and unique indexes
+// functional key parts have no column name, such indexes are ignored as we cannot guarantee their uniqueness
+func (_ MySQL) GetIndexes(schema, tableName string) (map[string]Index, error) {
+	query := `SELECT INDEX_NAME,
+			group_concat(COLUMN_NAME ORDER BY SEQ_IN_INDEX SEPARATOR ';'),
+			count(*) = count(COLUMN_NAME)
+		FROM information_schema.STATISTICS
+		WHERE NON_UNIQUE = 0
+			AND TABLE_SCHEMA = ?
+			AND TABLE_NAME = ?
+		GROUP BY INDEX_NAME`
+
+	rows, err := DB.Query(query, schema, tableName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get indexes, query: %s, schema: %s, table: %s", query, schema, tableName)
+	}
+	defer rows.Close()
+
+	indexes := map[string]Index{}
+	for rows.Next() {
+		var (
+			index         Index
+			columnsAgg    sql.NullString
+			onlyOnColumns bool
+		)
+		err := rows.Scan(&index.Name, &columnsAgg, &onlyOnColumns)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read indexes")
+		}
+		if !onlyOnColumns {
+			log.Debug().Str("index", index.Name).Str("table", tableName).Msg("skipping functional index")
+			continue
+		}
+		index.Primary = index.Name == "PRIMARY"
+		index.Columns = strings.Split(columnsAgg.String, ";")
+		indexes[index.Name] = index
+	}
+	return indexes, rows.Err()
+}
+
+// GetChecks returns the enforced CHECK constraints. They are only available since 8.0.16, older versions parse and ignore them
+func (_ MySQL) GetChecks(schema, tableName string) ([]Check, error) {
+	query := `SELECT cc.CONSTRAINT_NAME, cc.CHECK_CLAUSE
+		FROM information_schema.CHECK_CONSTRAINTS cc
+		JOIN information_schema.TABLE_CONSTRAINTS tc
+			ON tc.CONSTRAINT_SCHEMA = cc.CONSTRAINT_SCHEMA
+			AND tc.CONSTRAINT_NAME = cc.CONSTRAINT_NAME
+		WHERE tc.CONSTRAINT_TYPE = 'CHECK'
+			AND tc.ENFORCED = 'YES'
+			AND tc.TABLE_SCHEMA = ?
+			AND tc.TABLE_NAME = ?
+		ORDER BY 1`
+
+	rows, err := DB.Query(query, schema, tableName)
+	var mysqlErr *mysql.MySQLError
+	// unknown table or column in information_schema
+	if errors.As(err, &mysqlErr) && (mysqlErr.Number == 1109 || mysqlErr.Number == 1054) {
+		log.Debug().Err(err).Msg("CHECK constraints are not supported by this version")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "get checks, query: %s, schema: %s, table: %s", query, schema, tableName)
+	}
+	return scanChecks(rows)
+}
+
+// GetPartitions returns the first level of partitions, nil when the table is not partitioned
+func (_ MySQL) GetPartitions(schema, tableName string) (*Partitioning, error) {
+	query := `SELECT PARTITION_NAME,
+			PARTITION_METHOD,
+			coalesce(PARTITION_EXPRESSION, ''),
+			coalesce(PARTITION_DESCRIPTION, ''),
+			coalesce(sum(TABLE_ROWS), 0)
+		FROM information_schema.PARTITIONS
+		WHERE PARTITION_NAME IS NOT NULL
+			AND TABLE_SCHEMA = ?
+			AND TABLE_NAME = ?
+		GROUP BY PARTITION_NAME, PARTITION_ORDINAL_POSITION, PARTITION_METHOD, PARTITION_EXPRESSION, PARTITION_DESCRIPTION
+		ORDER BY PARTITION_ORDINAL_POSITION`
+
+	rows, err := DB.Query(query, schema, tableName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get partitions, query: %s, schema: %s, table: %s", query, schema, tableName)
+	}
+	defer rows.Close()
+
+	var partitioning *Partitioning
+	previousBound := ""
+	for rows.Next() {
+		var (
+			partition                       Partition
+			method, expression, description string
+		)
+		if err := rows.Scan(&partition.Name, &method, &expression, &description, &partition.Rows); err != nil {
+			return nil, errors.Wrap(err, "cannot read partitions")
+		}
+		if partitioning == nil {
+			partitioning = &Partitioning{Expression: expression}
+			// RANGE COLUMNS, LINEAR HASH, LINEAR KEY
+			switch method = strings.ToUpper(method); {
+			case strings.HasPrefix(method, "RANGE"):
+				partitioning.Method = PartitionRange
+			case strings.HasPrefix(method, "LIST"):
+				partitioning.Method = PartitionList
+			default:
+				partitioning.Method = PartitionHash
+			}
+			partitioning.parsePartitionKey()
+		}
+
+		values, quoted := splitPartitionValues(description)
+		switch partitioning.Method {
+		case PartitionRange:
+			// each partition starts where the previous one ends
+			partition.From = previousBound
+			if len(values) > 0 && !isUnboundedPartitionValue(values[0], quoted[0]) {
+				partition.To = values[0]
+			}
+			previousBound = partition.To
+		case PartitionList:
+			for i, value := range values {
+				if !quoted[i] && strings.EqualFold(value, "NULL") {
+					partition.Null = true
+					continue
+				}
+				partition.Values = append(partition.Values, value)
+			}
+		}
+		partitioning.Partitions = append(partitioning.Partitions, partition)
+	}
+	return partitioning, rows.Err()
+}
+
 func (_ MySQL) GetConstraints(schema, tableName string) ([]*Constraint, error) {
 	query := `SELECT tc.CONSTRAINT_NAME,
 			kcu.REFERENCED_TABLE_SCHEMA,
@@ -196,8 +554,153 @@ func (_ MySQL) GetConstraints(schema, tableName string) ([]*Constraint, error) {
 	return constraints, nil
 }
 
+// GetViewDefinition returns the SELECT of the view, an empty string when the table is not a view
+func (_ MySQL) GetViewDefinition(schema, tableName string) (string, error) {
+	var definition string
+	query := "SELECT VIEW_DEFINITION FROM information_schema.VIEWS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+	err := DB.QueryRow(query, schema, tableName).Scan(&definition)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "get view definition, query: %s, schema: %s, table: %s", query, schema, tableName)
+	}
+	return definition, nil
+}
+
+// GetTriggers returns the triggers firing on INSERT or UPDATE
+func (_ MySQL) GetTriggers(schema, tableName string) ([]Trigger, error) {
+	query := `SELECT TRIGGER_NAME, ACTION_TIMING, EVENT_MANIPULATION, ACTION_STATEMENT
+		FROM information_schema.TRIGGERS
+		WHERE EVENT_OBJECT_SCHEMA = ?
+			AND EVENT_OBJECT_TABLE = ?
+			AND EVENT_MANIPULATION IN ('INSERT', 'UPDATE')
+		ORDER BY ACTION_TIMING, EVENT_MANIPULATION, ACTION_ORDER`
+	rows, err := DB.Query(query, schema, tableName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get triggers, query: %s, schema: %s, table: %s", query, schema, tableName)
+	}
+	// unqualified tables are in the database of the trigger
+	return scanTriggers(rows, schema, true)
+}
+
 func (_ MySQL) InsertTemplate() string {
-	return "INSERT INTO %s.%s (%s) VALUES \n"
+	return "INSERT INTO %s.%s (%s)%s VALUES \n"
+}
+
+func (_ MySQL) DefaultKeyword() string {
+	return "DEFAULT"
+}
+
+// SyncSequences sets AUTO_INCREMENT after MAX(col), InnoDB does not go below it anyway
+func (mysql MySQL) SyncSequences(table *Table) error {
+	for _, field := range table.Fields {
+		if !field.AutoIncrement {
+			continue
+		}
+		highest, err := MaxInt(table, field.ColumnName)
+		if err != nil {
+			return errors.Wrapf(err, "cannot get the highest value of %s.%s", table.Name, field.ColumnName)
+		}
+		if highest < 1 {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE %s.%s AUTO_INCREMENT = %d", mysql.Escape(table.Schema), mysql.Escape(table.Name), highest+1)
+		if _, err := DB.Exec(query); err != nil {
+			return errors.Wrapf(err, "sync auto-increment, query: %s", query)
+		}
+		log.Debug().Str("table", table.Name).Str("column", field.ColumnName).Int64("auto_increment", highest+1).Msg("auto-increment synced")
+	}
+	return nil
+}
+
+func (_ MySQL) GetTableComment(schema, tableName string) (string, error) {
+	query := "SELECT TABLE_COMMENT FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+	var comment sql.NullString
+	err := DB.QueryRow(query, schema, tableName).Scan(&comment)
+	if err != nil {
+		return "", errors.Wrapf(err, "get table comment, query: %s, schema: %s, table: %s", query, schema, tableName)
+	}
+	return comment.String, nil
+}
+
+// GetSecondaryIndexes returns the non unique indexes, rebuilt from their key parts. Functional indexes are ignored
+func (mysql MySQL) GetSecondaryIndexes(schema, tableName string) ([]SecondaryIndex, error) {
+	query := `SELECT INDEX_NAME, INDEX_TYPE, COLUMN_NAME, SUB_PART, COLLATION = 'D'
+		FROM information_schema.STATISTICS
+		WHERE NON_UNIQUE = 1
+			AND TABLE_SCHEMA = ?
+			AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX`
+
+	rows, err := DB.Query(query, schema, tableName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get secondary indexes, query: %s, schema: %s, table: %s", query, schema, tableName)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	kinds := map[string]string{}
+	keyParts := map[string][]string{}
+	functional := map[string]bool{}
+	for rows.Next() {
+		var (
+			name, indexType string
+			column          sql.NullString
+			subPart         sql.NullInt64
+			desc            sql.NullBool
+		)
+		if err := rows.Scan(&name, &indexType, &column, &subPart, &desc); err != nil {
+			return nil, errors.Wrap(err, "cannot read secondary indexes")
+		}
+		if _, ok := kinds[name]; !ok {
+			names = append(names, name)
+			kinds[name] = "INDEX"
+			if indexType == "FULLTEXT" || indexType == "SPATIAL" {
+				kinds[name] = indexType + " INDEX"
+			}
+		}
+		if !column.Valid {
+			functional[name] = true
+			continue
+		}
+		part := mysql.Escape(strings.ReplaceAll(column.String, "`", "``"))
+		if subPart.Valid {
+			part += fmt.Sprintf("(%d)", subPart.Int64)
+		}
+		if desc.Bool {
+			part += " DESC"
+		}
+		keyParts[name] = append(keyParts[name], part)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	indexes := []SecondaryIndex{}
+	for _, name := range names {
+		if functional[name] {
+			log.Debug().Str("index", name).Str("table", tableName).Msg("skipping functional index")
+			continue
+		}
+		indexes = append(indexes, SecondaryIndex{
+			Name:       name,
+			Definition: fmt.Sprintf("ALTER TABLE %s.%s ADD %s %s (%s)", mysql.Escape(schema), mysql.Escape(tableName), kinds[name], mysql.Escape(name), strings.Join(keyParts[name], ", ")),
+		})
+	}
+	return indexes, nil
+}
+
+// DropIndex fails on the indexes needed by a foreign key
+func (mysql MySQL) DropIndex(table *Table, index SecondaryIndex) error {
+	query := fmt.Sprintf("ALTER TABLE %s.%s DROP INDEX %s", mysql.Escape(table.Schema), mysql.Escape(table.Name), mysql.Escape(index.Name))
+	_, err := DB.Exec(query)
+	return errors.Wrapf(err, "drop index, query: %s", query)
+}
+
+func (_ MySQL) CreateIndex(_ *Table, index SecondaryIndex) error {
+	_, err := DB.Exec(index.Definition)
+	return errors.Wrapf(err, "create index, query: %s", index.Definition)
 }
 
 func (_ MySQL) Escape(s string) string {
@@ -207,9 +710,14 @@ func (_ MySQL) Escape(s string) string {
 	return "`" + s + "`"
 }
 
+// SetTableMetadata uses the database of the connection, unless the table is qualified by another one
 func (_ MySQL) SetTableMetadata(table *Table, database, tablename string) {
-	table.Schema = database
-	table.Name = tablename
+	schema, name := query.SplitTableName(tablename)
+	if schema == "" {
+		schema = database
+	}
+	table.Schema = schema
+	table.Name = name
 }
 
 func (_ MySQL) BinomialWhereClause(freqPercent float64) string {
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestParseMySQLColumnType(t *testing.T) {
+	tests := []struct {
+		dataType   string
+		columnType string
+		expected   Field
+	}{
+		{dataType: "int", columnType: "int", expected: Field{}},
+		{dataType: "int", columnType: "int unsigned", expected: Field{Unsigned: true}},
+		{dataType: "tinyint", columnType: "tinyint(1)", expected: Field{DisplayWidth: 1}},
+		{dataType: "smallint", columnType: "smallint(5) unsigned zerofill", expected: Field{Unsigned: true, DisplayWidth: 5}},
+		{dataType: "bigint", columnType: "bigint(20) ZEROFILL", expected: Field{Unsigned: true, DisplayWidth: 20}},
+		{dataType: "bit", columnType: "bit(12)", expected: Field{NumericPrecision: sql.NullInt64{Int64: 12, Valid: true}}},
+		{dataType: "decimal", columnType: "decimal(10,2) unsigned", expected: Field{Unsigned: true}},
+		{dataType: "enum", columnType: "enum('a','b, c','it''s','back\\\\slash','')", expected: Field{SetEnumVals: []string{"a", "b, c", "it''s", `back\\slash`, ""}}},
+		{dataType: "set", columnType: "set('x','y')", expected: Field{SetEnumVals: []string{"x", "y"}}},
+	}
+	for _, test := range tests {
+		f := Field{DataType: test.dataType}
+		if err := parseMySQLColumnType(&f, test.columnType); err != nil {
+			t.Errorf("%s: %s", test.columnType, err)
+			continue
+		}
+		test.expected.DataType = test.dataType
+		test.expected.FullIntRange = true
+		if !reflect.DeepEqual(f, test.expected) {
+			t.Errorf("%s: expected %#v, got %#v", test.columnType, test.expected, f)
+		}
+	}
+
+	for _, columnType := range []string{"enum('a", "enum(a)"} {
+		f := Field{DataType: "enum"}
+		if err := parseMySQLColumnType(&f, columnType); err == nil {
+			t.Errorf("%s: expected an error", columnType)
+		}
+	}
+}
+
+func TestSplitMySQLMembers(t *testing.T) {
+	tests := []struct {
+		args     string
+		expected []string
+	}{
+		{args: "", expected: []string{}},
+		{args: "'a','b'", expected: []string{"a", "b"}},
+		{args: "'a', 'b'", expected: []string{"a", "b"}},
+		{args: "'a, b','c,d',','", expected: []string{"a, b", "c,d", ","}},
+		{args: "'it''s',''''", expected: []string{"it''s", "''"}},
+		{args: `'back\\slash','it\'s','\,'`, expected: []string{`back\\slash`, "it''s", ","}},
+	}
+	for _, test := range tests {
+		members, err := splitMySQLMembers(test.args)
+		if err != nil {
+			t.Errorf("%s: %s", test.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(members, test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.args, test.expected, members)
+		}
+	}
+
+	for _, args := range []string{"'a", "'a','b", "a", "'a',b", `'a\'`} {
+		if _, err := splitMySQLMembers(args); err == nil {
+			t.Errorf("%s: expected an error", args)
+		}
+	}
+}
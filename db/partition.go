@@ -0,0 +1,110 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Partitioning describes how a table is partitioned. Sub-partitions are not loaded, rows only need to fit in a partition
+type Partitioning struct {
+	Method     string // range, list or hash
+	Expression string // the partition key as the database prints it
+	Column     string // the partitioned column, empty when the key is an expression or spans several columns
+	Function   string // MySQL function applied to Column: year, to_days, to_seconds or unix_timestamp
+	Partitions []Partition
+}
+
+// Partition bounds are literals without their quotes.
+// Range partitions hold values from From, included, to To, excluded. Empty bounds are MINVALUE and MAXVALUE
+type Partition struct {
+	Name     string
+	From, To string
+	Values   []string // list partitions
+	Null     bool     // list partitions holding NULL
+	Default  bool     // holds the rows no other partition accepts
+	Rows     int64    // estimated by the database
+}
+
+const (
+	PartitionRange = "range"
+	PartitionList  = "list"
+	PartitionHash  = "hash"
+)
+
+var (
+	// `col`, "col", col or year(`col`)
+	partitionKeyRe = regexp.MustCompile("^\\s*(?:(\\w+)\\s*\\(\\s*)?[`\"]?(\\w+)[`\"]?\\s*\\)?\\s*$")
+
+	partitionFunctions = map[string]bool{"year": true, "to_days": true, "to_seconds": true, "unix_timestamp": true}
+)
+
+// parsePartitionKey sets Column and Function when the key is a single column, or a supported function of a column
+func (p *Partitioning) parsePartitionKey() {
+	matches := partitionKeyRe.FindStringSubmatch(p.Expression)
+	if matches == nil {
+		return
+	}
+	function := strings.ToLower(matches[1])
+	if function != "" && !partitionFunctions[function] {
+		return
+	}
+	p.Function = function
+	p.Column = matches[2]
+}
+
+// splitPartitionValues splits a list of literals, like 1,'a',NULL. Quotes are removed and doubled quotes unescaped
+// The second value reports which values were quoted
+func splitPartitionValues(s string) ([]string, []bool) {
+	values, quoted := []string{}, []bool{}
+	var (
+		current  strings.Builder
+		inQuotes bool
+		isQuoted bool
+	)
+	flush := func() {
+		value := current.String()
+		if !isQuoted {
+			value = strings.TrimSpace(value)
+		}
+		values = append(values, value)
+		quoted = append(quoted, isQuoted)
+		current.Reset()
+		isQuoted = false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inQuotes && c == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			current.WriteByte(c)
+			i++
+		case c == '\'':
+			// spaces before an opening quote
+			if !inQuotes && !isQuoted {
+				current.Reset()
+			}
+			inQuotes = !inQuotes
+			isQuoted = true
+		case !inQuotes && c == ',':
+			flush()
+		// casts printed by postgres, '2020-01-01'::date
+		case !inQuotes && c == ':' && i+1 < len(s) && s[i+1] == ':':
+			for i+1 < len(s) && s[i+1] != ',' {
+				i++
+			}
+		case !inQuotes && isQuoted:
+			// spaces after a closing quote
+		default:
+			current.WriteByte(c)
+		}
+	}
+	if current.Len() > 0 || isQuoted || len(values) > 0 {
+		flush()
+	}
+	return values, quoted
+}
+
+// isUnboundedPartitionValue reports MINVALUE and MAXVALUE bounds
+func isUnboundedPartitionValue(value string, quoted bool) bool {
+	return !quoted && (strings.EqualFold(value, "MAXVALUE") || strings.EqualFold(value, "MINVALUE"))
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePartitionKey(t *testing.T) {
+	tests := []struct {
+		expression string
+		column     string
+		function   string
+	}{
+		{expression: "`created_at`", column: "created_at"},
+		{expression: `"CreatedAt"`, column: "CreatedAt"},
+		{expression: "year(`created_at`)", column: "created_at", function: "year"},
+		{expression: "TO_DAYS(created_at)", column: "created_at", function: "to_days"},
+		{expression: "`a`,`b`"},
+		{expression: "lower(name)"},
+		{expression: "(id % 10)"},
+	}
+	for _, test := range tests {
+		p := Partitioning{Expression: test.expression}
+		p.parsePartitionKey()
+		if p.Column != test.column || p.Function != test.function {
+			t.Errorf("%s: expected column %q and function %q, got %q and %q", test.expression, test.column, test.function, p.Column, p.Function)
+		}
+	}
+}
+
+func TestSplitPartitionValues(t *testing.T) {
+	tests := []struct {
+		input  string
+		values []string
+		quoted []bool
+	}{
+		{input: "MAXVALUE", values: []string{"MAXVALUE"}, quoted: []bool{false}},
+		{input: "1, 2,3", values: []string{"1", "2", "3"}, quoted: []bool{false, false, false}},
+		{input: "'fr', 'it''s, fine',NULL", values: []string{"fr", "it's, fine", "NULL"}, quoted: []bool{true, true, false}},
+		{input: "'2020-01-01'::date, MINVALUE", values: []string{"2020-01-01", "MINVALUE"}, quoted: []bool{true, false}},
+		{input: "''", values: []string{""}, quoted: []bool{true}},
+	}
+	for _, test := range tests {
+		values, quoted := splitPartitionValues(test.input)
+		if !reflect.DeepEqual(values, test.values) || !reflect.DeepEqual(quoted, test.quoted) {
+			t.Errorf("%s: expected %q %v, got %q %v", test.input, test.values, test.quoted, values, quoted)
+		}
+	}
+}
@@ -3,11 +3,16 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
+	"github.com/ylacancellera/random-data-load/query"
 )
 
 var postgresTypeMapping = map[string]string{
@@ -17,25 +22,117 @@ var postgresTypeMapping = map[string]string{
 	"time with time zone":         "time",
 	"timestamp with time zone":    "timestamp",
 	"timestamp without time zone": "timestamp",
+	"jsonb":                       "json",
+	// bit strings, unlike the integer bit of MySQL
+	"bit":         "bitstring",
+	"bit varying": "varbit",
+}
+
+// mapPostgresType renames the type read from information_schema to the one of the generators.
+// Integers have a fixed size, their values span the whole range of the type
+func mapPostgresType(f *Field) {
+	if replacment, ok := postgresTypeMapping[f.DataType]; ok {
+		f.DataType = replacment
+	}
+	switch f.DataType {
+	case "smallint", "integer", "bigint":
+		f.FullIntRange = true
+	}
 }
 
 type Postgres struct{}
 
+func init() {
+	Register(EngineInfo{Name: "pg", Aliases: []string{"postgres", "postgresql"}, DefaultPort: 5432, Dialect: query.DialectPostgres, Engine: Postgres{}})
+}
+
+// Connect lets lib/pq fill what is missing from the PG* environment variables and ~/.pgpass, like libpq
 func (_ Postgres) Connect(dbInfo Config) (*sql.DB, error) {
-	return sql.Open("postgres", fmt.Sprintf("user=%s dbname=%s password=%s sslmode=disable host=%s port=%d ", dbInfo.User, dbInfo.Database, dbInfo.Password, dbInfo.Host, dbInfo.Port))
+	params := [][2]string{
+		{"user", dbInfo.User},
+		{"password", dbInfo.Password},
+		{"dbname", dbInfo.Database},
+		{"host", dbInfo.Host},
+		{"sslrootcert", dbInfo.TLSCA},
+		{"sslcert", dbInfo.TLSCert},
+		{"sslkey", dbInfo.TLSKey},
+		// lib/pq sends unknown parameters as run-time parameters of the session
+		{"search_path", dbInfo.SearchPath},
+	}
+	if dbInfo.DisableTriggers {
+		// needs a superuser, or the SET privilege on the parameter since PostgreSQL 15
+		params = append(params, [2]string{"session_replication_role", "replica"})
+	}
+	// commits do not wait for the WAL to be flushed, a crash loses the last transactions but not consistency
+	params = append(params, dbInfo.SessionSettings([][2]string{{"synchronous_commit", "off"}})...)
+	if dbInfo.Port != 0 {
+		params = append(params, [2]string{"port", strconv.Itoa(dbInfo.Port)})
+	}
+	if dbInfo.Socket != "" {
+		// libpq expects the directory, the port is part of the socket file name
+		socketDir, socketFile := filepath.Split(dbInfo.Socket)
+		if port, ok := strings.CutPrefix(socketFile, ".s.PGSQL."); ok {
+			params = append(params, [2]string{"host", filepath.Clean(socketDir)}, [2]string{"port", port})
+		} else {
+			params = append(params, [2]string{"host", dbInfo.Socket})
+		}
+	}
+	sslmode := dbInfo.TLSMode
+	if sslmode == "" && os.Getenv("PGSSLMODE") == "" {
+		// lib/pq would require TLS otherwise, TLS is opt-in
+		sslmode = "disable"
+	}
+	params = append(params, [2]string{"sslmode", sslmode})
+
+	var dsn strings.Builder
+	for _, param := range params {
+		if param[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&dsn, "%s=%s ", param[0], pgQuoteParam(param[1]))
+	}
+
+	connector, err := pq.NewConnector(dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// https://www.postgresql.org/docs/current/libpq-connect.html#LIBPQ-CONNSTRING-KEYWORD-VALUE
+func pgQuoteParam(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
 }
 
 func (postgres Postgres) GetFields(schema, tablename string) ([]Field, error) {
+	// a domain gives its base type, and can be NOT NULL itself or through its own base domains
 	query := `SELECT
 		column_name, 
-		is_nullable::boolean, 
+		is_nullable::boolean AND NOT coalesce((
+			WITH RECURSIVE domains AS (
+				SELECT t.typbasetype, t.typnotnull FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+				WHERE n.nspname = domain_schema AND t.typname = domain_name
+				UNION ALL
+				SELECT t.typbasetype, t.typnotnull FROM pg_type t JOIN domains d ON t.oid = d.typbasetype WHERE t.typtype = 'd'
+			) SELECT bool_or(typnotnull) FROM domains), false),
 		data_type, 
 		coalesce(character_maximum_length, 2000),
 		numeric_precision, 
 		numeric_scale, 
 		CASE WHEN is_identity='YES' THEN 'PRI' else '' END,
 		CASE WHEN identity_generation='ALWAYS' THEN true else false END,
-		column_default is not null
+		column_default is not null,
+		is_generated = 'ALWAYS',
+		coalesce(col_description(format('%I.%I', table_schema, table_name)::regclass, ordinal_position), ''),
+		udt_schema,
+		udt_name,
+		dtd_identifier,
+		(SELECT a.atttypmod FROM pg_attribute a WHERE a.attrelid = format('%I.%I', table_schema, table_name)::regclass AND a.attname = column_name),
+		EXISTS (SELECT 1 FROM pg_constraint x
+			CROSS JOIN LATERAL unnest(x.conkey, x.conexclop) AS k(attnum, op)
+			JOIN pg_operator o ON o.oid = k.op
+			WHERE x.conrelid = format('%I.%I', table_schema, table_name)::regclass AND x.contype = 'x'
+				AND k.attnum = ordinal_position::int AND o.oprname = '&&')
 	FROM information_schema.columns
 	WHERE table_schema=$1 AND table_name=$2`
 
@@ -52,22 +149,26 @@ func (postgres Postgres) GetFields(schema, tablename string) ([]Field, error) {
 
 	var found bool
 	fields := []Field{}
+	udts := []pgUDT{}
 	for rows.Next() {
 		found = true
 		var f Field
 
 		var columnType string
-		scanRecipients := postgres.makeScanRecipients(&f, &columnType, cols)
+		var udt pgUDT
+		scanRecipients := append(postgres.makeScanRecipients(&f, &columnType, cols), &udt.schema, &udt.name, &udt.dtdIdentifier, &udt.typmod, &f.NoOverlap)
 		err := rows.Scan(scanRecipients...)
 		if err != nil {
 			log.Error().Err(err).Msg("cannot get fields")
 			continue
 		}
 
-		if replacment, ok := postgresTypeMapping[f.DataType]; ok {
-			f.DataType = replacment
+		if f.Generated {
+			f.GeneratedBy = "generated column"
 		}
+		mapPostgresType(&f)
 		fields = append(fields, f)
+		udts = append(udts, udt)
 	}
 	if err = rows.Err(); err != nil {
 		return []Field{}, err
@@ -75,9 +176,174 @@ func (postgres Postgres) GetFields(schema, tablename string) ([]Field, error) {
 	if !found {
 		return []Field{}, errors.Wrapf(ErrFieldsNotFound, "query: %s", query)
 	}
+	for i := range fields {
+		if err := postgres.resolveType(&fields[i], udts[i], schema, tablename, "TABLE"); err != nil {
+			return []Field{}, err
+		}
+	}
 	return fields, nil
 }
 
+// pgUDT is the type of a column or of an attribute, resolved once the fields are read
+type pgUDT struct {
+	schema, name  string
+	dtdIdentifier string // identifies the array types in information_schema.element_types
+	typmod        int    // length or precision, applied to the elements of arrays
+}
+
+// resolveType completes the USER-DEFINED and ARRAY fields of a table, or of a composite type
+func (postgres Postgres) resolveType(f *Field, udt pgUDT, objectSchema, objectName, objectType string) error {
+	switch f.DataType {
+	case "USER-DEFINED":
+		if udt.name == "geometry" || udt.name == "geography" {
+			return postgres.resolveSpatial(f, udt)
+		}
+		return postgres.resolveUserDefined(f, udt.schema, udt.name)
+	case "ARRAY":
+		return postgres.resolveArray(f, udt, objectSchema, objectName, objectType)
+	}
+	return nil
+}
+
+// resolveSpatial reads the shape and the SRID kept in the modifier of PostGIS columns, like geometry(Point,4326)
+func (postgres Postgres) resolveSpatial(f *Field, udt pgUDT) error {
+	query := `
+SELECT format_type(t.oid, $3)
+FROM pg_type t
+JOIN pg_namespace n ON n.oid = t.typnamespace
+WHERE n.nspname = $1 AND t.typname = $2`
+	var formatted string
+	if err := DB.QueryRow(query, udt.schema, udt.name, udt.typmod).Scan(&formatted); err != nil {
+		return errors.Wrapf(err, "resolve type %s.%s, query: %s", udt.schema, udt.name, query)
+	}
+	parsePostGISType(f, formatted)
+	return nil
+}
+
+// resolveArray turns arrays into "array" fields when their element type is supported.
+// The elements are generated like a nullable column of the same name
+func (postgres Postgres) resolveArray(f *Field, udt pgUDT, objectSchema, objectName, objectType string) error {
+	query := `
+SELECT data_type, udt_schema, udt_name
+FROM information_schema.element_types
+WHERE object_schema = $1 AND object_name = $2 AND object_type = $3 AND collection_type_identifier = $4`
+	element := Field{ColumnName: f.ColumnName, IsNullable: true, CharacterMaximumLength: sql.NullInt64{Int64: 2000, Valid: true}}
+	var elementUDT pgUDT
+	err := DB.QueryRow(query, objectSchema, objectName, objectType, udt.dtdIdentifier).Scan(&element.DataType, &elementUDT.schema, &elementUDT.name)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "resolve the elements of %s.%s, query: %s", objectName, f.ColumnName, query)
+	}
+
+	// varchar(n)[] and numeric(p,s)[] keep n, p and s in the modifier of the column
+	if modifier := udt.typmod - 4; modifier >= 0 {
+		switch element.DataType {
+		case "character varying", "character":
+			element.CharacterMaximumLength = sql.NullInt64{Int64: int64(modifier), Valid: true}
+		case "numeric":
+			element.NumericPrecision = sql.NullInt64{Int64: int64(modifier >> 16), Valid: true}
+			element.NumericScale = sql.NullInt64{Int64: int64(modifier & 0xffff), Valid: true}
+		}
+	}
+	mapPostgresType(&element)
+	if err := postgres.resolveUserDefined(&element, elementUDT.schema, elementUDT.name); err != nil {
+		return err
+	}
+	if isSupportedType(element.DataType) && element.DataType != "array" {
+		f.DataType, f.Element = "array", &element
+	}
+	return nil
+}
+
+// resolveUserDefined turns USER-DEFINED enums into "enum" fields with their labels, composite types into "composite" fields with their attributes,
+// and citext into "citext". Other user-defined types, from extensions, stay unsupported
+func (postgres Postgres) resolveUserDefined(f *Field, udtSchema, udtName string) error {
+	if f.DataType != "USER-DEFINED" {
+		return nil
+	}
+	query := `
+SELECT t.typtype::text,
+	array(SELECT replace(e.enumlabel, '''', '''''') FROM pg_enum e WHERE e.enumtypid = t.oid ORDER BY e.enumsortorder)
+FROM pg_type t
+JOIN pg_namespace n ON n.oid = t.typnamespace
+WHERE n.nspname = $1 AND t.typname = $2`
+	var kind string
+	var labels []string
+	err := DB.QueryRow(query, udtSchema, udtName).Scan(&kind, pq.Array(&labels))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "resolve type %s.%s, query: %s", udtSchema, udtName, query)
+	}
+
+	switch kind {
+	case "b":
+		// base types of extensions, generated like the type they look like
+		if udtName == "citext" {
+			f.DataType = "citext"
+		}
+	case "e":
+		f.DataType, f.SetEnumVals = "enum", labels
+	case "c":
+		attributes, err := postgres.compositeAttributes(udtSchema, udtName)
+		if err != nil {
+			return err
+		}
+		f.DataType, f.Attributes = "composite", attributes
+	}
+	return nil
+}
+
+func (postgres Postgres) compositeAttributes(udtSchema, udtName string) ([]Field, error) {
+	query := `
+SELECT attribute_name,
+	data_type,
+	coalesce(character_maximum_length, 2000),
+	numeric_precision,
+	numeric_scale,
+	attribute_udt_schema,
+	attribute_udt_name,
+	dtd_identifier,
+	coalesce((SELECT a.atttypmod FROM pg_attribute a
+		JOIN pg_type t ON t.typrelid = a.attrelid
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE n.nspname = udt_schema AND t.typname = udt_name AND a.attname = attribute_name), -1)
+FROM information_schema.attributes
+WHERE udt_schema = $1 AND udt_name = $2
+ORDER BY ordinal_position`
+	rows, err := DB.Query(query, udtSchema, udtName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get attributes of %s.%s, query: %s", udtSchema, udtName, query)
+	}
+	defer rows.Close()
+
+	attributes := []Field{}
+	udts := []pgUDT{}
+	for rows.Next() {
+		f := Field{IsNullable: true}
+		var udt pgUDT
+		if err := rows.Scan(&f.ColumnName, &f.DataType, &f.CharacterMaximumLength, &f.NumericPrecision, &f.NumericScale, &udt.schema, &udt.name, &udt.dtdIdentifier, &udt.typmod); err != nil {
+			return nil, errors.Wrapf(err, "get attributes of %s.%s", udtSchema, udtName)
+		}
+		mapPostgresType(&f)
+		attributes = append(attributes, f)
+		udts = append(udts, udt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	for i := range attributes {
+		if err := postgres.resolveType(&attributes[i], udts[i], udtSchema, udtName, "USER-DEFINED TYPE"); err != nil {
+			return nil, err
+		}
+	}
+	return attributes, nil
+}
+
 func (_ Postgres) makeScanRecipients(f *Field, columnType *string, cols []string) []interface{} {
 	fields := []interface{}{
 		&f.ColumnName,
@@ -91,6 +357,8 @@ func (_ Postgres) makeScanRecipients(f *Field, columnType *string, cols []string
 		&f.ColumnKey,
 		&f.AutoIncrement,
 		&f.HasDefaultValue,
+		&f.Generated,
+		&f.Comment,
 	}
 
 	return fields
@@ -138,23 +406,371 @@ ORDER BY c.constraint_name;
 
 	return constraints, nil
 }
+
+// GetIndexes returns the primary key and unique indexes
+// partial and expression indexes are ignored, as well as INCLUDE columns
+func (_ Postgres) GetIndexes(schema, tablename string) (map[string]Index, error) {
+	query := `
+SELECT i.relname,
+	ix.indisprimary,
+	array_to_string(array(
+		SELECT a.attname
+		FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE k.ord <= ix.indnkeyatts
+		ORDER BY k.ord), ';')
+FROM pg_index ix
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_class i ON i.oid = ix.indexrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+WHERE ix.indisunique
+	AND ix.indexprs IS NULL
+	AND ix.indpred IS NULL
+	AND n.nspname = $1
+	AND t.relname = $2
+		`
+	rows, err := DB.Query(query, schema, tablename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get indexes, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	defer rows.Close()
+
+	indexes := map[string]Index{}
+	for rows.Next() {
+		var index Index
+		var columnsAgg string
+		err := rows.Scan(&index.Name, &index.Primary, &columnsAgg)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read indexes")
+		}
+		index.Columns = strings.Split(columnsAgg, ";")
+		indexes[index.Name] = index
+	}
+	return indexes, rows.Err()
+}
+
+// GetChecks returns the CHECK constraints, without the CHECK keyword and the NOT VALID / NO INHERIT options
+func (_ Postgres) GetChecks(schema, tablename string) ([]Check, error) {
+	// the checks of the domains of the columns, and of their base domains, are checks on the column once VALUE is replaced
+	query := `
+WITH RECURSIVE domains AS (
+	SELECT a.attname, a.atttypid AS typid
+	FROM pg_attribute a
+	JOIN pg_class t ON t.oid = a.attrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	WHERE n.nspname = $1 AND t.relname = $2 AND a.attnum > 0 AND NOT a.attisdropped
+	UNION ALL
+	SELECT d.attname, ty.typbasetype
+	FROM domains d
+	JOIN pg_type ty ON ty.oid = d.typid
+	WHERE ty.typtype = 'd'
+)
+SELECT c.conname, pg_get_constraintdef(c.oid)
+FROM pg_constraint c
+JOIN pg_class t ON t.oid = c.conrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+WHERE c.contype = 'c'
+	AND n.nspname = $1
+	AND t.relname = $2
+UNION ALL
+SELECT d.attname || '.' || c.conname, regexp_replace(pg_get_constraintdef(c.oid), '\mVALUE\M', quote_ident(d.attname), 'g')
+FROM domains d
+JOIN pg_constraint c ON c.contypid = d.typid
+WHERE c.contype = 'c'
+ORDER BY 1
+		`
+	rows, err := DB.Query(query, schema, tablename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get checks, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	checks, err := scanChecks(rows)
+	for i := range checks {
+		clause := strings.TrimPrefix(checks[i].Clause, "CHECK ")
+		clause = strings.TrimSuffix(clause, " NOT VALID")
+		checks[i].Clause = strings.TrimSuffix(clause, " NO INHERIT")
+	}
+	return checks, err
+}
+
+var (
+	pgPartitionKeyRe   = regexp.MustCompile(`^(\w+) \((.*)\)$`)
+	pgRangeBoundRe     = regexp.MustCompile(`^FOR VALUES FROM \((.*)\) TO \((.*)\)$`)
+	pgListBoundRe      = regexp.MustCompile(`^FOR VALUES IN \((.*)\)$`)
+	pgPartitionMethods = map[string]string{"RANGE": PartitionRange, "LIST": PartitionList, "HASH": PartitionHash}
+)
+
+// GetPartitions returns the partitions of a declaratively partitioned table, nil when the table is not partitioned
+func (_ Postgres) GetPartitions(schema, tablename string) (*Partitioning, error) {
+	query := `
+SELECT pg_get_partkeydef(p.oid), c.relname, pg_get_expr(c.relpartbound, c.oid), greatest(c.reltuples, 0)::bigint
+FROM pg_partitioned_table pt
+JOIN pg_class p ON p.oid = pt.partrelid
+JOIN pg_namespace n ON n.oid = p.relnamespace
+JOIN pg_inherits i ON i.inhparent = p.oid
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE n.nspname = $1
+	AND p.relname = $2
+ORDER BY c.relname
+		`
+	rows, err := DB.Query(query, schema, tablename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get partitions, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	defer rows.Close()
+
+	var partitioning *Partitioning
+	for rows.Next() {
+		var (
+			partition     Partition
+			keydef, bound string
+		)
+		if err := rows.Scan(&keydef, &partition.Name, &bound, &partition.Rows); err != nil {
+			return nil, errors.Wrap(err, "cannot read partitions")
+		}
+		if partitioning == nil {
+			// RANGE (created_at), LIST (lower(name))
+			partitioning = &Partitioning{Method: PartitionHash, Expression: keydef}
+			if matches := pgPartitionKeyRe.FindStringSubmatch(keydef); matches != nil {
+				partitioning.Method = pgPartitionMethods[matches[1]]
+				partitioning.Expression = matches[2]
+				partitioning.parsePartitionKey()
+				// functions are MySQL ones
+				if partitioning.Function != "" {
+					partitioning.Column, partitioning.Function = "", ""
+				}
+			}
+		}
+
+		switch {
+		case bound == "DEFAULT":
+			partition.Default = true
+		case pgRangeBoundRe.MatchString(bound):
+			matches := pgRangeBoundRe.FindStringSubmatch(bound)
+			from, fromQuoted := splitPartitionValues(matches[1])
+			to, toQuoted := splitPartitionValues(matches[2])
+			if len(from) > 0 && !isUnboundedPartitionValue(from[0], fromQuoted[0]) {
+				partition.From = from[0]
+			}
+			if len(to) > 0 && !isUnboundedPartitionValue(to[0], toQuoted[0]) {
+				partition.To = to[0]
+			}
+		case pgListBoundRe.MatchString(bound):
+			values, quoted := splitPartitionValues(pgListBoundRe.FindStringSubmatch(bound)[1])
+			for i, value := range values {
+				if !quoted[i] && value == "NULL" {
+					partition.Null = true
+					continue
+				}
+				partition.Values = append(partition.Values, value)
+			}
+		}
+		partitioning.Partitions = append(partitioning.Partitions, partition)
+	}
+	return partitioning, rows.Err()
+}
+
+// GetViewDefinition returns the SELECT of the view or materialized view, an empty string when the table is not a view
+func (_ Postgres) GetViewDefinition(schema, tablename string) (string, error) {
+	var definition string
+	query := `
+SELECT definition FROM pg_views WHERE schemaname = $1 AND viewname = $2
+UNION ALL
+SELECT definition FROM pg_matviews WHERE schemaname = $1 AND matviewname = $2
+		`
+	err := DB.QueryRow(query, schema, tablename).Scan(&definition)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "get view definition, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	return definition, nil
+}
+
+// GetTriggers returns the enabled triggers firing on INSERT or UPDATE, with the source of their function
+func (_ Postgres) GetTriggers(schema, tablename string) ([]Trigger, error) {
+	// tgtype bits: 2 BEFORE, 4 INSERT, 16 UPDATE, 64 INSTEAD OF
+	query := `
+SELECT t.tgname,
+	CASE WHEN t.tgtype & 2 = 2 THEN 'BEFORE' WHEN t.tgtype & 64 = 64 THEN 'INSTEAD OF' ELSE 'AFTER' END,
+	e.event,
+	coalesce(p.prosrc, '')
+FROM pg_trigger t
+JOIN pg_class c ON c.oid = t.tgrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+JOIN pg_proc p ON p.oid = t.tgfoid
+JOIN (VALUES (4, 'INSERT'), (16, 'UPDATE')) e(bit, event) ON t.tgtype & e.bit = e.bit
+WHERE NOT t.tgisinternal
+	AND t.tgenabled <> 'D'
+	AND n.nspname = $1
+	AND c.relname = $2
+ORDER BY t.tgname, e.bit
+		`
+	rows, err := DB.Query(query, schema, tablename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get triggers, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	// unqualified tables follow the search_path of the session firing the trigger
+	return scanTriggers(rows, schema, false)
+}
+
 func (_ Postgres) InsertTemplate() string {
-	return "INSERT INTO %s.%s (%s) VALUES \n"
+	return "INSERT INTO %s.%s (%s)%s VALUES \n"
+}
+
+func (_ Postgres) DefaultKeyword() string {
+	return "DEFAULT"
+}
+
+// OverridingSystemValue lets explicit values into GENERATED ALWAYS identity columns
+func (_ Postgres) OverridingSystemValue() string {
+	return "OVERRIDING SYSTEM VALUE"
+}
+
+// SyncSequences moves the sequences of serial and identity columns to MAX(col), when they are behind
+func (postgres Postgres) SyncSequences(table *Table) error {
+	query := `
+SELECT a.attname, pg_get_serial_sequence(format('%I.%I', n.nspname, c.relname), a.attname)
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = $1
+	AND c.relname = $2
+	AND a.attnum > 0
+	AND NOT a.attisdropped
+	AND pg_get_serial_sequence(format('%I.%I', n.nspname, c.relname), a.attname) IS NOT NULL
+		`
+	rows, err := DB.Query(query, table.Schema, table.Name)
+	if err != nil {
+		return errors.Wrapf(err, "get sequences, query: %s, schema: %s, table: %s", query, table.Schema, table.Name)
+	}
+	sequences := [][2]string{}
+	for rows.Next() {
+		var column, sequence string
+		if err := rows.Scan(&column, &sequence); err != nil {
+			rows.Close()
+			return errors.Wrap(err, "cannot read sequences")
+		}
+		sequences = append(sequences, [2]string{column, sequence})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range sequences {
+		column, sequence := s[0], s[1]
+		// the sequence name is already quoted by pg_get_serial_sequence
+		setval := fmt.Sprintf(`SELECT setval(%s, m) FROM (SELECT max(%s) AS m FROM %s.%s) t
+WHERE m > (SELECT CASE WHEN is_called THEN last_value ELSE last_value - 1 END FROM %s)`,
+			pq.QuoteLiteral(sequence), postgres.Escape(column), postgres.Escape(table.Schema), postgres.Escape(table.Name), sequence)
+		var value int64
+		err := DB.QueryRow(setval).Scan(&value)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "sync sequence, query: %s", setval)
+		}
+		log.Debug().Str("table", table.Name).Str("column", column).Str("sequence", sequence).Int64("value", value).Msg("sequence synced")
+	}
+	return nil
+}
+
+func (_ Postgres) GetTableComment(schema, tablename string) (string, error) {
+	query := "SELECT coalesce(obj_description(format('%I.%I', $1::text, $2::text)::regclass, 'pg_class'), '')"
+	var comment string
+	err := DB.QueryRow(query, schema, tablename).Scan(&comment)
+	if err != nil {
+		return "", errors.Wrapf(err, "get table comment, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	return comment, nil
+}
+
+// GetSecondaryIndexes returns the indexes that are neither unique nor backing a constraint
+func (_ Postgres) GetSecondaryIndexes(schema, tablename string) ([]SecondaryIndex, error) {
+	query := `
+SELECT i.relname, pg_get_indexdef(ix.indexrelid)
+FROM pg_index ix
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_class i ON i.oid = ix.indexrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+WHERE NOT ix.indisunique
+	AND NOT ix.indisprimary
+	AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = ix.indexrelid)
+	AND n.nspname = $1
+	AND t.relname = $2
+ORDER BY i.relname
+		`
+	rows, err := DB.Query(query, schema, tablename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get secondary indexes, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	defer rows.Close()
+
+	indexes := []SecondaryIndex{}
+	for rows.Next() {
+		var index SecondaryIndex
+		if err := rows.Scan(&index.Name, &index.Definition); err != nil {
+			return nil, errors.Wrap(err, "cannot read secondary indexes")
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, rows.Err()
+}
+
+func (postgres Postgres) DropIndex(table *Table, index SecondaryIndex) error {
+	query := fmt.Sprintf("DROP INDEX %s.%s", postgres.Escape(table.Schema), postgres.Escape(index.Name))
+	_, err := DB.Exec(query)
+	return errors.Wrapf(err, "drop index, query: %s", query)
+}
+
+// CreateIndex runs the definition given by pg_get_indexdef, the table is qualified by its schema
+func (_ Postgres) CreateIndex(_ *Table, index SecondaryIndex) error {
+	_, err := DB.Exec(index.Definition)
+	return errors.Wrapf(err, "create index, query: %s", index.Definition)
+}
+
+// SetLogged rewrites the table, an UNLOGGED table is not written to the WAL and is emptied after a crash
+func (postgres Postgres) SetLogged(table *Table, logged bool) error {
+	persistence := "UNLOGGED"
+	if logged {
+		persistence = "LOGGED"
+	}
+	query := fmt.Sprintf("ALTER TABLE %s.%s SET %s", postgres.Escape(table.Schema), postgres.Escape(table.Name), persistence)
+	_, err := DB.Exec(query)
+	return errors.Wrapf(err, "set table persistence, query: %s", query)
 }
 
 func (_ Postgres) Escape(s string) string {
 	return "\"" + s + "\""
 }
 
+// SetTableMetadata resolves unqualified tables along the search_path, like the query would
 func (_ Postgres) SetTableMetadata(table *Table, database, tablename string) {
 	// database is useless for catalogs, it's only used for connection on pg
-	schema := "public"
-	if elems := strings.Split(tablename, "."); len(elems) > 1 {
-		schema = elems[0]
-		tablename = elems[1]
+	schema, name := query.SplitTableName(tablename)
+	if schema == "" {
+		schema = searchPathSchema(name)
 	}
 	table.Schema = schema
-	table.Name = tablename
+	table.Name = name
+}
+
+// searchPathSchema returns the schema of the first table with this name along the search_path, "public" when there is none
+func searchPathSchema(tablename string) string {
+	if DB == nil {
+		return "public"
+	}
+	var schema string
+	query := `SELECT n.nspname FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE c.oid = to_regclass(quote_ident($1))`
+	err := DB.QueryRow(query, tablename).Scan(&schema)
+	if err != nil {
+		// a missing table is reported when loading its columns
+		log.Debug().Err(err).Str("table", tablename).Msg("table not found along the search_path, assuming public")
+		return "public"
+	}
+	return schema
 }
 
 func (_ Postgres) BinomialWhereClause(freqPercent float64) string {
@@ -0,0 +1,24 @@
+package db
+
+import "testing"
+
+func TestMapPostgresType(t *testing.T) {
+	tests := []struct {
+		dataType string
+		expected Field
+	}{
+		{dataType: "smallint", expected: Field{DataType: "smallint", FullIntRange: true}},
+		{dataType: "integer", expected: Field{DataType: "integer", FullIntRange: true}},
+		{dataType: "bigint", expected: Field{DataType: "bigint", FullIntRange: true}},
+		{dataType: "numeric", expected: Field{DataType: "decimal"}},
+		{dataType: "character varying", expected: Field{DataType: "varchar"}},
+		{dataType: "text", expected: Field{DataType: "text"}},
+	}
+	for _, test := range tests {
+		f := Field{DataType: test.dataType}
+		mapPostgresType(&f)
+		if f.DataType != test.expected.DataType || f.FullIntRange != test.expected.FullIntRange {
+			t.Errorf("%s: expected %s, full range %v, got %s, %v", test.dataType, test.expected.DataType, test.expected.FullIntRange, f.DataType, f.FullIntRange)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// EngineInfo describes an engine to the CLI and to the query parser
+type EngineInfo struct {
+	Name        string
+	Aliases     []string
+	DefaultPort int    // used when --port is not given, 0 when irrelevant
+	Dialect     string // query parser dialect, see query.Dialects
+	Engine      Engine
+}
+
+var (
+	registry      = map[string]EngineInfo{}
+	registryMutex sync.RWMutex
+)
+
+var ErrUnknownEngine = errors.New("unsupported engine")
+
+// Register makes an engine available as --engine=name, or any of its aliases
+// registering an existing name replaces it, so that an engine can stand in for a real database
+func Register(info EngineInfo) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[info.Name] = info
+}
+
+// LookupEngine finds an engine by name or alias
+func LookupEngine(name string) (EngineInfo, error) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	if info, ok := registry[name]; ok {
+		return info, nil
+	}
+	for _, info := range registry {
+		if slices.Contains(info.Aliases, name) {
+			return info, nil
+		}
+	}
+	return EngineInfo{}, errors.Wrapf(ErrUnknownEngine, "%s, expected one of %s", name, strings.Join(engineNames(), ","))
+}
+
+// EngineNames lists every registered name and alias, sorted. It is used to validate --engine
+func EngineNames() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return engineNames()
+}
+
+func engineNames() []string {
+	names := []string{}
+	for name, info := range registry {
+		names = append(names, name)
+		names = append(names, info.Aliases...)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dialect returns the query parser dialect of an engine
+func Dialect(name string) (string, error) {
+	info, err := LookupEngine(name)
+	return info.Dialect, err
+}
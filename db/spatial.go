@@ -0,0 +1,41 @@
+package db
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GeometryTypes are the shapes generated, a column without a shape gets any of them
+var GeometryTypes = []string{"point", "linestring", "polygon"}
+
+// geometry, geometry(Point), geography(Polygon,4326), as written by format_type()
+var pgSpatialTypeRe = regexp.MustCompile(`^(\w+)(?:\((\w+)(?:,\s*(\d+))?\))?$`)
+
+// setGeometryType turns a spatial column into a "geometry" or "geography" field of the shape.
+// Other shapes, like multipolygon or pointz, are left unsupported
+func setGeometryType(f *Field, dataType, shape string) {
+	shape = strings.ToLower(shape)
+	if shape == "geometry" {
+		shape = ""
+	}
+	if shape != "" && !slices.Contains(GeometryTypes, shape) {
+		return
+	}
+	f.DataType, f.GeometryType = dataType, shape
+}
+
+// parsePostGISType reads the shape and the SRID of geometry(Point,4326), geographies default to WGS 84
+func parsePostGISType(f *Field, formatted string) {
+	matches := pgSpatialTypeRe.FindStringSubmatch(formatted)
+	if matches == nil {
+		return
+	}
+	srid, _ := strconv.ParseInt(matches[3], 10, 64)
+	if matches[1] == "geography" && srid == 0 {
+		srid = 4326
+	}
+	f.SRID = srid
+	setGeometryType(f, matches[1], matches[2])
+}
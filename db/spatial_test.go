@@ -0,0 +1,26 @@
+package db
+
+import "testing"
+
+func TestParsePostGISType(t *testing.T) {
+	tests := []struct {
+		formatted string
+		dataType  string
+		shape     string
+		srid      int64
+	}{
+		{formatted: "geometry", dataType: "geometry"},
+		{formatted: "geometry(Point)", dataType: "geometry", shape: "point"},
+		{formatted: "geometry(Polygon,3857)", dataType: "geometry", shape: "polygon", srid: 3857},
+		{formatted: "geography", dataType: "geography", srid: 4326},
+		{formatted: "geography(LineString,4269)", dataType: "geography", shape: "linestring", srid: 4269},
+		{formatted: "geometry(MultiPolygon,4326)", dataType: "USER-DEFINED", srid: 4326},
+	}
+	for _, test := range tests {
+		f := Field{DataType: "USER-DEFINED"}
+		parsePostGISType(&f, test.formatted)
+		if f.DataType != test.dataType || f.GeometryType != test.shape || f.SRID != test.srid {
+			t.Errorf("%s: expected %s %q of SRID %d, got %s %q of SRID %d", test.formatted, test.dataType, test.shape, test.srid, f.DataType, f.GeometryType, f.SRID)
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/ylacancellera/random-data-load/query"
+	_ "modernc.org/sqlite"
+)
+
+// SQLite only has storage classes, declared types are mapped using the affinity rules when no exact match is found
+// https://www.sqlite.org/datatype3.html#determination_of_column_affinity
+var sqliteTypeMapping = map[string]string{
+	"integer":           "bigint",
+	"int":               "bigint",
+	"real":              "double",
+	"double precision":  "double",
+	"numeric":           "decimal",
+	"character varying": "varchar",
+	"clob":              "text",
+	"datetime":          "datetime",
+	"timestamp":         "timestamp",
+	"date":              "date",
+	"time":              "time",
+	"bool":              "bool",
+	"boolean":           "boolean",
+	"bit":               "bool", // SQLite has no bit literal
+	"uuid":              "uuid",
+}
+
+var sqliteTypeRe = regexp.MustCompile(`^\s*([a-z ]+?)\s*(?:\(\s*(\d+)\s*(?:,\s*(\d+)\s*)?\))?\s*$`)
+
+type SQLite struct{}
+
+func init() {
+	// the query syntax is close enough to postgres for what we extract
+	Register(EngineInfo{Name: "sqlite", Aliases: []string{"sqlite3"}, Dialect: query.DialectPostgres, Engine: SQLite{}})
+}
+
+func (_ SQLite) Connect(dbInfo Config) (*sql.DB, error) {
+	if dbInfo.DisableTriggers {
+		return nil, errors.New("SQLite cannot disable triggers for a session, drop them or use --triggers=keep")
+	}
+	// --database is the path to the database file
+	// foreign keys are not enforced by default on SQLite
+	dsn := "file:" + dbInfo.Database + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(10000)"
+	// session variables are PRAGMAs, run by the driver on every new connection
+	for _, v := range dbInfo.SessionSettings([][2]string{{"synchronous", "OFF"}}) {
+		dsn += "&_pragma=" + url.QueryEscape(v[0]+"("+v[1]+")")
+	}
+	return sql.Open("sqlite", dsn)
+}
+
+func (sqlite SQLite) GetFields(schema, tablename string) ([]Field, error) {
+	// table_xinfo also lists generated columns, flagged by hidden=2 (virtual) or 3 (stored)
+	query := `SELECT name, "notnull", type, pk, dflt_value IS NOT NULL, hidden,
+		(SELECT count(*) FROM pragma_table_info(?1, ?2) WHERE pk > 0)
+	FROM pragma_table_xinfo(?1, ?2)
+	ORDER BY cid`
+
+	rows, err := DB.Query(query, tablename, schema)
+	if err != nil {
+		return []Field{}, errors.Wrapf(err, "sqlite.GetFields: query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	defer rows.Close()
+
+	var found bool
+	fields := []Field{}
+	for rows.Next() {
+		found = true
+		var (
+			f                 Field
+			notNull           bool
+			declaredType      string
+			pk, hidden, lenPk int
+		)
+		err := rows.Scan(&f.ColumnName, &notNull, &declaredType, &pk, &f.HasDefaultValue, &hidden, &lenPk)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot get fields")
+			continue
+		}
+		// hidden columns of virtual tables are skipped, generated columns can still be referenced by foreign keys
+		switch hidden {
+		case 1:
+			log.Debug().Str("field", f.ColumnName).Str("table", tablename).Msg("skipping hidden column")
+			continue
+		case 2, 3:
+			f.Generated = true
+			f.GeneratedBy = "generated column"
+		}
+
+		f.IsNullable = !notNull
+		sqlite.setDataType(&f, declaredType)
+
+		if pk > 0 {
+			f.ColumnKey = "PRI"
+			f.IsNullable = false
+			// a single INTEGER PRIMARY KEY is an alias of the rowid, it gets filled automatically
+			if lenPk == 1 && strings.EqualFold(strings.TrimSpace(declaredType), "integer") {
+				f.AutoIncrement = true
+			}
+		}
+		fields = append(fields, f)
+	}
+	if err = rows.Err(); err != nil {
+		return []Field{}, err
+	}
+	if !found {
+		return []Field{}, errors.Wrapf(ErrFieldsNotFound, "query: %s", query)
+	}
+
+	ddl, err := sqlite.tableDDL(schema, tablename)
+	if err != nil {
+		return []Field{}, err
+	}
+	_, comments := sqliteComments(ddl)
+	for i := range fields {
+		fields[i].Comment = comments[strings.ToLower(fields[i].ColumnName)]
+	}
+	return fields, nil
+}
+
+func (_ SQLite) setDataType(f *Field, declaredType string) {
+	declaredType = strings.ToLower(declaredType)
+
+	base := declaredType
+	if matches := sqliteTypeRe.FindStringSubmatch(declaredType); matches != nil {
+		base = matches[1]
+		if matches[2] != "" {
+			length, _ := strconv.ParseInt(matches[2], 10, 64)
+			f.CharacterMaximumLength = sql.NullInt64{Int64: length, Valid: true}
+			f.NumericPrecision = sql.NullInt64{Int64: length, Valid: true}
+		}
+		if matches[3] != "" {
+			scale, _ := strconv.ParseInt(matches[3], 10, 64)
+			f.NumericScale = sql.NullInt64{Int64: scale, Valid: true}
+		}
+	}
+
+	if replacement, ok := sqliteTypeMapping[base]; ok {
+		f.DataType = replacement
+	} else if isSupportedType(base) {
+		f.DataType = base
+	} else {
+		switch {
+		case strings.Contains(base, "int"):
+			f.DataType = "bigint"
+		case strings.Contains(base, "char"), strings.Contains(base, "clob"), strings.Contains(base, "text"):
+			f.DataType = "text"
+		case base == "", strings.Contains(base, "blob"):
+			f.DataType = "blob"
+		case strings.Contains(base, "real"), strings.Contains(base, "floa"), strings.Contains(base, "doub"):
+			f.DataType = "double"
+		default:
+			f.DataType = "decimal"
+		}
+	}
+
+	if !f.CharacterMaximumLength.Valid {
+		// SQLite does not enforce any length, it will be capped by --max-text-size
+		f.CharacterMaximumLength = sql.NullInt64{Int64: 65535, Valid: true}
+	}
+}
+
+// GetIndexes returns the primary key and unique indexes, partial indexes are ignored
+// an INTEGER PRIMARY KEY has no index, it is added from the fields by LoadTable
+func (_ SQLite) GetIndexes(schema, tablename string) (map[string]Index, error) {
+	// expression parts have no name
+	query := `SELECT l.name, l.origin = 'pk',
+		(SELECT group_concat(name, ';') FROM (SELECT name FROM pragma_index_info(l.name, ?2) ORDER BY seqno))
+	FROM pragma_index_list(?1, ?2) l
+	WHERE l."unique" AND NOT l.partial
+		AND NOT EXISTS (SELECT 1 FROM pragma_index_info(l.name, ?2) WHERE name IS NULL)`
+
+	rows, err := DB.Query(query, tablename, schema)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get indexes, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	defer rows.Close()
+
+	indexes := map[string]Index{}
+	for rows.Next() {
+		var index Index
+		var columnsAgg string
+		err := rows.Scan(&index.Name, &index.Primary, &columnsAgg)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read indexes")
+		}
+		index.Columns = strings.Split(columnsAgg, ";")
+		indexes[index.Name] = index
+	}
+	return indexes, rows.Err()
+}
+
+// tableDDL returns the CREATE TABLE statement, as it was written
+func (sqlite SQLite) tableDDL(schema, tablename string) (string, error) {
+	var ddl string
+	query := fmt.Sprintf("SELECT sql FROM %s.sqlite_master WHERE type = 'table' AND name = ?1", sqlite.Escape(schema))
+	err := DB.QueryRow(query, tablename).Scan(&ddl)
+	return ddl, errors.Wrapf(err, "get table ddl, query: %s, schema: %s, table: %s", query, schema, tablename)
+}
+
+// GetChecks extracts the CHECK clauses of the CREATE TABLE statement, SQLite does not expose them otherwise
+func (sqlite SQLite) GetChecks(schema, tablename string) ([]Check, error) {
+	ddl, err := sqlite.tableDDL(schema, tablename)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteChecks(tablename, ddl), nil
+}
+
+// GetTableComment returns the -- comments of the CREATE TABLE statement written before the first column
+func (sqlite SQLite) GetTableComment(schema, tablename string) (string, error) {
+	ddl, err := sqlite.tableDDL(schema, tablename)
+	if err != nil {
+		return "", err
+	}
+	comment, _ := sqliteComments(ddl)
+	return comment, nil
+}
+
+// sqliteComments reads the -- comments of a CREATE TABLE statement, SQLite has no COMMENT ON.
+// A comment belongs to the column defined on its line, the comments before the first column belong to the table
+func sqliteComments(ddl string) (string, map[string]string) {
+	table := []string{}
+	columns := map[string]string{}
+	inColumns := false
+	for _, line := range strings.Split(ddl, "\n") {
+		code, comment, found := strings.Cut(line, "--")
+		if !inColumns {
+			// the columns start after the opening parenthesis of CREATE TABLE
+			_, after, ok := strings.Cut(code, "(")
+			if !ok {
+				if found {
+					table = append(table, strings.TrimSpace(comment))
+				}
+				continue
+			}
+			inColumns, code = true, after
+		}
+		if !found {
+			continue
+		}
+		fields := strings.Fields(strings.TrimLeft(code, " \t,"))
+		if len(fields) == 0 {
+			if len(columns) == 0 {
+				table = append(table, strings.TrimSpace(comment))
+			}
+			continue
+		}
+		columns[strings.ToLower(strings.Trim(fields[0], "\"`[]"))] = strings.TrimSpace(comment)
+	}
+	return strings.Join(table, "\n"), columns
+}
+
+var sqliteCreateViewRe = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:TEMP\s+|TEMPORARY\s+)?VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?.*?\bAS\s+(\(?\s*(?:SELECT|WITH|VALUES)\b.*)$`)
+
+// GetViewDefinition extracts the SELECT of the CREATE VIEW statement, an empty string when the table is not a view
+func (sqlite SQLite) GetViewDefinition(schema, tablename string) (string, error) {
+	var ddl string
+	query := fmt.Sprintf("SELECT sql FROM %s.sqlite_master WHERE type = 'view' AND name = ?1", sqlite.Escape(schema))
+	err := DB.QueryRow(query, tablename).Scan(&ddl)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "get view definition, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	matches := sqliteCreateViewRe.FindStringSubmatch(ddl)
+	if matches == nil {
+		return "", errors.Errorf("cannot find the SELECT of view %s: %s", tablename, ddl)
+	}
+	return matches[1], nil
+}
+
+var sqliteCreateTriggerRe = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:TEMP\s+|TEMPORARY\s+)?TRIGGER\s+.*?\s(?:(BEFORE|AFTER|INSTEAD\s+OF)\s+)?(INSERT|UPDATE|DELETE)\b.*?\bBEGIN\b(.*)$`)
+
+// GetTriggers extracts the timing, event and body of the CREATE TRIGGER statements firing on INSERT or UPDATE
+func (sqlite SQLite) GetTriggers(schema, tablename string) ([]Trigger, error) {
+	query := fmt.Sprintf("SELECT name, sql FROM %s.sqlite_master WHERE type = 'trigger' AND tbl_name = ?1 ORDER BY name", sqlite.Escape(schema))
+	rows, err := DB.Query(query, tablename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get triggers, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	defer rows.Close()
+
+	triggers := []Trigger{}
+	for rows.Next() {
+		var name, ddl string
+		if err := rows.Scan(&name, &ddl); err != nil {
+			return nil, errors.Wrap(err, "cannot read triggers")
+		}
+		matches := sqliteCreateTriggerRe.FindStringSubmatch(ddl)
+		if matches == nil {
+			log.Warn().Str("trigger", name).Str("table", tablename).Msg("cannot read the definition of the trigger, ignoring it")
+			continue
+		}
+		event := strings.ToUpper(matches[2])
+		if event == "DELETE" {
+			continue
+		}
+		timing := strings.ToUpper(strings.Join(strings.Fields(matches[1]), " "))
+		if timing == "" {
+			timing = "BEFORE"
+		}
+		triggers = append(triggers, Trigger{Name: name, Timing: timing, Events: []string{event}, Writes: qualifyWrites(triggerWrites(matches[3]), schema)})
+	}
+	return triggers, rows.Err()
+}
+
+// sqliteChecks finds every CHECK (...) outside of quotes and comments. Unnamed ones are named after their position
+func sqliteChecks(tablename, ddl string) []Check {
+	checks := []Check{}
+	words := []string{} // previous words, to find CONSTRAINT name
+	for i := 0; i < len(ddl); {
+		c := ddl[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			end := byte(c)
+			if c == '[' {
+				end = ']'
+			}
+			j := i + 1
+			for j < len(ddl) && ddl[j] != end {
+				j++
+			}
+			words = append(words, strings.Trim(ddl[i:min(j+1, len(ddl))], "'\"`[]"))
+			i = j + 1
+		case strings.HasPrefix(ddl[i:], "--"):
+			for i < len(ddl) && ddl[i] != '\n' {
+				i++
+			}
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(ddl) && (ddl[j] == '_' || ddl[j] >= 'a' && ddl[j] <= 'z' || ddl[j] >= 'A' && ddl[j] <= 'Z' || ddl[j] >= '0' && ddl[j] <= '9') {
+				j++
+			}
+			word := ddl[i:j]
+			i = j
+			if !strings.EqualFold(word, "check") {
+				words = append(words, word)
+				continue
+			}
+			for i < len(ddl) && strings.ContainsRune(" \t\r\n", rune(ddl[i])) {
+				i++
+			}
+			clause, next := balancedParens(ddl, i)
+			i = next
+			name := fmt.Sprintf("%s_check_%d", tablename, len(checks)+1)
+			if len(words) >= 2 && strings.EqualFold(words[len(words)-2], "constraint") {
+				name = words[len(words)-1]
+			}
+			checks = append(checks, Check{Name: name, Clause: clause})
+			words = words[:0]
+		default:
+			if !strings.ContainsRune(" \t\r\n", rune(c)) {
+				words = append(words, string(c))
+			}
+			i++
+		}
+	}
+	return checks
+}
+
+// balancedParens returns the parenthesized expression starting at i, quotes included, and the position after it
+func balancedParens(s string, i int) (string, int) {
+	if i >= len(s) || s[i] != '(' {
+		return "", i
+	}
+	start, depth := i, 0
+	var quote byte
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return s[start : i+1], i + 1
+			}
+		}
+	}
+	return s[start:], i
+}
+
+func (_ SQLite) GetConstraints(schema, tablename string) ([]*Constraint, error) {
+	query := `SELECT id, "table", "from", "to" FROM pragma_foreign_key_list(?1, ?2) ORDER BY id, seq`
+
+	rows, err := DB.Query(query, tablename, schema)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get constraints, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	defer rows.Close()
+
+	// foreign keys are unnamed on SQLite, they are grouped by id
+	constraints := []*Constraint{}
+	byID := map[int]*Constraint{}
+	for rows.Next() {
+		var (
+			id       int
+			refTable string
+			column   string
+			refCol   sql.NullString
+		)
+		err := rows.Scan(&id, &refTable, &column, &refCol)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read constraints")
+		}
+		c, ok := byID[id]
+		if !ok {
+			c = &Constraint{
+				ConstraintName:        fmt.Sprintf("fk_%s_%d", tablename, id),
+				ReferencedTableSchema: schema,
+				ReferencedTableName:   refTable,
+			}
+			byID[id] = c
+			constraints = append(constraints, c)
+		}
+		c.ColumnsName = append(c.ColumnsName, column)
+		// "REFERENCES parent" without columns points to the primary key, resolved below
+		if refCol.Valid {
+			c.ReferencedColumnsName = append(c.ReferencedColumnsName, refCol.String)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, c := range constraints {
+		if len(c.ReferencedColumnsName) != 0 {
+			continue
+		}
+		c.ReferencedColumnsName, err = sqlitePrimaryKey(schema, c.ReferencedTableName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot resolve primary key of %s referenced by %s", c.ReferencedTableName, tablename)
+		}
+	}
+
+	return constraints, nil
+}
+
+func sqlitePrimaryKey(schema, tablename string) ([]string, error) {
+	rows, err := DB.Query(`SELECT name FROM pragma_table_info(?1, ?2) WHERE pk > 0 ORDER BY pk`, tablename, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols := []string{}
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return cols, rows.Err()
+}
+
+func (_ SQLite) InsertTemplate() string {
+	return "INSERT INTO %s.%s (%s)%s VALUES \n"
+}
+
+// GetSecondaryIndexes returns the non unique indexes created by CREATE INDEX, with their statement
+func (sqlite SQLite) GetSecondaryIndexes(schema, tablename string) ([]SecondaryIndex, error) {
+	query := fmt.Sprintf(`SELECT m.name, m.sql
+	FROM pragma_index_list(?1, ?2) l
+	JOIN %s.sqlite_master m ON m.type = 'index' AND m.name = l.name
+	WHERE NOT l."unique"
+		AND l.origin = 'c'
+		AND m.sql IS NOT NULL
+	ORDER BY m.name`, sqlite.Escape(schema))
+	rows, err := DB.Query(query, tablename, schema)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get secondary indexes, query: %s, schema: %s, table: %s", query, schema, tablename)
+	}
+	defer rows.Close()
+
+	indexes := []SecondaryIndex{}
+	for rows.Next() {
+		var index SecondaryIndex
+		if err := rows.Scan(&index.Name, &index.Definition); err != nil {
+			return nil, errors.Wrap(err, "cannot read secondary indexes")
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, rows.Err()
+}
+
+func (sqlite SQLite) DropIndex(table *Table, index SecondaryIndex) error {
+	query := fmt.Sprintf("DROP INDEX %s.%s", sqlite.Escape(table.Schema), sqlite.Escape(index.Name))
+	_, err := DB.Exec(query)
+	return errors.Wrapf(err, "drop index, query: %s", query)
+}
+
+// sqliteWriteMutex runs the index builds one at a time, SQLite has a single writer and they could outlast busy_timeout
+var sqliteWriteMutex sync.Mutex
+
+func (_ SQLite) CreateIndex(_ *Table, index SecondaryIndex) error {
+	sqliteWriteMutex.Lock()
+	defer sqliteWriteMutex.Unlock()
+	_, err := DB.Exec(index.Definition)
+	return errors.Wrapf(err, "create index, query: %s", index.Definition)
+}
+
+func (_ SQLite) DefaultKeyword() string {
+	return "NULL"
+}
+
+func (_ SQLite) Escape(s string) string {
+	return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+}
+
+func (_ SQLite) SetTableMetadata(table *Table, database, tablename string) {
+	// database is the file path, the schema is "main" unless another database file is attached
+	schema, name := query.SplitTableName(tablename)
+	if schema == "" {
+		schema = "main"
+	}
+	table.Schema = schema
+	table.Name = name
+}
+
+// random() returns a signed 64 bits integer, so we keep 6 digits of precision for the coin flip
+func (_ SQLite) BinomialWhereClause(freqPercent float64) string {
+	return fmt.Sprintf("WHERE abs(random()) %% 1000000 < %d", int64(freqPercent*10000))
+}
+
+func (_ SQLite) ErrShouldRetryTx(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSqliteChecks(t *testing.T) {
+	ddl := `CREATE TABLE t1 (
+	id integer PRIMARY KEY,
+	-- CHECK (commented) is ignored
+	"check ( quoted )" text CHECK (length("check ( quoted )") <= 5),
+	price real CONSTRAINT price_positive CHECK (price > 0 AND price < (10 * 2)),
+	status text CHECK(status IN ('a', 'b)')),
+	CHECK (id <> 0)
+)`
+	expected := []Check{
+		{Name: "t1_check_1", Clause: `(length("check ( quoted )") <= 5)`},
+		{Name: "price_positive", Clause: "(price > 0 AND price < (10 * 2))"},
+		{Name: "t1_check_3", Clause: "(status IN ('a', 'b)'))"},
+		{Name: "t1_check_4", Clause: "(id <> 0)"},
+	}
+
+	checks := sqliteChecks("t1", ddl)
+	if !reflect.DeepEqual(checks, expected) {
+		t.Errorf("expected %#v, got %#v", expected, checks)
+	}
+}
+
+func TestSqliteComments(t *testing.T) {
+	ddl := `CREATE TABLE t1 ( -- rdl: null(0.5)
+	-- orders of the shop
+	id integer PRIMARY KEY,
+	"Status" text, -- rdl: values(new,done)
+	qty integer -- rdl: zipf(1.1)
+)`
+	table, columns := sqliteComments(ddl)
+	expected := map[string]string{"status": "rdl: values(new,done)", "qty": "rdl: zipf(1.1)"}
+	if table != "rdl: null(0.5)\norders of the shop" {
+		t.Errorf("unexpected table comment %q", table)
+	}
+	if !reflect.DeepEqual(columns, expected) {
+		t.Errorf("expected %#v, got %#v", expected, columns)
+	}
+}
+
+func TestSqliteEscape(t *testing.T) {
+	tests := map[string]string{
+		"t1":       `"t1"`,
+		"my table": `"my table"`,
+		`a"b`:      `"a""b"`,
+		`"t1"`:     `"""t1"""`,
+		`"a"b"`:    `"""a""b"""`,
+		"":         `""`,
+	}
+	for name, expected := range tests {
+		if escaped := (SQLite{}).Escape(name); escaped != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, escaped)
+		}
+	}
+}
+
+func TestSqliteSetTableMetadata(t *testing.T) {
+	tests := map[string][2]string{
+		"t1":          {"main", "t1"},
+		"aux.t1":      {"aux", "t1"},
+		"aux.my.name": {"aux.my", "name"},
+	}
+	for tablename, expected := range tests {
+		table := &Table{}
+		(SQLite{}).SetTableMetadata(table, "/tmp/test.db", tablename)
+		if table.Schema != expected[0] || table.Name != expected[1] {
+			t.Errorf("%s: expected %s.%s, got %s.%s", tablename, expected[0], expected[1], table.Schema, table.Name)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"database/sql"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Trigger fires on the INSERT or UPDATE statements issued while loading a table
+type Trigger struct {
+	Name   string
+	Timing string   // BEFORE, AFTER or INSTEAD OF
+	Events []string // INSERT and/or UPDATE
+	Writes []string // tables written by the body, qualified when the trigger names them so. Best effort, dynamic SQL is missed
+}
+
+const (
+	// TriggersKeep lets triggers fire during the load
+	TriggersKeep = "keep"
+	// TriggersDisable turns triggers off for the sessions of the tool
+	TriggersDisable = "disable"
+	// TriggersFail refuses to load tables having triggers
+	TriggersFail = "fail"
+)
+
+var (
+	identifierPart = "(?:[\\w$]+|\"[^\"]+\"|`[^`]+`|\\[[^\\]]+\\])"
+	// INSERT INTO t, INSERT IGNORE INTO t, REPLACE INTO t, UPDATE t, UPDATE ONLY t, DELETE FROM t
+	triggerWriteRe = regexp.MustCompile(`(?i)\b(?:INSERT\s+(?:OR\s+\w+\s+)?(?:IGNORE\s+)?INTO|REPLACE\s+INTO|UPDATE(?:\s+ONLY)?|DELETE\s+FROM)\s+(` + identifierPart + `(?:\s*\.\s*` + identifierPart + `)?)`)
+
+	// words following UPDATE that are not tables: ON CONFLICT DO UPDATE SET, FOR UPDATE OF, FOR UPDATE SKIP LOCKED
+	notWrittenTables = []string{"set", "of", "skip", "nowait"}
+)
+
+// triggerWrites lists the tables written by the body of a trigger
+func triggerWrites(body string) []string {
+	writes := []string{}
+	for _, matches := range triggerWriteRe.FindAllStringSubmatch(body, -1) {
+		parts := strings.Split(matches[1], ".")
+		for i := range parts {
+			parts[i] = strings.Trim(strings.TrimSpace(parts[i]), "\"`[]")
+		}
+		name := strings.Join(parts, ".")
+		if slices.Contains(notWrittenTables, strings.ToLower(name)) || slices.Contains(writes, name) {
+			continue
+		}
+		writes = append(writes, name)
+	}
+	return writes
+}
+
+// qualifyWrites prefixes the tables written by a trigger with the schema of its table, for engines resolving them there
+func qualifyWrites(writes []string, schema string) []string {
+	for i, name := range writes {
+		if !strings.Contains(name, ".") {
+			writes[i] = schema + "." + name
+		}
+	}
+	return writes
+}
+
+// scanTriggers reads name, timing, event and body rows, merging the events of a same trigger
+func scanTriggers(rows *sql.Rows, schema string, qualify bool) ([]Trigger, error) {
+	defer rows.Close()
+	triggers := []Trigger{}
+	for rows.Next() {
+		var name, timing, event, body string
+		if err := rows.Scan(&name, &timing, &event, &body); err != nil {
+			return nil, errors.Wrap(err, "cannot read triggers")
+		}
+		event = strings.ToUpper(event)
+		if i := slices.IndexFunc(triggers, func(t Trigger) bool { return t.Name == name }); i != -1 {
+			triggers[i].Events = append(triggers[i].Events, event)
+			continue
+		}
+		writes := triggerWrites(body)
+		if qualify {
+			writes = qualifyWrites(writes, schema)
+		}
+		triggers = append(triggers, Trigger{Name: name, Timing: strings.ToUpper(timing), Events: []string{event}, Writes: writes})
+	}
+	return triggers, rows.Err()
+}
+
+// TriggerTarget is a table written by triggers of the loaded tables, that is not loaded itself
+type TriggerTarget struct {
+	Table    *Table // only its schema and name are set
+	Triggers []string
+}
+
+// TriggerTargets lists the tables written by the triggers of the tables, outside of them
+func TriggerTargets(tables []*Table, database string) []TriggerTarget {
+	targets := []TriggerTarget{}
+	for _, table := range tables {
+		for _, trigger := range table.Triggers {
+			for _, name := range trigger.Writes {
+				target := &Table{}
+				engine.SetTableMetadata(target, database, name)
+				if slices.ContainsFunc(tables, func(t *Table) bool { return t.FullName() == target.FullName() }) {
+					continue
+				}
+				i := slices.IndexFunc(targets, func(t TriggerTarget) bool { return t.Table.FullName() == target.FullName() })
+				if i == -1 {
+					targets = append(targets, TriggerTarget{Table: target})
+					i = len(targets) - 1
+				}
+				if !slices.Contains(targets[i].Triggers, trigger.Name) {
+					targets[i].Triggers = append(targets[i].Triggers, trigger.Name)
+				}
+			}
+		}
+	}
+	return targets
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTriggerWrites(t *testing.T) {
+	tests := []struct {
+		body   string
+		writes []string
+	}{
+		{body: "INSERT INTO audit_log (id, action) VALUES (NEW.id, 'insert')", writes: []string{"audit_log"}},
+		{body: "BEGIN UPDATE `stats`.`counters` SET n = n + 1; DELETE FROM queue WHERE id = NEW.id; END", writes: []string{"stats.counters", "queue"}},
+		{body: `BEGIN
+	INSERT INTO "History"."Orders" SELECT NEW.*;
+	INSERT INTO totals VALUES (NEW.id) ON CONFLICT (id) DO UPDATE SET n = totals.n + 1;
+	PERFORM 1 FROM totals FOR UPDATE SKIP LOCKED;
+	RETURN NEW;
+END`, writes: []string{"History.Orders", "totals"}},
+		{body: "INSERT OR REPLACE INTO cache(id) VALUES (new.id); REPLACE INTO cache(id) VALUES (new.id)", writes: []string{"cache"}},
+		{body: "SET NEW.updated_at = now()", writes: []string{}},
+	}
+	for _, test := range tests {
+		if writes := triggerWrites(test.body); !reflect.DeepEqual(writes, test.writes) {
+			t.Errorf("%s: expected %v, got %v", test.body, test.writes, writes)
+		}
+	}
+}
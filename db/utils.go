@@ -5,6 +5,7 @@ import (
 
 	"slices"
 
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
@@ -38,6 +39,126 @@ INSERT_LOOP:
 	return tablesSorted
 }
 
+// loopEdge is a foreign key followed while looking for loops between tables
+type loopEdge struct {
+	table      *Table
+	constraint *Constraint
+}
+
+// BreakConstraintLoops defers one foreign key of every loop between the tables of this run, so that they can be sorted.
+// Self-referencing foreign keys must be resolved beforehand.
+// The columns of a deferred foreign key are left NULL by the inserts, then updated once every table is loaded
+func BreakConstraintLoops(tables []*Table) error {
+	for {
+		loop := findConstraintLoop(tables)
+		if loop == nil {
+			return nil
+		}
+
+		edge, ok := constraintToDefer(loop)
+		if !ok {
+			names := []string{}
+			for _, e := range loop {
+				names = append(names, e.table.Name+"."+e.constraint.ConstraintName)
+			}
+			return errors.Errorf("foreign keys %s form a loop. To break it, the columns of one of them must be nullable and its table needs a primary key. Consider dropping one of these foreign keys", strings.Join(names, ", "))
+		}
+		edge.constraint.deferred = true
+		log.Info().Str("table", edge.table.Name).Str("constraint", edge.constraint.ConstraintName).Str("referenced table", edge.constraint.ReferencedTableName).Msg("foreign key is part of a loop between tables. Leaving it NULL during the inserts, it will be updated once every table is loaded")
+	}
+}
+
+// findConstraintLoop returns the foreign keys of a loop between tables, nil if there is none.
+// Tables inserted twice, to resolve self-referencing foreign keys, are one node
+func findConstraintLoop(tables []*Table) []loopEdge {
+	names := []string{}
+	byName := map[string][]*Table{}
+	for _, t := range tables {
+		name := strings.ToLower(t.FullName())
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], t)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	path := []loopEdge{}
+
+	var visit func(name string) []loopEdge
+	visit = func(name string) []loopEdge {
+		state[name] = visiting
+		for _, t := range byName[name] {
+			for _, c := range t.Constraints {
+				referenced := strings.ToLower(c.ReferencedTableSchema + "." + c.ReferencedTableName)
+				if c.deferred || !c.willBeInsertedDuringThisRun || referenced == name {
+					continue
+				}
+				if _, ok := byName[referenced]; !ok {
+					continue
+				}
+				switch state[referenced] {
+				case visiting:
+					// the loop starts where the referenced table was entered
+					loop := append(slices.Clone(path), loopEdge{t, c})
+					for i, e := range loop {
+						if strings.ToLower(e.table.FullName()) == referenced {
+							return loop[i:]
+						}
+					}
+				case unvisited:
+					path = append(path, loopEdge{t, c})
+					if loop := visit(referenced); loop != nil {
+						return loop
+					}
+					path = path[:len(path)-1]
+				}
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range names {
+		if state[name] == unvisited {
+			if loop := visit(name); loop != nil {
+				return loop
+			}
+		}
+	}
+	return nil
+}
+
+// constraintToDefer picks the foreign key breaking the loop.
+// Foreign keys that are not sampled cost nothing, otherwise the columns must be nullable and rows identified by a primary key to be updated
+func constraintToDefer(loop []loopEdge) (loopEdge, bool) {
+	candidates := []loopEdge{}
+	for _, e := range loop {
+		if !e.constraint.isSampled() {
+			return e, true
+		}
+		if e.table.PrimaryKey() == nil {
+			continue
+		}
+		if slices.ContainsFunc(e.constraint.Fields, func(f Field) bool { return !f.IsNullable }) {
+			continue
+		}
+		candidates = append(candidates, e)
+	}
+	if len(candidates) == 0 {
+		return loopEdge{}, false
+	}
+	// the loop can be found from any of its tables, keep the choice stable
+	slices.SortFunc(candidates, func(a, b loopEdge) int {
+		return strings.Compare(a.table.Name+"."+a.constraint.ConstraintName, b.table.Name+"."+b.constraint.ConstraintName)
+	})
+	return candidates[0], true
+}
+
 func EscapedNamesListFromFields(fields []Field) string {
 	names := make([]string, 0, len(fields))
 	for _, field := range fields {
@@ -20,13 +20,14 @@ type ColumnFrequency map[string]Frequency
 
 type Frequency struct {
 	Null             float64
+	HasNull          bool      // Null was set, either by --null-freq-map or by a hint
 	IndexValues      []string  // list of values that should  end up in the column
 	IndexFrequencies []float64 // with their associated frequencies
 }
 
 type FrequencyNullParameter TableFrequency
 
-var ErrMalformedFrequencyNullParameter = errors.New("malformed null frequency mapping, the format is \"table.col=(0.0-1.0)[;table.col2=(0.0-1.0)]\". Example \nitems.tags=0.63;items.price=0\"")
+var ErrMalformedFrequencyNullParameter = errors.New("malformed null frequency mapping, the format is \"[schema.]table.col=(0.0-1.0)[;table.col2=(0.0-1.0)]\". Example \nitems.tags=0.63;items.price=0\"")
 
 func init() {
 	SharedTableFrequency = map[string]ColumnFrequency{}
@@ -52,8 +53,8 @@ func (fnp *FrequencyNullParameter) Decode(ctx *kong.DecodeContext, target reflec
 			return ErrMalformedFrequencyNullParameter
 		}
 
-		tableColParts := strings.Split(parts[0], ".")
-		if len(tableColParts) != 2 {
+		table, col, ok := splitTableColumn(parts[0])
+		if !ok {
 			return ErrMalformedFrequencyNullParameter
 		}
 
@@ -62,14 +63,14 @@ func (fnp *FrequencyNullParameter) Decode(ctx *kong.DecodeContext, target reflec
 			return errors.Wrap(ErrMalformedFrequencyNullParameter, err.Error())
 		}
 
-		var ok bool
-		if colMap, ok = SharedTableFrequency[tableColParts[0]]; !ok {
+		if colMap, ok = SharedTableFrequency[table]; !ok {
 			colMap = map[string]Frequency{}
 		}
-		colMap[tableColParts[1]] = Frequency{
-			Null: freq,
+		colMap[col] = Frequency{
+			Null:    freq,
+			HasNull: true,
 		}
-		SharedTableFrequency[tableColParts[0]] = colMap
+		SharedTableFrequency[table] = colMap
 	}
 
 AFFECT_NONETHELESS:
@@ -92,7 +93,7 @@ func (c ColumnFrequency) Null(col string, isNullable bool) bool {
 
 type FrequencyIndexValuesParameter TableFrequency
 
-var ErrMalformedFrequencyIndexValuesParameter = errors.New("malformed values frequency mapping, the format is \"table.col=val:(0.0-1.0),val2(0.0-1.0)[;table.col2=(0.0-1.0)]\". Example \nitems.tags=web:0.925,cloud:0.17;items.price=9.99:0.59\"")
+var ErrMalformedFrequencyIndexValuesParameter = errors.New("malformed values frequency mapping, the format is \"[schema.]table.col=val:(0.0-1.0),val2(0.0-1.0)[;table.col2=(0.0-1.0)]\". Example \nitems.tags=web:0.925,cloud:0.17;items.price=9.99:0.59\"")
 
 func (fnp *FrequencyIndexValuesParameter) Decode(ctx *kong.DecodeContext, target reflect.Value) error {
 	var value string
@@ -114,8 +115,8 @@ func (fnp *FrequencyIndexValuesParameter) Decode(ctx *kong.DecodeContext, target
 			return errors.Wrap(ErrMalformedFrequencyIndexValuesParameter, "missing =, or too many =")
 		}
 
-		tableColParts := strings.Split(parts[0], ".")
-		if len(tableColParts) != 2 {
+		table, col, ok := splitTableColumn(parts[0])
+		if !ok {
 			return errors.Wrapf(ErrMalformedFrequencyIndexValuesParameter, "malformed table.col: %s", parts[0])
 		}
 
@@ -131,15 +132,14 @@ func (fnp *FrequencyIndexValuesParameter) Decode(ctx *kong.DecodeContext, target
 				return errors.Wrap(ErrMalformedFrequencyIndexValuesParameter, err.Error())
 			}
 
-			var ok bool
-			if colMap, ok = SharedTableFrequency[tableColParts[0]]; !ok {
+			if colMap, ok = SharedTableFrequency[table]; !ok {
 				colMap = map[string]Frequency{}
 			}
-			storedFreq := colMap[tableColParts[1]]
+			storedFreq := colMap[col]
 			storedFreq.IndexValues = append(storedFreq.IndexValues, valFreqParts[0])
 			storedFreq.IndexFrequencies = append(storedFreq.IndexFrequencies, freq)
-			colMap[tableColParts[1]] = storedFreq
-			SharedTableFrequency[tableColParts[0]] = colMap
+			colMap[col] = storedFreq
+			SharedTableFrequency[table] = colMap
 		}
 	}
 
@@ -170,25 +170,80 @@ func (c ColumnFrequency) InjectIndexValue(col string) (string, bool) {
 	return "", false
 }
 
+// MergeNullHint sets the null frequency of a column from its comment, unless --null-freq-map already did under one of the names of the table.
+// The hint is stored under the first name
+func MergeNullHint(names []string, col string, nullFreq float64) {
+	if ForTable(names...)[col].HasNull {
+		return
+	}
+	colFreqMap, ok := SharedTableFrequency[names[0]]
+	if !ok {
+		colFreqMap = map[string]Frequency{}
+	}
+	freq := colFreqMap[col]
+	freq.Null, freq.HasNull = nullFreq, true
+	colFreqMap[col] = freq
+	SharedTableFrequency[names[0]] = colFreqMap
+}
+
+// ForTable returns the frequencies of the columns of a table, merged from its names, "schema.table" and "table".
+// The null frequency of the first names has priority, the values of every name are injected
+func ForTable(names ...string) ColumnFrequency {
+	merged := ColumnFrequency{}
+	for i := len(names) - 1; i >= 0; i-- {
+		for table, colFreqMap := range SharedTableFrequency {
+			if !strings.EqualFold(table, names[i]) {
+				continue
+			}
+			for col, freq := range colFreqMap {
+				stored, ok := merged[col]
+				if !ok || freq.HasNull {
+					stored.Null, stored.HasNull = freq.Null, freq.HasNull
+				}
+				stored.IndexValues = append(slices.Clone(stored.IndexValues), freq.IndexValues...)
+				stored.IndexFrequencies = append(slices.Clone(stored.IndexFrequencies), freq.IndexFrequencies...)
+				merged[col] = stored
+			}
+		}
+	}
+	return merged
+}
+
+// splitTableColumn cuts "table.col" or "schema.table.col" before the column
+func splitTableColumn(tableCol string) (string, string, bool) {
+	parts := strings.Split(tableCol, ".")
+	if len(parts) != 2 && len(parts) != 3 || slices.Contains(parts, "") {
+		return "", "", false
+	}
+	return strings.Join(parts[:len(parts)-1], "."), parts[len(parts)-1], true
+}
+
+// MergeQueryParameters injects the values of the query at the default frequency.
+// The keys are "table.column", or "table.column$.path" for the keys of JSON columns, the table being qualified when the query does
 func MergeQueryParameters(params map[string][]string, defaultFrequency float64) {
 	for tableCol, values := range params {
-		parts := strings.Split(tableCol, ".")
-		if len(parts) != 2 {
+		// the path of a JSON key may hold dots, it is cut first
+		tableColumn, path, isJSON := strings.Cut(tableCol, "$")
+		table, col, ok := splitTableColumn(tableColumn)
+		if !ok || isJSON && !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
 			log.Debug().Str("queryParams idx", tableCol).Msg("queryParams malformed")
 			continue
 		}
-		colFreqMap, ok := SharedTableFrequency[parts[0]]
+		if isJSON {
+			col += "$" + path
+		}
+		colFreqMap, ok := SharedTableFrequency[table]
 		if !ok {
 			colFreqMap = map[string]Frequency{}
 		}
-		freq, ok := colFreqMap[parts[1]]
+		freq, ok := colFreqMap[col]
 		if !ok {
 			freq = Frequency{}
 		}
 		freq.IndexValues = append(freq.IndexValues, values...)
 		freq.IndexFrequencies = append(freq.IndexFrequencies, slices.Repeat([]float64{defaultFrequency}, len(values))...)
-		colFreqMap[parts[1]] = freq
-		SharedTableFrequency[parts[0]] = colFreqMap
+		colFreqMap[col] = freq
+		SharedTableFrequency[table] = colFreqMap
 	}
 
 }
@@ -0,0 +1,99 @@
+package frequency
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeQueryParameters(t *testing.T) {
+	SharedTableFrequency = map[string]ColumnFrequency{}
+	t.Cleanup(func() { SharedTableFrequency = nil })
+
+	MergeQueryParameters(map[string][]string{
+		"t1.c1":         {"1", "2"},
+		"t1.doc$.a.b":   {"x"},
+		"t1.doc$[0].c":  {"y"},
+		"t1":            {"table only"},
+		"s.t1.c1":       {"qualified"},
+		"db.s.t1.c1":    {"too many parts"},
+		"t1..c1":        {"empty part"},
+		"t1.doc$":       {"no path"},
+		"t1.doc$a":      {"malformed path"},
+		"s.t1.doc.x$.a": {"too many parts"},
+		"t2.c1":         {"3"},
+	}, 0.5)
+
+	expected := map[string]ColumnFrequency{
+		"t1": {
+			"c1":        {IndexValues: []string{"1", "2"}, IndexFrequencies: []float64{0.5, 0.5}},
+			"doc$.a.b":  {IndexValues: []string{"x"}, IndexFrequencies: []float64{0.5}},
+			"doc$[0].c": {IndexValues: []string{"y"}, IndexFrequencies: []float64{0.5}},
+		},
+		"s.t1": {
+			"c1": {IndexValues: []string{"qualified"}, IndexFrequencies: []float64{0.5}},
+		},
+		"t2": {
+			"c1": {IndexValues: []string{"3"}, IndexFrequencies: []float64{0.5}},
+		},
+	}
+	if !reflect.DeepEqual(SharedTableFrequency, expected) {
+		t.Errorf("expected %v, got %v", expected, SharedTableFrequency)
+	}
+}
+
+func TestForTable(t *testing.T) {
+	SharedTableFrequency = map[string]ColumnFrequency{
+		"t1": {
+			"c1": {Null: 0.2, HasNull: true, IndexValues: []string{"bare"}, IndexFrequencies: []float64{0.1}},
+			"c2": {Null: 0.3, HasNull: true},
+		},
+		"S.T1": {
+			"c1": {Null: 0.5, HasNull: true, IndexValues: []string{"qualified"}, IndexFrequencies: []float64{0.2}},
+			"c3": {IndexValues: []string{"x"}, IndexFrequencies: []float64{0.4}},
+		},
+		"other.t1": {
+			"c1": {Null: 0.9, HasNull: true},
+		},
+	}
+	t.Cleanup(func() { SharedTableFrequency = nil })
+
+	expected := ColumnFrequency{
+		"c1": {Null: 0.5, HasNull: true, IndexValues: []string{"bare", "qualified"}, IndexFrequencies: []float64{0.1, 0.2}},
+		"c2": {Null: 0.3, HasNull: true},
+		"c3": {IndexValues: []string{"x"}, IndexFrequencies: []float64{0.4}},
+	}
+	if got := ForTable("s.t1", "t1"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// the bare name is left out when another table has it
+	expected = ColumnFrequency{
+		"c1": {Null: 0.5, HasNull: true, IndexValues: []string{"qualified"}, IndexFrequencies: []float64{0.2}},
+		"c3": {IndexValues: []string{"x"}, IndexFrequencies: []float64{0.4}},
+	}
+	if got := ForTable("s.t1"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestMergeNullHint(t *testing.T) {
+	SharedTableFrequency = map[string]ColumnFrequency{
+		"t1": {"c1": {Null: 0.2, HasNull: true}},
+	}
+	t.Cleanup(func() { SharedTableFrequency = nil })
+
+	// --null-freq-map on the bare name has priority over the hint
+	MergeNullHint([]string{"s.t1", "t1"}, "c1", 0.7)
+	MergeNullHint([]string{"s.t1", "t1"}, "c2", 0.7)
+	// unless the table is not named by it
+	MergeNullHint([]string{"other.t1"}, "c1", 0.9)
+
+	expected := map[string]ColumnFrequency{
+		"t1":       {"c1": {Null: 0.2, HasNull: true}},
+		"s.t1":     {"c2": {Null: 0.7, HasNull: true}},
+		"other.t1": {"c1": {Null: 0.9, HasNull: true}},
+	}
+	if !reflect.DeepEqual(SharedTableFrequency, expected) {
+		t.Errorf("expected %v, got %v", expected, SharedTableFrequency)
+	}
+}
@@ -0,0 +1,77 @@
+package generate
+
+import (
+	"math/rand"
+	"strings"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+var (
+	// ArrayLength returns the number of elements of the next generated array, see SetArrayLength
+	ArrayLength, _, _ = parseCount("array length", DefaultArrayLength, 0)
+	// ArrayNullFrequency is how often an element of an array is NULL
+	ArrayNullFrequency float64
+	// ArrayUniqueElements forbids duplicated elements within an array
+	ArrayUniqueElements bool
+)
+
+const DefaultArrayLength = "uniform(0,5)"
+
+// SetArrayLength reads n, poisson(mean) or uniform(min,max)
+func SetArrayLength(spec string) error {
+	length, _, err := parseCount("array length", spec, 0)
+	if err != nil {
+		return err
+	}
+	ArrayLength = length
+	return nil
+}
+
+// RandomArray generates the literal of a one-dimensional PostgreSQL array, e.g. '{"1","2",NULL}'
+type RandomArray struct {
+	value string
+}
+
+func (r *RandomArray) String() string {
+	return r.value
+}
+
+func (r *RandomArray) IsQuotable() bool {
+	return true
+}
+
+// randomArray generates every element like a column of the element type
+func (in *Insert) randomArray(field db.Field) Getter {
+	if field.Element == nil {
+		return nil
+	}
+	length := ArrayLength()
+	elements := make([]string, 0, length)
+	seen := map[string]bool{}
+	// small domains, like enums or booleans, can hold fewer distinct elements than asked
+	for attempts := int64(0); int64(len(elements)) < length && attempts < length*10; attempts++ {
+		if ArrayNullFrequency > 0 && rand.Float64() < ArrayNullFrequency {
+			if ArrayUniqueElements && seen[NULL] {
+				continue
+			}
+			elements, seen[NULL] = append(elements, NULL), true
+			continue
+		}
+		g := in.randomGetter(*field.Element)
+		if g == nil {
+			return nil
+		}
+		element := quoteElement(g.String())
+		if ArrayUniqueElements && seen[element] {
+			continue
+		}
+		elements, seen[element] = append(elements, element), true
+	}
+	return &RandomArray{"{" + strings.Join(elements, ",") + "}"}
+}
+
+// quoteElement quotes a value inside an array or a row literal
+func quoteElement(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
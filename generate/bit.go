@@ -0,0 +1,56 @@
+package generate
+
+import (
+	"math/rand"
+	"strings"
+)
+
+const maxVarbitSize = 64
+
+// RandomBits generates a PostgreSQL bit string, e.g. '0110'
+type RandomBits struct {
+	value string
+}
+
+func (r *RandomBits) String() string {
+	return r.value
+}
+
+func (r *RandomBits) IsQuotable() bool {
+	return true
+}
+
+// NewRandomBits generates exactly size bits for bit(n), and up to size bits for bit varying(n), up to maxVarbitSize when unbounded
+func NewRandomBits(size int64, varying bool) *RandomBits {
+	switch {
+	case varying && size <= 0:
+		size = 1 + rand.Int63n(maxVarbitSize)
+	case varying:
+		size = 1 + rand.Int63n(min(size, maxVarbitSize))
+	default:
+		size = max(1, size)
+	}
+	var sb strings.Builder
+	for range size {
+		sb.WriteByte(byte('0' + rand.Intn(2)))
+	}
+	return &RandomBits{sb.String()}
+}
+
+// RandomBit generates a MySQL bit-value literal, e.g. b'0110'
+type RandomBit struct {
+	value string
+}
+
+func (r *RandomBit) String() string {
+	return "b'" + r.value + "'"
+}
+
+func (r *RandomBit) IsQuotable() bool {
+	return false
+}
+
+// NewRandomBit generates width bits, bit(1) by default
+func NewRandomBit(width int64) *RandomBit {
+	return &RandomBit{NewRandomBits(width, false).value}
+}
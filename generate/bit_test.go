@@ -0,0 +1,31 @@
+package generate
+
+import "testing"
+
+func TestNewRandomBits(t *testing.T) {
+	tests := []struct {
+		size     int64
+		varying  bool
+		min, max int
+	}{
+		{size: 4, min: 4, max: 4},
+		{size: 0, min: 1, max: 1},
+		{size: 3, varying: true, min: 1, max: 3},
+		{size: 1000, varying: true, min: 1, max: maxVarbitSize},
+		// bit varying without a length
+		{size: 0, varying: true, min: 1, max: maxVarbitSize},
+	}
+	for _, test := range tests {
+		longest := 0
+		for range 200 {
+			bits := NewRandomBits(test.size, test.varying).String()
+			if len(bits) < test.min || len(bits) > test.max {
+				t.Fatalf("size %d, varying %v: %q should have between %d and %d bits", test.size, test.varying, bits, test.min, test.max)
+			}
+			longest = max(longest, len(bits))
+		}
+		if test.max > 8 && longest <= 8 {
+			t.Errorf("size %d, varying %v: expected bit strings longer than 8 bits, got %d at most", test.size, test.varying, longest)
+		}
+	}
+}
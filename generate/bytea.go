@@ -0,0 +1,27 @@
+package generate
+
+import (
+	"encoding/hex"
+	"math/rand"
+)
+
+const maxByteaSize = 255
+
+// RandomBytea generates random bytes, written as a PostgreSQL hex literal: '\x00ff'
+type RandomBytea struct {
+	value []byte
+}
+
+func (r *RandomBytea) String() string {
+	return `\x` + hex.EncodeToString(r.value)
+}
+
+func (r *RandomBytea) IsQuotable() bool {
+	return true
+}
+
+func NewRandomBytea(maxSize int64) *RandomBytea {
+	value := make([]byte, 1+rand.Int63n(max(1, min(maxSize, maxByteaSize))))
+	rand.Read(value)
+	return &RandomBytea{value}
+}
@@ -0,0 +1,579 @@
+package generate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CHECK clauses are parsed into a small expression tree, then translated into rules on single columns or pairs of columns.
+// Only the forms that can drive generation are understood: comparisons with literals or other columns, IN lists, BETWEEN, IS NOT NULL, length()
+
+// checkRule constrains a column, or the length of a column, against literals or another column
+type checkRule struct {
+	column string
+	length bool     // the rule applies to length(column)
+	op     string   // <, <=, >, >=, in, not in, not null
+	values []string // literals, for in and not in every allowed or forbidden value
+	other  string   // column compared to column
+}
+
+type checkToken struct {
+	kind string // ident, quoted, string, number, op
+	text string
+}
+
+type checkNode struct {
+	kind   string // column, literal, call, array, binary, in, between, isnull, any, not
+	op     string // binary operator, function name
+	value  string // column name or literal
+	quoted bool   // string literal
+	negate bool   // NOT IN, NOT BETWEEN, IS NOT NULL
+	args   []*checkNode
+}
+
+func tokenizeCheck(clause string) ([]checkToken, error) {
+	// some MySQL versions escape the quotes of CHECK_CLAUSE
+	clause = strings.ReplaceAll(clause, `\'`, `'`)
+
+	tokens := []checkToken{}
+	isIdent := func(c byte) bool {
+		return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+	for i := 0; i < len(clause); {
+		c := clause[i]
+		switch {
+		case strings.ContainsRune(" \t\r\n", rune(c)):
+			i++
+		case c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(clause); j++ {
+				if clause[j] != '\'' {
+					sb.WriteByte(clause[j])
+					continue
+				}
+				if j+1 < len(clause) && clause[j+1] == '\'' {
+					sb.WriteByte('\'')
+					j++
+					continue
+				}
+				break
+			}
+			if j >= len(clause) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, checkToken{"string", sb.String()})
+			i = j + 1
+		case c == '`' || c == '"':
+			j := strings.IndexByte(clause[i+1:], c)
+			if j < 0 {
+				return nil, errors.New("unterminated identifier")
+			}
+			tokens = append(tokens, checkToken{"quoted", clause[i+1 : i+1+j]})
+			i += j + 2
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(clause) && clause[i+1] >= '0' && clause[i+1] <= '9':
+			j := i
+			for j < len(clause) && (clause[j] >= '0' && clause[j] <= '9' || clause[j] == '.' || clause[j] == 'e' || clause[j] == 'E') {
+				j++
+			}
+			tokens = append(tokens, checkToken{"number", clause[i:j]})
+			i = j
+		case isIdent(c):
+			j := i
+			for j < len(clause) && isIdent(clause[j]) {
+				j++
+			}
+			// charset introducers, like _utf8mb4'value' on MySQL
+			if c == '_' && j < len(clause) && clause[j] == '\'' {
+				i = j
+				continue
+			}
+			tokens = append(tokens, checkToken{"ident", clause[i:j]})
+			i = j
+		default:
+			op := string(c)
+			for _, candidate := range []string{"::", "<=", ">=", "<>", "!=", "||"} {
+				if strings.HasPrefix(clause[i:], candidate) {
+					op = candidate
+				}
+			}
+			tokens = append(tokens, checkToken{"op", op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+type checkParser struct {
+	tokens []checkToken
+	pos    int
+}
+
+func parseCheck(clause string) (*checkNode, error) {
+	tokens, err := tokenizeCheck(clause)
+	if err != nil {
+		return nil, err
+	}
+	p := &checkParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, errors.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return node, nil
+}
+
+func (p *checkParser) peek() checkToken {
+	if p.pos >= len(p.tokens) {
+		return checkToken{}
+	}
+	return p.tokens[p.pos]
+}
+
+// keyword consumes the next token if it is the given keyword
+func (p *checkParser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == "ident" && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *checkParser) op(op string) bool {
+	t := p.peek()
+	if t.kind == "op" && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *checkParser) expect(op string) error {
+	if !p.op(op) {
+		return errors.Errorf("expected %q, got %q", op, p.peek().text)
+	}
+	return nil
+}
+
+func (p *checkParser) parseOr() (*checkNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &checkNode{kind: "binary", op: "or", args: []*checkNode{left, right}}
+	}
+	return left, nil
+}
+
+func (p *checkParser) parseAnd() (*checkNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &checkNode{kind: "binary", op: "and", args: []*checkNode{left, right}}
+	}
+	return left, nil
+}
+
+func (p *checkParser) parseNot() (*checkNode, error) {
+	if p.keyword("not") {
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &checkNode{kind: "not", args: []*checkNode{node}}, nil
+	}
+	return p.parseComparison()
+}
+
+var checkComparisons = []string{"=", "<>", "!=", "<", "<=", ">", ">="}
+
+func (p *checkParser) parseComparison() (*checkNode, error) {
+	left, err := p.parseArithmetic()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == "op" && slices.Contains(checkComparisons, t.text):
+		p.pos++
+		op := t.text
+		if op == "!=" {
+			op = "<>"
+		}
+		for _, quantifier := range []string{"any", "some", "all"} {
+			if p.keyword(quantifier) {
+				if err := p.expect("("); err != nil {
+					return nil, err
+				}
+				right, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(")"); err != nil {
+					return nil, err
+				}
+				return &checkNode{kind: "any", op: op, value: strings.ToLower(quantifier), args: []*checkNode{left, right}}, nil
+			}
+		}
+		right, err := p.parseArithmetic()
+		if err != nil {
+			return nil, err
+		}
+		return &checkNode{kind: "binary", op: op, args: []*checkNode{left, right}}, nil
+
+	case t.kind == "ident":
+		start := p.pos
+		negate := p.keyword("not")
+		switch {
+		case p.keyword("in"):
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			node := &checkNode{kind: "in", negate: negate, args: []*checkNode{left}}
+			for {
+				item, err := p.parseArithmetic()
+				if err != nil {
+					return nil, err
+				}
+				node.args = append(node.args, item)
+				if !p.op(",") {
+					break
+				}
+			}
+			return node, p.expect(")")
+		case p.keyword("between"):
+			low, err := p.parseArithmetic()
+			if err != nil {
+				return nil, err
+			}
+			if !p.keyword("and") {
+				return nil, errors.New("expected AND in BETWEEN")
+			}
+			high, err := p.parseArithmetic()
+			if err != nil {
+				return nil, err
+			}
+			return &checkNode{kind: "between", negate: negate, args: []*checkNode{left, low, high}}, nil
+		case p.keyword("like"), p.keyword("regexp"), p.keyword("rlike"), p.keyword("similar"), p.keyword("glob"):
+			return nil, errors.Errorf("pattern matching is not supported")
+		case !negate && p.keyword("is"):
+			negate = p.keyword("not")
+			if !p.keyword("null") {
+				return nil, errors.Errorf("only IS [NOT] NULL is supported")
+			}
+			return &checkNode{kind: "isnull", negate: negate, args: []*checkNode{left}}, nil
+		}
+		p.pos = start
+	}
+	return left, nil
+}
+
+func (p *checkParser) parseArithmetic() (*checkNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != "op" || !slices.Contains([]string{"+", "-", "*", "/", "%", "||"}, t.text) {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = &checkNode{kind: "binary", op: t.text, args: []*checkNode{left, right}}
+	}
+}
+
+func (p *checkParser) parsePrimary() (*checkNode, error) {
+	var node *checkNode
+	t := p.peek()
+	switch {
+	case t.kind == "":
+		return nil, errors.New("unexpected end of clause")
+	case p.op("("):
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		node = inner
+	case t.kind == "op" && t.text == "-":
+		p.pos++
+		next := p.peek()
+		if next.kind != "number" {
+			return nil, errors.New("unary minus is only supported on numbers")
+		}
+		p.pos++
+		node = &checkNode{kind: "literal", value: "-" + next.text}
+	case t.kind == "number":
+		p.pos++
+		node = &checkNode{kind: "literal", value: t.text}
+	case t.kind == "string":
+		p.pos++
+		node = &checkNode{kind: "literal", value: t.text, quoted: true}
+	case t.kind == "quoted":
+		p.pos++
+		node = &checkNode{kind: "column", value: t.text}
+	case t.kind == "ident":
+		p.pos++
+		switch {
+		case strings.EqualFold(t.text, "array") && p.op("["):
+			node = &checkNode{kind: "array"}
+			for !p.op("]") {
+				item, err := p.parseArithmetic()
+				if err != nil {
+					return nil, err
+				}
+				node.args = append(node.args, item)
+				p.op(",")
+			}
+		case p.op("("):
+			node = &checkNode{kind: "call", op: strings.ToLower(t.text)}
+			for !p.op(")") {
+				arg, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				node.args = append(node.args, arg)
+				if !p.op(",") && p.peek().text != ")" {
+					return nil, errors.Errorf("unexpected %q in %s()", p.peek().text, t.text)
+				}
+			}
+		case strings.EqualFold(t.text, "null"), strings.EqualFold(t.text, "true"), strings.EqualFold(t.text, "false"):
+			node = &checkNode{kind: "literal", value: strings.ToLower(t.text)}
+		default:
+			node = &checkNode{kind: "column", value: t.text}
+		}
+	default:
+		return nil, errors.Errorf("unexpected %q", t.text)
+	}
+
+	// casts do not matter for generation: (price)::numeric, 'a'::character varying, ARRAY[...]::text[]
+	for {
+		switch {
+		case p.op("::"):
+			if p.peek().kind != "ident" {
+				return nil, errors.New("expected a type after ::")
+			}
+			p.pos++
+			for p.keyword("varying") || p.keyword("precision") || p.keyword("without") || p.keyword("with") || p.keyword("time") || p.keyword("zone") {
+			}
+			if p.op("(") {
+				for p.pos < len(p.tokens) && !p.op(")") {
+					p.pos++
+				}
+			}
+			for p.op("[") {
+				if err := p.expect("]"); err != nil {
+					return nil, err
+				}
+			}
+		case p.keyword("collate"):
+			p.pos++
+		default:
+			return node, nil
+		}
+	}
+}
+
+// checkRules translates a CHECK clause into rules, every part of the clause has to be understood
+func checkRules(clause string) ([]checkRule, error) {
+	node, err := parseCheck(clause)
+	if err != nil {
+		return nil, err
+	}
+	rules := []checkRule{}
+	for _, conjunct := range conjuncts(node) {
+		r, err := nodeRules(conjunct)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r...)
+	}
+	return rules, nil
+}
+
+func conjuncts(node *checkNode) []*checkNode {
+	if node.kind == "binary" && node.op == "and" {
+		return append(conjuncts(node.args[0]), conjuncts(node.args[1])...)
+	}
+	return []*checkNode{node}
+}
+
+var flippedComparisons = map[string]string{"=": "=", "<>": "<>", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+func nodeRules(node *checkNode) ([]checkRule, error) {
+	switch node.kind {
+	case "binary":
+		if node.op == "or" {
+			return nil, errors.New("OR is not supported")
+		}
+		if _, ok := flippedComparisons[node.op]; !ok {
+			return nil, errors.Errorf("operator %s is not supported", node.op)
+		}
+		leftCol, leftLen, leftOK := columnOperand(node.args[0])
+		rightCol, rightLen, rightOK := columnOperand(node.args[1])
+		switch {
+		case leftOK && rightOK:
+			if leftLen || rightLen {
+				return nil, errors.New("comparing lengths of columns is not supported")
+			}
+			return []checkRule{{column: leftCol, op: node.op, other: rightCol}}, nil
+		case leftOK && isLiteral(node.args[1]):
+			return []checkRule{comparisonRule(leftCol, leftLen, node.op, node.args[1].value)}, nil
+		case rightOK && isLiteral(node.args[0]):
+			return []checkRule{comparisonRule(rightCol, rightLen, flippedComparisons[node.op], node.args[0].value)}, nil
+		}
+		return nil, errors.Errorf("unsupported comparison %s", describeNode(node))
+
+	case "in":
+		column, length, ok := columnOperand(node.args[0])
+		if !ok || length {
+			return nil, errors.Errorf("unsupported IN on %s", describeNode(node.args[0]))
+		}
+		values, err := literals(node.args[1:])
+		if err != nil {
+			return nil, err
+		}
+		op := "in"
+		if node.negate {
+			op = "not in"
+		}
+		return []checkRule{{column: column, op: op, values: values}}, nil
+
+	case "any":
+		// pg prints IN lists as col = ANY (ARRAY[...]), and NOT IN as col <> ALL (ARRAY[...])
+		column, length, ok := columnOperand(node.args[0])
+		if !ok || length || node.args[1].kind != "array" {
+			return nil, errors.Errorf("unsupported %s %s", strings.ToUpper(node.value), describeNode(node.args[0]))
+		}
+		values, err := literals(node.args[1].args)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case node.op == "=" && node.value != "all":
+			return []checkRule{{column: column, op: "in", values: values}}, nil
+		case node.op == "<>" && node.value == "all":
+			return []checkRule{{column: column, op: "not in", values: values}}, nil
+		}
+		return nil, errors.Errorf("unsupported %s %s", node.op, strings.ToUpper(node.value))
+
+	case "between":
+		column, length, ok := columnOperand(node.args[0])
+		if !ok || node.negate || !isLiteral(node.args[1]) || !isLiteral(node.args[2]) {
+			return nil, errors.New("only BETWEEN literals is supported")
+		}
+		return []checkRule{
+			comparisonRule(column, length, ">=", node.args[1].value),
+			comparisonRule(column, length, "<=", node.args[2].value),
+		}, nil
+
+	case "isnull":
+		column, _, ok := columnOperand(node.args[0])
+		if !ok || !node.negate {
+			return nil, errors.New("only IS NOT NULL is supported")
+		}
+		return []checkRule{{column: column, op: "not null"}}, nil
+
+	case "not":
+		inner := node.args[0]
+		if inner.kind == "in" || inner.kind == "isnull" {
+			negated := *inner
+			negated.negate = !negated.negate
+			return nodeRules(&negated)
+		}
+		return nil, errors.Errorf("unsupported NOT %s", describeNode(inner))
+
+	case "call":
+		// mariadb adds json_valid() to json columns, generated json is always valid
+		if node.op == "json_valid" {
+			return nil, nil
+		}
+		return nil, errors.Errorf("unsupported function %s()", node.op)
+
+	case "literal":
+		if node.value == "true" || node.value == "1" {
+			return nil, nil
+		}
+	}
+	return nil, errors.Errorf("unsupported expression %s", describeNode(node))
+}
+
+func comparisonRule(column string, length bool, op, value string) checkRule {
+	switch op {
+	case "=":
+		return checkRule{column: column, length: length, op: "in", values: []string{value}}
+	case "<>":
+		return checkRule{column: column, length: length, op: "not in", values: []string{value}}
+	}
+	return checkRule{column: column, length: length, op: op, values: []string{value}}
+}
+
+var lengthFunctions = []string{"length", "char_length", "character_length", "octet_length", "len"}
+
+// columnOperand accepts a column, or the length of a column
+func columnOperand(node *checkNode) (string, bool, bool) {
+	switch {
+	case node.kind == "column":
+		return node.value, false, true
+	case node.kind == "call" && slices.Contains(lengthFunctions, node.op) && len(node.args) == 1 && node.args[0].kind == "column":
+		return node.args[0].value, true, true
+	}
+	return "", false, false
+}
+
+func isLiteral(node *checkNode) bool {
+	return node.kind == "literal" && node.value != "null"
+}
+
+func literals(nodes []*checkNode) ([]string, error) {
+	values := []string{}
+	for _, node := range nodes {
+		if !isLiteral(node) {
+			return nil, errors.Errorf("%s is not a literal", describeNode(node))
+		}
+		values = append(values, node.value)
+	}
+	return values, nil
+}
+
+func describeNode(node *checkNode) string {
+	switch node.kind {
+	case "column":
+		return node.value
+	case "literal":
+		if node.quoted {
+			return "'" + node.value + "'"
+		}
+		return node.value
+	case "call":
+		return node.op + "()"
+	case "binary":
+		return fmt.Sprintf("%s %s %s", describeNode(node.args[0]), node.op, describeNode(node.args[1]))
+	}
+	return node.kind
+}
@@ -0,0 +1,78 @@
+package generate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckRules(t *testing.T) {
+	tests := []struct {
+		clause string
+		rules  []checkRule
+		err    bool
+	}{
+		{clause: "price > 0", rules: []checkRule{{column: "price", op: ">", values: []string{"0"}}}},
+		{clause: "0 < price", rules: []checkRule{{column: "price", op: ">", values: []string{"0"}}}},
+		{clause: "`price` >= -10.5", rules: []checkRule{{column: "price", op: ">=", values: []string{"-10.5"}}}},
+		{clause: "((price)::numeric > (0)::numeric)", rules: []checkRule{{column: "price", op: ">", values: []string{"0"}}}},
+		{clause: "discount <= price", rules: []checkRule{{column: "discount", op: "<=", other: "price"}}},
+		{clause: "qty != 0", rules: []checkRule{{column: "qty", op: "not in", values: []string{"0"}}}},
+		{clause: "length(name) <= 10", rules: []checkRule{{column: "name", length: true, op: "<=", values: []string{"10"}}}},
+		{clause: "a > 1 AND (b < 2 AND (c >= 3))", rules: []checkRule{
+			{column: "a", op: ">", values: []string{"1"}},
+			{column: "b", op: "<", values: []string{"2"}},
+			{column: "c", op: ">=", values: []string{"3"}},
+		}},
+		{clause: "status IN ('new', 'it''s', 'done')", rules: []checkRule{{column: "status", op: "in", values: []string{"new", "it's", "done"}}}},
+		{clause: "status NOT IN ('x')", rules: []checkRule{{column: "status", op: "not in", values: []string{"x"}}}},
+		{clause: "NOT (status IN ('x'))", rules: []checkRule{{column: "status", op: "not in", values: []string{"x"}}}},
+		{clause: "n in (-1, 0, 1)", rules: []checkRule{{column: "n", op: "in", values: []string{"-1", "0", "1"}}}},
+		{clause: "(status = ANY (ARRAY['a'::text, 'b'::text]))", rules: []checkRule{{column: "status", op: "in", values: []string{"a", "b"}}}},
+		{clause: "(status <> ALL (ARRAY['a'::character varying]::text[]))", rules: []checkRule{{column: "status", op: "not in", values: []string{"a"}}}},
+		{clause: "`status` in (_utf8mb4\\'a\\',_utf8mb4\\'b\\')", rules: []checkRule{{column: "status", op: "in", values: []string{"a", "b"}}}},
+		{clause: "n BETWEEN -5 AND 5", rules: []checkRule{
+			{column: "n", op: ">=", values: []string{"-5"}},
+			{column: "n", op: "<=", values: []string{"5"}},
+		}},
+		{clause: "created BETWEEN '2020-01-01' AND '2020-12-31' AND n > 0", rules: []checkRule{
+			{column: "created", op: ">=", values: []string{"2020-01-01"}},
+			{column: "created", op: "<=", values: []string{"2020-12-31"}},
+			{column: "n", op: ">", values: []string{"0"}},
+		}},
+		{clause: "name IS NOT NULL", rules: []checkRule{{column: "name", op: "not null"}}},
+		{clause: "json_valid(doc)", rules: []checkRule{}},
+
+		// a rule understood from part of the clause would be wrong
+		{clause: "a > 1 OR b < 2", err: true},
+		{clause: "a > 1 AND (b = 1 OR b = 2)", err: true},
+		{clause: "NOT (a > 1)", err: true},
+		{clause: "a NOT BETWEEN 1 AND 2", err: true},
+		{clause: "a > 1 + 2", err: true},
+		{clause: "a + b > 1", err: true},
+		{clause: "a IN (1, b)", err: true},
+		{clause: "a IS NULL", err: true},
+		{clause: "name LIKE 'a%'", err: true},
+		{clause: "lower(name) = name", err: true},
+		{clause: "length(a) < length(b)", err: true},
+		{clause: "a > 1 2", err: true},
+		{clause: "a > ", err: true},
+		{clause: "a = 'unterminated", err: true},
+		{clause: "a > - b", err: true},
+	}
+	for _, test := range tests {
+		rules, err := checkRules(test.clause)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", test.clause, rules)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.clause, err)
+			continue
+		}
+		if !reflect.DeepEqual(rules, test.rules) {
+			t.Errorf("%s: expected %+v, got %+v", test.clause, test.rules, rules)
+		}
+	}
+}
@@ -0,0 +1,475 @@
+package generate
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+// CheckStatus tells whether the generators honour a CHECK constraint
+type CheckStatus struct {
+	Name     string
+	Clause   string
+	Enforced bool
+	Reason   string
+}
+
+// columnCheck gathers every rule on a single column
+type columnCheck struct {
+	field          db.Field
+	in             []string
+	hasIn          bool
+	notIn          []string
+	mins, maxs     []checkBound
+	minLen, maxLen []checkBound
+	notNull        bool
+	checks         []int // index of the statuses, to report the bounds that cannot be satisfied
+}
+
+type checkBound struct {
+	value  string
+	strict bool
+}
+
+const checkTries = 10
+
+var checkTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02", "2006-01-02T15:04:05", "15:04:05", "2006-01-02 15:04:05-07", time.RFC3339Nano}
+
+// buildChecks translates the CHECK constraints of the table into column generators and row rules
+func (in *Insert) buildChecks() {
+	in.checkStatuses = []CheckStatus{}
+	in.checkGenerators = map[string]func() Getter{}
+	in.checkNotNull = map[string]bool{}
+	in.rowChecks = []checkRule{}
+
+	sampled := map[string]bool{}
+	for _, field := range in.table.ConstraintsToSample().Fields() {
+		sampled[strings.ToLower(field.ColumnName)] = true
+	}
+	columns := map[string]*columnCheck{}
+
+	for _, check := range in.table.Checks {
+		status := CheckStatus{Name: check.Name, Clause: check.Clause, Enforced: true}
+		rules, err := checkRules(check.Clause)
+		if err != nil {
+			status.Enforced = false
+			status.Reason = "cannot parse: " + err.Error()
+		}
+		for _, rule := range rules {
+			if reason := in.addCheckRule(columns, sampled, rule, len(in.checkStatuses)); reason != "" && status.Enforced {
+				status.Enforced = false
+				status.Reason = reason
+			}
+		}
+		in.checkStatuses = append(in.checkStatuses, status)
+	}
+
+	for key, cc := range columns {
+		gen, err := in.checkGenerator(cc)
+		if err != nil {
+			for _, i := range cc.checks {
+				if in.checkStatuses[i].Enforced {
+					in.checkStatuses[i].Enforced = false
+					in.checkStatuses[i].Reason = cc.field.ColumnName + ": " + err.Error()
+				}
+			}
+			continue
+		}
+		if gen != nil {
+			in.checkGenerators[key] = gen
+		}
+		in.checkNotNull[key] = cc.notNull
+	}
+}
+
+// addCheckRule returns why the rule cannot be enforced, or an empty string
+func (in *Insert) addCheckRule(columns map[string]*columnCheck, sampled map[string]bool, rule checkRule, status int) string {
+	for _, column := range []string{rule.column, rule.other} {
+		if column == "" {
+			continue
+		}
+		if in.table.FieldByName(column) == nil {
+			return "unknown column " + column
+		}
+		if sampled[strings.ToLower(column)] {
+			return column + " is sampled from a foreign key"
+		}
+	}
+	if rule.other != "" {
+		in.rowChecks = append(in.rowChecks, rule)
+		return ""
+	}
+
+	key := strings.ToLower(rule.column)
+	cc, ok := columns[key]
+	if !ok {
+		cc = &columnCheck{field: *in.table.FieldByName(rule.column)}
+		columns[key] = cc
+	}
+	cc.checks = append(cc.checks, status)
+
+	switch {
+	case rule.op == "not null":
+		cc.notNull = true
+	case rule.length && rule.op == "in" && len(rule.values) == 1:
+		cc.minLen = append(cc.minLen, checkBound{rule.values[0], false})
+		cc.maxLen = append(cc.maxLen, checkBound{rule.values[0], false})
+	case rule.length && (rule.op == ">" || rule.op == ">="):
+		cc.minLen = append(cc.minLen, checkBound{rule.values[0], rule.op == ">"})
+	case rule.length && (rule.op == "<" || rule.op == "<="):
+		cc.maxLen = append(cc.maxLen, checkBound{rule.values[0], rule.op == "<"})
+	case rule.length:
+		return "unsupported length() " + rule.op + " " + strings.Join(rule.values, ", ")
+	case rule.op == "in":
+		if cc.hasIn {
+			cc.in = slices.DeleteFunc(cc.in, func(v string) bool { return !slices.Contains(rule.values, v) })
+		} else {
+			cc.in = slices.Clone(rule.values)
+		}
+		cc.hasIn = true
+	case rule.op == "not in":
+		cc.notIn = append(cc.notIn, rule.values...)
+	case rule.op == ">" || rule.op == ">=":
+		cc.mins = append(cc.mins, checkBound{rule.values[0], rule.op == ">"})
+	case rule.op == "<" || rule.op == "<=":
+		cc.maxs = append(cc.maxs, checkBound{rule.values[0], rule.op == "<"})
+	}
+	return ""
+}
+
+func (in *Insert) checkGenerator(cc *columnCheck) (func() Getter, error) {
+	field := cc.field
+	var gen func() Getter
+
+	switch {
+	case cc.hasIn:
+		allowed := slices.DeleteFunc(slices.Clone(cc.in), func(v string) bool { return slices.Contains(cc.notIn, v) })
+		if len(allowed) == 0 {
+			return nil, errors.New("no value satisfies every IN list")
+		}
+		quotable := !isNumericType(field.DataType)
+		gen = func() Getter { return &Literal{allowed[rand.Intn(len(allowed))], quotable} }
+
+	case len(cc.mins) > 0 || len(cc.maxs) > 0:
+		var err error
+		gen, err = boundedGenerator(field, cc.mins, cc.maxs)
+		if err != nil {
+			return nil, err
+		}
+
+	case len(cc.minLen) > 0 || len(cc.maxLen) > 0:
+		if !isUniqueStringField(field) {
+			return nil, errors.Errorf("length() is only supported on strings, not %s", field.DataType)
+		}
+		maxLen := min(field.CharacterMaximumLength.Int64, in.maxTextSize)
+		lo, hi, err := narrow(0, maxLen, intBounds(cc.minLen, true), intBounds(cc.maxLen, false))
+		if err != nil {
+			return nil, err
+		}
+		bounded := field
+		bounded.CharacterMaximumLength.Int64 = hi
+		gen = func() Getter {
+			s := []rune(in.randomGetter(bounded).String())
+			if int64(len(s)) > hi {
+				s = s[:hi]
+			}
+			for int64(len(s)) < lo {
+				s = append(s, rune(uniqueAlphabet[rand.Intn(len(uniqueAlphabet))]))
+			}
+			return &Literal{string(s), true}
+		}
+
+	default:
+		if len(cc.notIn) == 0 {
+			return nil, nil
+		}
+		gen = func() Getter { return in.randomGetter(field) }
+	}
+
+	if len(cc.notIn) == 0 || cc.hasIn {
+		return gen, nil
+	}
+	return func() Getter {
+		v := gen()
+		for try := 0; try < checkTries && slices.Contains(cc.notIn, v.String()); try++ {
+			v = gen()
+		}
+		return v
+	}, nil
+}
+
+func isNumericType(dataType string) bool {
+	switch dataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "float", "decimal", "double", "numeric", "year", "bit":
+		return true
+	}
+	return false
+}
+
+func boundedGenerator(field db.Field, mins, maxs []checkBound) (func() Getter, error) {
+	for _, b := range append(slices.Clone(mins), maxs...) {
+		if _, err := strconv.ParseFloat(b.value, 64); err != nil && isNumericType(field.DataType) {
+			return nil, errors.Errorf("%s is not a number", b.value)
+		}
+	}
+
+	switch field.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year":
+		defaultMin, defaultMax := intRange(field)
+		lo, hi, err := narrow(defaultMin, defaultMax, intBounds(mins, true), intBounds(maxs, false))
+		if err != nil {
+			return nil, err
+		}
+		return func() Getter { return NewRandomIntRange(lo, hi) }, nil
+
+	case "float", "decimal", "double", "numeric":
+		scale := int64(2)
+		defaultMax := float64(maxValues["decimal"])
+		if field.NumericPrecision.Int64 > 0 && field.NumericScale.Valid {
+			scale = field.NumericScale.Int64
+			defaultMax = math.Pow10(int(field.NumericPrecision.Int64-scale)) - math.Pow10(int(-scale))
+		}
+		step := math.Pow10(int(-scale))
+		floats := func(bounds []checkBound, sign float64) []float64 {
+			values := []float64{}
+			for _, b := range bounds {
+				f, _ := strconv.ParseFloat(b.value, 64)
+				if b.strict {
+					f += sign * step
+				}
+				values = append(values, f)
+			}
+			return values
+		}
+		lo, hi, err := narrow(0, defaultMax, floats(mins, 1), floats(maxs, -1))
+		if err != nil {
+			return nil, err
+		}
+		return func() Getter {
+			f := lo + rand.Float64()*(hi-lo)
+			f = math.Min(math.Max(math.Round(f/step)*step, lo), hi)
+			return &Literal{strconv.FormatFloat(f, 'f', int(scale), 64), false}
+		}, nil
+
+	case "date", "datetime", "timestamp", "time":
+		unit, layout := int64(1), "2006-01-02 15:04:05"
+		now := time.Now().Unix()
+		defaultMin, defaultMax := now-oneYear, now
+		switch field.DataType {
+		case "date":
+			unit, layout = 24*60*60, "2006-01-02"
+		case "time":
+			layout = "15:04:05"
+			defaultMin, defaultMax = 0, 24*60*60-1
+		}
+		seconds := func(bounds []checkBound, sign int64) ([]int64, error) {
+			values := []int64{}
+			for _, b := range bounds {
+				t, err := parseCheckTime(b.value)
+				if err != nil {
+					return nil, err
+				}
+				s := t.Unix()
+				if field.DataType == "time" {
+					s = int64(t.Hour()*3600 + t.Minute()*60 + t.Second())
+				}
+				if b.strict {
+					s += sign * unit
+				}
+				values = append(values, s)
+			}
+			return values, nil
+		}
+		minSeconds, err := seconds(mins, 1)
+		if err != nil {
+			return nil, err
+		}
+		maxSeconds, err := seconds(maxs, -1)
+		if err != nil {
+			return nil, err
+		}
+		lo, hi, err := narrow(defaultMin, defaultMax, minSeconds, maxSeconds)
+		if err != nil {
+			return nil, err
+		}
+		return func() Getter {
+			s := lo + rand.Int63n(hi-lo+1)
+			return &Literal{time.Unix(s, 0).UTC().Format(layout), true}
+		}, nil
+	}
+	return nil, errors.Errorf("comparisons are not supported on %s columns", field.DataType)
+}
+
+func intBounds(bounds []checkBound, isMin bool) []int64 {
+	values := []int64{}
+	for _, b := range bounds {
+		f, err := strconv.ParseFloat(b.value, 64)
+		if err != nil {
+			continue
+		}
+		var v float64
+		if isMin {
+			v = math.Ceil(f)
+			if b.strict && v == f {
+				v++
+			}
+		} else {
+			v = math.Floor(f)
+			if b.strict && v == f {
+				v--
+			}
+		}
+		values = append(values, int64(math.Max(math.Min(v, math.MaxInt64), math.MinInt64)))
+	}
+	return values
+}
+
+// narrow applies the tightest bounds to the default range.
+// A range outside of the default one keeps the width of the default range
+func narrow[T int64 | float64](lo, hi T, mins, maxs []T) (T, T, error) {
+	span := hi - lo
+	if len(mins) > 0 {
+		lo = slices.Max(mins)
+	}
+	if len(maxs) > 0 {
+		hi = slices.Min(maxs)
+	}
+	switch {
+	case len(mins) > 0 && len(maxs) == 0 && hi < lo:
+		hi = lo + span
+		if hi < lo {
+			hi = lo // overflow
+		}
+	case len(maxs) > 0 && len(mins) == 0 && lo > hi:
+		lo = hi - span
+		if lo > hi {
+			lo = hi
+		}
+	}
+	if lo > hi {
+		return lo, hi, errors.Errorf("no value satisfies the bounds %v and %v", mins, maxs)
+	}
+	return lo, hi, nil
+}
+
+func parseCheckTime(value string) (time.Time, error) {
+	for _, layout := range checkTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("%s is not a date or time", value)
+}
+
+// CheckStatuses reports every CHECK constraint of the table, and whether the generators honour it
+func (in *Insert) CheckStatuses() []CheckStatus {
+	return in.checkStatuses
+}
+
+// applyRowChecks fixes the rows breaking a comparison between two generated columns: values are swapped, or regenerated when equal
+func (in *Insert) applyRowChecks(fields []db.Field, values []Getter) {
+	for _, rule := range in.rowChecks {
+		a := slices.IndexFunc(fields, func(f db.Field) bool { return strings.EqualFold(f.ColumnName, rule.column) })
+		b := slices.IndexFunc(fields, func(f db.Field) bool { return strings.EqualFold(f.ColumnName, rule.other) })
+		if a < 0 || b < 0 {
+			continue
+		}
+		// unique values cannot be moved around
+		if _, ok := in.unique[strings.ToLower(fields[b].ColumnName)]; ok {
+			continue
+		}
+		va, okA := values[a].(*GetterWrapper)
+		vb, okB := values[b].(*GetterWrapper)
+		if !okA || !okB {
+			continue
+		}
+
+		for try := 0; try < checkTries; try++ {
+			// comparisons with NULL pass a CHECK
+			if _, null := va.Elem.(*Null); null || vb.Elem == nil {
+				break
+			}
+			if _, null := vb.Elem.(*Null); null {
+				break
+			}
+			cmp := compareValues(va.Elem.String(), vb.Elem.String())
+			if comparisonHolds(rule.op, cmp) {
+				break
+			}
+			switch {
+			case rule.op == "=":
+				vb.Elem = va.Elem
+			case cmp != 0 && comparisonHolds(rule.op, -cmp) && !in.isUnique(fields[a]):
+				va.Elem, vb.Elem = vb.Elem, va.Elem
+			default:
+				vb.Elem = in.generator(fields[b])()
+			}
+		}
+	}
+}
+
+func (in *Insert) isUnique(field db.Field) bool {
+	_, ok := in.unique[strings.ToLower(field.ColumnName)]
+	return ok
+}
+
+func comparisonHolds(op string, cmp int) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return true
+}
+
+// compareValues compares numbers, then dates, then strings
+func compareValues(a, b string) int {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	ta, errA := parseCheckTime(a)
+	tb, errB := parseCheckTime(b)
+	if errA == nil && errB == nil {
+		return ta.Compare(tb)
+	}
+	return strings.Compare(a, b)
+}
+
+// generator returns how values of the field are generated, honouring its CHECK constraints
+func (in *Insert) generator(field db.Field) func() Getter {
+	// a value outside of every partition would be rejected
+	if in.isPartitionKey(field) {
+		return in.partition.next
+	}
+	if gen, ok := in.checkGenerators[strings.ToLower(field.ColumnName)]; ok {
+		return gen
+	}
+	if gen, ok := in.hintGenerators[strings.ToLower(field.ColumnName)]; ok {
+		return gen
+	}
+	return func() Getter { return in.randomGetter(field) }
+}
@@ -0,0 +1,39 @@
+package generate
+
+import (
+	"strings"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+// RandomComposite generates a row literal of a PostgreSQL composite type, e.g. '("12.5","abc",)'
+type RandomComposite struct {
+	value string
+}
+
+func (r *RandomComposite) String() string {
+	return r.value
+}
+
+func (r *RandomComposite) IsQuotable() bool {
+	return true
+}
+
+// randomComposite generates every attribute like a column. Attributes of unsupported types are left NULL, composite attributes cannot be NOT NULL
+func (in *Insert) randomComposite(field db.Field) *RandomComposite {
+	var sb strings.Builder
+	sb.WriteString("(")
+	for i, attribute := range field.Attributes {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		g := in.randomGetter(attribute)
+		if g == nil {
+			continue
+		}
+		// every attribute is quoted, nested composites included
+		sb.WriteString(quoteElement(g.String()))
+	}
+	sb.WriteString(")")
+	return &RandomComposite{sb.String()}
+}
@@ -1,10 +1,12 @@
 package generate
 
+import "github.com/ylacancellera/random-data-load/db"
+
 type DefaultKeyword struct {
 }
 
 func (r *DefaultKeyword) String() string {
-	return "DEFAULT"
+	return db.DefaultKeyword()
 }
 
 func (r *DefaultKeyword) IsQuotable() bool {
@@ -0,0 +1,117 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+// FillDeferredConstraint updates the columns of a foreign key left NULL to break a loop between tables, once every table is loaded.
+// Up to count rows still holding NULL are updated, the last ones by primary key first.
+// Every batch of bulksize rows samples the referenced table like an insert would, and is updated in one transaction
+func FillDeferredConstraint(table *db.Table, constraint *db.Constraint, fklinks ForeignKeyLinks, count, bulksize int64) (int64, error) {
+	pk := table.PrimaryKey()
+	if pk == nil {
+		return 0, errors.Errorf("table %s has no primary key to update the rows", table.Name)
+	}
+	pkFields := []db.Field{}
+	for _, column := range pk.Columns {
+		field := table.FieldByName(column)
+		if field == nil {
+			return 0, errors.Errorf("could not find column %s from table %s", column, table.Name)
+		}
+		pkFields = append(pkFields, *field)
+	}
+
+	isNull := make([]string, 0, len(constraint.Fields))
+	for _, field := range constraint.Fields {
+		isNull = append(isNull, db.Escape(field.ColumnName)+" IS NULL")
+	}
+
+	var updated int64
+	for updated < count {
+		limit := min(bulksize, count-updated)
+		query := fmt.Sprintf("SELECT %s FROM %s.%s WHERE %s ORDER BY 1 DESC LIMIT %d",
+			db.EscapedNamesListFromFields(pkFields), db.Escape(table.Schema), db.Escape(table.Name), strings.Join(isNull, " AND "), limit)
+		keys, err := scanKeys(query, pkFields)
+		if err != nil {
+			return updated, err
+		}
+		if len(keys) == 0 {
+			break
+		}
+
+		values := make([][]Getter, len(keys))
+		for i := range values {
+			values[i] = make([]Getter, len(constraint.ReferencedFields))
+		}
+		samplerInit := fklinks.relationship(constraint.ReferencedTableName, table.Name)
+		sampler := samplerInit(constraint.ReferencedFields, constraint.ReferencedTableSchema, constraint.ReferencedTableName, constraint.ConstraintName, values, fklinks.CoinFlipPercent)
+		if err := sampler.Sample(); err != nil {
+			return updated, errors.Wrap(err, "FillDeferredConstraint")
+		}
+
+		if err := updateRows(table, constraint.Fields, values, pkFields, keys); err != nil {
+			return updated, err
+		}
+		updated += int64(len(keys))
+	}
+	return updated, nil
+}
+
+func scanKeys(query string, fields []db.Field) ([][]Getter, error) {
+	log.Debug().Str("query", query).Msg("query")
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get the rows to update: %s", query)
+	}
+	defer rows.Close()
+
+	keys := [][]Getter{}
+	for rows.Next() {
+		scanned := make([]any, len(fields))
+		key := make([]Getter, len(fields))
+		for i, field := range fields {
+			getter := getterFromField(field)
+			if getter == nil {
+				return nil, errors.Errorf("unsupported datatype %s for primary key column %s", field.DataType, field.ColumnName)
+			}
+			scanned[i] = getter
+			key[i] = &GetterWrapper{getter}
+		}
+		if err := rows.Scan(scanned...); err != nil {
+			return nil, errors.Wrapf(err, "failed to scan keys with query %s", query)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// updateRows sets the sampled values, one statement per row so that values keep the type of their column
+func updateRows(table *db.Table, fields []db.Field, values [][]Getter, keyFields []db.Field, keys [][]Getter) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "updateRows")
+	}
+	defer tx.Rollback() //nolint
+
+	for i, key := range keys {
+		set := make([]string, 0, len(fields))
+		for j, field := range fields {
+			set = append(set, db.Escape(field.ColumnName)+" = "+values[i][j].String())
+		}
+		where := make([]string, 0, len(keyFields))
+		for j, field := range keyFields {
+			where = append(where, db.Escape(field.ColumnName)+" = "+key[j].String())
+		}
+		query := fmt.Sprintf("UPDATE %s.%s SET %s WHERE %s", db.Escape(table.Schema), db.Escape(table.Name), strings.Join(set, ", "), strings.Join(where, " AND "))
+		if _, err := tx.Exec(query); err != nil {
+			return errors.Wrapf(err, "failed to update with query %s", query)
+		}
+	}
+	return errors.Wrap(tx.Commit(), "updateRows")
+}
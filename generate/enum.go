@@ -2,6 +2,7 @@ package generate
 
 import (
 	"math/rand"
+	"strings"
 )
 
 // RandomEnum Getter
@@ -21,3 +22,29 @@ func NewRandomEnum(allowedValues []string) *RandomEnum {
 	i := rand.Int63n(int64(len(allowedValues)))
 	return &RandomEnum{allowedValues[i]}
 }
+
+// RandomSet picks any members of a set, at least one, in the order they were declared
+type RandomSet struct {
+	value string
+}
+
+func (r *RandomSet) String() string {
+	return r.value
+}
+
+func (r *RandomSet) IsQuotable() bool {
+	return true
+}
+
+func NewRandomSet(members []string) *RandomSet {
+	picked := []string{}
+	for _, member := range members {
+		if rand.Intn(2) == 0 {
+			picked = append(picked, member)
+		}
+	}
+	if len(picked) == 0 && len(members) > 0 {
+		picked = append(picked, members[rand.Intn(len(members))])
+	}
+	return &RandomSet{strings.Join(picked, ",")}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"slices"
 	"strings"
 	"sync"
@@ -27,6 +28,26 @@ type Insert struct {
 	uuidVersion  int
 	maxRetries   int
 	frequencies  frequency.ColumnFrequency
+	unique       map[string]uniqueColumn
+
+	// CHECK constraints, per lower-cased column, and the comparisons between columns
+	checkStatuses   []CheckStatus
+	checkGenerators map[string]func() Getter
+	checkNotNull    map[string]bool
+	rowChecks       []checkRule
+
+	partition       *partitionKey
+	partitionStatus *PartitionStatus
+
+	// range columns of exclusion constraints, per lower-cased column
+	rangeCursors map[string]*rangeCursor
+
+	// rdl: annotations of the column comments, per lower-cased column
+	hintStatuses   []HintStatus
+	hintGenerators map[string]func() Getter
+
+	// parents of the hierarchy level being inserted
+	parents *levelParents
 }
 
 type ForeignKeyLinks struct {
@@ -46,14 +67,28 @@ var fkLinkToSamplerCreator = map[string]SamplerBuilder{
 	BinomialFlag:   NewDBRandomSample,
 }
 
+var fkLinkToSamplerName = map[string]string{
+	SequentialFlag: "uniform",
+	BinomialFlag:   "binomial",
+}
+
 func (r ForeignKeyLinks) relationship(parent, child string) SamplerBuilder {
+	return fkLinkToSamplerCreator[r.relationshipFlag(parent, child)]
+}
+
+func (r ForeignKeyLinks) relationshipFlag(parent, child string) string {
 	if r.Sequential[parent] == child {
-		return fkLinkToSamplerCreator[SequentialFlag]
+		return SequentialFlag
 	}
 	if r.Binomial[parent] == child {
-		return fkLinkToSamplerCreator[BinomialFlag]
+		return BinomialFlag
 	}
-	return fkLinkToSamplerCreator[r.DefaultRelationship]
+	return r.DefaultRelationship
+}
+
+// SamplerName returns which sampler (uniform or binomial) will be used to pick parent rows for this relationship
+func (r ForeignKeyLinks) SamplerName(parent, child string) string {
+	return fkLinkToSamplerName[r.relationshipFlag(parent, child)]
 }
 
 var (
@@ -83,6 +118,10 @@ func New(table *db.Table, fklinks ForeignKeyLinks, workersCount int, maxTextSize
 		frequencies:  freqs,
 	}
 	in.NotifyChan = make(chan int64)
+	in.unique = in.uniqueColumns()
+	in.buildChecks()
+	in.buildPartitions()
+	in.buildHints()
 	return in
 }
 
@@ -102,6 +141,9 @@ func (in *Insert) DryRun(count, bulksize int64) error {
 }
 
 func (in *Insert) run(count int64, bulksize int64, dryRun bool) error {
+	if err := in.startRangeCursors(); err != nil {
+		return err
+	}
 	// Example: want 11 rows with bulksize 4:
 	// count = int(11 / 4) = 2 -> 2 bulk inserts having 4 rows each = 8 rows
 	// We need to run this insert twice:
@@ -117,9 +159,9 @@ func (in *Insert) run(count int64, bulksize int64, dryRun bool) error {
 	numJobs := completeInserts + 1 // + remainder
 
 	bulksizeJobs := make(chan int64, numJobs)
+	// errChan is never closed: after an error, the other workers still report the jobs they hold, the buffer takes them
 	errChan := make(chan error, numJobs)
 	defer close(bulksizeJobs)
-	defer close(errChan)
 
 	for w := 1; w <= in.workersCount; w++ {
 		go in.worker(errChan, bulksizeJobs, dryRun)
@@ -186,10 +228,15 @@ func (in *Insert) genQuery(count int64) *string {
 	constraintsToSample := in.table.ConstraintsToSample()
 	fieldsToSample := constraintsToSample.Fields()
 	var insertQuery strings.Builder
+	overriding := ""
+	if in.table.ExplicitKeys && db.OverridingSystemValue() != "" {
+		overriding = " " + db.OverridingSystemValue()
+	}
 	_, err := insertQuery.WriteString(fmt.Sprintf(db.InsertTemplate(), //nolint
 		db.Escape(in.table.Schema),
 		db.Escape(in.table.Name),
 		db.EscapedNamesListFromFields(slices.Concat(fieldsAsDefault, fieldsToGen, fieldsToSample)),
+		overriding,
 	))
 	if err != nil {
 		log.Error().Err(err).Msg("failed to build string")
@@ -292,52 +339,141 @@ func (in *Insert) insert(count int64, dryRun bool) (int64, error) {
 }
 
 func (in *Insert) generateFieldsRow(fields []db.Field, insertValues []Getter) {
+	// one tuple per unique index and row
+	tuples := map[*uniqueGroup][]Getter{}
 	for colIndex := range insertValues {
 		field := fields[colIndex]
+		if u, ok := in.unique[strings.ToLower(field.ColumnName)]; ok {
+			if _, ok := tuples[u.group]; !ok {
+				tuples[u.group] = u.group.nextTuple()
+			}
+			// injecting the same value in many rows would break the index
+			gw := &GetterWrapper{}
+			if in.frequencies.Null(field.ColumnName, field.IsNullable) {
+				gw.Elem = &Null{}
+			}
+			gw.Assign(tuples[u.group][u.pos])
+			insertValues[colIndex] = gw
+			continue
+		}
 		gw := NewGetterWrapper(field.ColumnName, field.IsNullable, in.frequencies)
-		if gw.Elem != nil {
-			goto SKIP
+		if _, null := gw.Elem.(*Null); null && (in.checkNotNull[strings.ToLower(field.ColumnName)] || in.isPartitionKey(field) && !in.partition.null) {
+			gw.Elem = nil
 		}
-		switch field.DataType {
-		case "bool", "boolean":
-			gw.Assign(NewRandomBool())
-		case "tinyint", "bit":
-			gw.Assign(NewRandomIntRange(0, 1))
-		case "smallint", "mediumint", "int", "integer", "bigint":
-			maxValue := maxValues["bigint"]
-			if m, ok := maxValues[field.DataType]; ok {
-				maxValue = m
-			}
-			gw.Assign(NewRandomInt(maxValue))
-		case "float", "decimal", "double", "numeric":
-			gw.Assign(NewRandomDecimal(field.NumericPrecision.Int64, field.NumericScale.Int64))
-		case "date":
-			gw.Assign(NewRandomDate())
-		case "datetime", "timestamp":
-			gw.Assign(NewRandomDateTime())
-		case "time":
-			gw.Assign(NewRandomTime())
-		case "uuid":
-			gw.Assign(NewRandomUUID(in.uuidVersion))
-		case "char", "varchar", "tinyblob", "tinytext", "blob", "text", "mediumtext", "mediumblob", "longblob", "longtext":
-			maxSize := in.maxTextSize
-			if maxSize > field.CharacterMaximumLength.Int64 {
-				maxSize = field.CharacterMaximumLength.Int64
+		if gw.Elem == nil {
+			g := in.generator(field)()
+			if g == nil {
+				log.Error().Str("type", field.DataType).Str("field", field.ColumnName).Msg("unsupported datatypes when generating fields")
 			}
-			gw.Assign(NewRandomString(field.ColumnName, maxSize))
-		case "year":
-			// TODO: meh.
-			gw.Assign(NewRandomIntRange(int64(time.Now().Year()-5), int64(time.Now().Year())))
-		case "enum", "set":
-			gw.Assign(NewRandomEnum(field.SetEnumVals))
-		case "binary", "varbinary":
-			gw.Assign(NewRandomBinary(field.CharacterMaximumLength.Int64))
-		default:
-			log.Error().Str("type", field.DataType).Str("field", field.ColumnName).Msg("unsupported datatypes when generating fields")
+			gw.Assign(g)
 		}
-	SKIP:
 		insertValues[colIndex] = gw
 	}
+	in.applyRowChecks(fields, insertValues)
+}
+
+// randomGetter returns a new random value for the field, or nil when the datatype is not handled
+func (in *Insert) randomGetter(field db.Field) Getter {
+	switch field.DataType {
+	case "bool", "boolean":
+		return NewRandomBool()
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		if field.FullIntRange {
+			return NewRandomIntRange(intRange(field))
+		}
+		if field.DataType == "tinyint" {
+			return NewRandomIntRange(0, 1)
+		}
+		return NewRandomInt(maxValues[field.DataType])
+	case "bit":
+		return NewRandomBit(field.NumericPrecision.Int64)
+	case "float", "decimal", "double", "numeric":
+		return NewRandomDecimal(field.NumericPrecision.Int64, field.NumericScale.Int64)
+	case "date":
+		return NewRandomDate()
+	case "datetime", "timestamp":
+		return NewRandomDateTime()
+	case "time":
+		return NewRandomTime()
+	case "uuid":
+		return NewRandomUUID(in.uuidVersion)
+	case "inet4", "inet":
+		return NewRandomInet(false)
+	case "inet6":
+		return NewRandomInet(true)
+	case "json":
+		if schema := ColumnJSONSchema(in.table, field.ColumnName); schema != nil {
+			return in.randomJSON(field, schema)
+		}
+		return NewRandomJSON()
+	case "char", "varchar", "tinyblob", "tinytext", "blob", "text", "mediumtext", "mediumblob", "longblob", "longtext", "citext":
+		maxSize := in.maxTextSize
+		if maxSize > field.CharacterMaximumLength.Int64 {
+			maxSize = field.CharacterMaximumLength.Int64
+		}
+		return NewRandomString(field.ColumnName, maxSize)
+	case "year":
+		// TODO: meh.
+		return NewRandomIntRange(int64(time.Now().Year()-5), int64(time.Now().Year()))
+	case "enum":
+		return NewRandomEnum(field.SetEnumVals)
+	case "set":
+		return NewRandomSet(field.SetEnumVals)
+	case "binary", "varbinary":
+		return NewRandomBinary(field.CharacterMaximumLength.Int64)
+	case "composite":
+		return in.randomComposite(field)
+	case "array":
+		return in.randomArray(field)
+	case "cidr":
+		return NewRandomCIDR()
+	case "macaddr":
+		return NewRandomMacAddr()
+	case "interval":
+		return NewRandomInterval()
+	case "money":
+		return NewRandomMoney()
+	case "bytea":
+		return NewRandomBytea(in.maxTextSize)
+	case "bitstring", "varbit":
+		return NewRandomBits(field.CharacterMaximumLength.Int64, field.DataType == "varbit")
+	case "int4range", "int8range", "numrange", "daterange", "tsrange", "tstzrange":
+		if c, ok := in.rangeCursors[strings.ToLower(field.ColumnName)]; ok && field.NoOverlap {
+			return c.Range()
+		}
+		return NewRandomRange(field.DataType)
+	case "xml":
+		return NewRandomXML()
+	case "tsvector":
+		return NewRandomTsvector()
+	case "geometry", "geography":
+		return in.randomGeometry(field)
+	}
+	return nil
+}
+
+// GeneratorName returns the name of the generator that will be used for the field, mostly for reporting purposes
+func (in *Insert) GeneratorName(field db.Field) string {
+	if u, ok := in.unique[strings.ToLower(field.ColumnName)]; ok {
+		return "unique " + u.group.strategy + " on " + u.group.index
+	}
+	if in.isPartitionKey(field) {
+		return fmt.Sprintf("partition key, %s over %d partitions", PartitionDistribution, len(in.partition.targets))
+	}
+	if _, ok := in.checkGenerators[strings.ToLower(field.ColumnName)]; ok {
+		return "check constraint"
+	}
+	if _, ok := in.hintGenerators[strings.ToLower(field.ColumnName)]; ok {
+		return "hint " + field.Hints.Annotation
+	}
+	if schema := ColumnJSONSchema(in.table, field.ColumnName); schema != nil && field.DataType == "json" {
+		return "json shaped by " + schema.Source
+	}
+	g := in.randomGetter(field)
+	if g == nil {
+		return "unsupported"
+	}
+	return reflect.TypeOf(g).Elem().Name()
 }
 
 func (in *Insert) sampleConstraints(constraints db.Constraints, values [][]Getter) error {
@@ -353,6 +489,12 @@ func (in *Insert) sampleConstraints(constraints db.Constraints, values [][]Gette
 			subSlice[i] = values[i][colIdx : colIdx+len(constraint.ReferencedFields)]
 		}
 
+		if in.parents != nil && in.parents.constraint == constraint {
+			in.parents.fill(subSlice)
+			colIdx += len(constraint.ReferencedFields)
+			continue
+		}
+
 		samplerInit := in.fklinks.relationship(constraint.ReferencedTableName, in.table.Name)
 		sampler := samplerInit(constraint.ReferencedFields, constraint.ReferencedTableSchema, constraint.ReferencedTableName, constraint.ConstraintName, subSlice, in.fklinks.CoinFlipPercent)
 		err = sampler.Sample()
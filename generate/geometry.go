@@ -0,0 +1,231 @@
+package generate
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+const DefaultGeoPolygonVertices = "uniform(3,8)"
+
+var (
+	// GeoBox bounds the generated coordinates: min x, min y, max x, max y. Geographic columns keep within the longitudes and latitudes
+	GeoBox = geoBox{-180, -90, 180, 90}
+	// GeoPolygonVertices returns the number of vertices of the next polygon, and of points of the next linestring
+	GeoPolygonVertices, _, _ = parseCount("polygon vertices", DefaultGeoPolygonVertices, 3)
+
+	// geoHotspots are the centers of the clustered points, none when they are uniform. They are fractions of the width and height of the box,
+	// so that they stay within the box of geographic columns, whose longitudes and latitudes are kept within range
+	geoHotspots []geoPoint
+	// geoRadius is in the unit of the coordinates, 0 for 1% of the box
+	geoRadius float64
+)
+
+type geoBox struct{ minX, minY, maxX, maxY float64 }
+
+type geoPoint struct{ x, y float64 }
+
+var geoDistributionRe = regexp.MustCompile(`(?i)^(uniform|clustered)(?:\(\s*(\d+)\s*,\s*([0-9.]+)\s*\))?$`)
+
+// SetGeoBox reads minx,miny,maxx,maxy
+func SetGeoBox(spec string) error {
+	parts := strings.Split(spec, ",")
+	if len(parts) != 4 {
+		return errors.Errorf("bounding box %q should be minx,miny,maxx,maxy", spec)
+	}
+	bounds := [4]float64{}
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return errors.Errorf("bounding box %q should be minx,miny,maxx,maxy", spec)
+		}
+		bounds[i] = f
+	}
+	if bounds[0] >= bounds[2] || bounds[1] >= bounds[3] {
+		return errors.Errorf("bounding box %q should have its minimums below its maximums", spec)
+	}
+	GeoBox = geoBox{bounds[0], bounds[1], bounds[2], bounds[3]}
+	return nil
+}
+
+// SetGeoPolygonVertices reads n, poisson(mean) or uniform(min,max), polygons having at least 3 vertices
+func SetGeoPolygonVertices(spec string) error {
+	vertices, _, err := parseCount("polygon vertices", spec, 3)
+	if err != nil {
+		return err
+	}
+	GeoPolygonVertices = vertices
+	return nil
+}
+
+// SetGeoDistribution reads uniform, or clustered(hotspots,radius): the points gather around hotspots picked in the bounding box,
+// radius being the standard deviation of their distance to the hotspot, in the unit of the coordinates.
+// clustered alone has 5 hotspots, with a radius of 1% of the bounding box
+func SetGeoDistribution(spec string) error {
+	matches := geoDistributionRe.FindStringSubmatch(spec)
+	if matches == nil {
+		return errors.Errorf("points distribution %q should be uniform, clustered or clustered(hotspots,radius)", spec)
+	}
+	geoHotspots = nil
+	if strings.ToLower(matches[1]) == "uniform" {
+		if matches[2] != "" {
+			return errors.Errorf("points distribution %q: uniform takes no arguments", spec)
+		}
+		return nil
+	}
+	hotspots, radius := 5, 0.0
+	if matches[2] != "" {
+		hotspots, _ = strconv.Atoi(matches[2])
+		radius, _ = strconv.ParseFloat(matches[3], 64)
+		if hotspots < 1 || radius <= 0 {
+			return errors.Errorf("points distribution %q should have at least a hotspot and a positive radius", spec)
+		}
+	}
+	for range hotspots {
+		geoHotspots = append(geoHotspots, geoPoint{rand.Float64(), rand.Float64()})
+	}
+	geoRadius = radius
+	return nil
+}
+
+// size is the radius of the generated polygons and the steps of the linestrings, 1% of the smaller side of the box
+func (b geoBox) size() float64 {
+	return min(b.maxX-b.minX, b.maxY-b.minY) / 100
+}
+
+func (b geoBox) uniform() geoPoint {
+	return b.at(geoPoint{rand.Float64(), rand.Float64()})
+}
+
+// at returns the point of the box at fractions of its width and height
+func (b geoBox) at(fraction geoPoint) geoPoint {
+	return geoPoint{b.minX + fraction.x*(b.maxX-b.minX), b.minY + fraction.y*(b.maxY-b.minY)}
+}
+
+func (b geoBox) contains(p geoPoint) bool {
+	return p.x >= b.minX && p.x <= b.maxX && p.y >= b.minY && p.y <= b.maxY
+}
+
+func (b geoBox) clamp(p geoPoint) geoPoint {
+	return geoPoint{min(max(p.x, b.minX), b.maxX), min(max(p.y, b.minY), b.maxY)}
+}
+
+// point picks a point of the box, following the distribution
+func (b geoBox) point() geoPoint {
+	if len(geoHotspots) == 0 {
+		return b.uniform()
+	}
+	hotspot := b.at(geoHotspots[rand.Intn(len(geoHotspots))])
+	radius := geoRadius
+	if radius == 0 {
+		radius = b.size()
+	}
+	p := geoPoint{}
+	for range 10 {
+		p = geoPoint{hotspot.x + rand.NormFloat64()*radius, hotspot.y + rand.NormFloat64()*radius}
+		if b.contains(p) {
+			return p
+		}
+	}
+	return b.clamp(p)
+}
+
+// RandomGeometry is a point, a linestring or a polygon written in WKT, inserted with ST_GeomFromText, or ST_GeogFromText for PostGIS geographies
+type RandomGeometry struct {
+	value string
+}
+
+func (r *RandomGeometry) String() string {
+	return r.value
+}
+
+func (r *RandomGeometry) IsQuotable() bool {
+	return false
+}
+
+// randomGeometry generates a valid shape: linestrings are random walks, polygons are star-shaped around their center
+func (in *Insert) randomGeometry(field db.Field) Getter {
+	box := GeoBox
+	if field.DataType == "geography" || field.LatitudeFirst || field.SRID == 4326 {
+		box = geoBox{max(box.minX, -180), max(box.minY, -90), min(box.maxX, 180), min(box.maxY, 90)}
+		if box.minX >= box.maxX || box.minY >= box.maxY {
+			box = geoBox{-180, -90, 180, 90}
+		}
+	}
+	shape := field.GeometryType
+	if shape == "" {
+		shape = db.GeometryTypes[rand.Intn(len(db.GeometryTypes))]
+	}
+
+	var wkt string
+	switch shape {
+	case "point":
+		wkt = "POINT(" + box.point().wkt(field.LatitudeFirst) + ")"
+	case "linestring":
+		wkt = "LINESTRING(" + strings.Join(box.linestring(field.LatitudeFirst), ",") + ")"
+	case "polygon":
+		wkt = "POLYGON((" + strings.Join(box.polygon(field.LatitudeFirst), ",") + "))"
+	default:
+		return nil
+	}
+
+	switch {
+	case field.DataType == "geography":
+		return &RandomGeometry{fmt.Sprintf("ST_GeogFromText('SRID=%d;%s')", field.SRID, wkt)}
+	case field.SRID != 0:
+		return &RandomGeometry{fmt.Sprintf("ST_GeomFromText('%s', %d)", wkt, field.SRID)}
+	}
+	return &RandomGeometry{fmt.Sprintf("ST_GeomFromText('%s')", wkt)}
+}
+
+func (b geoBox) linestring(latitudeFirst bool) []string {
+	step := b.size()
+	p := b.point()
+	points := []string{p.wkt(latitudeFirst)}
+	for len(points) < int(max(2, GeoPolygonVertices())) {
+		angle := rand.Float64() * 2 * math.Pi
+		length := step * (0.5 + rand.Float64()/2)
+		p = b.clamp(geoPoint{p.x + length*math.Cos(angle), p.y + length*math.Sin(angle)})
+		points = append(points, p.wkt(latitudeFirst))
+	}
+	return points
+}
+
+// polygon turns counterclockwise around its center, each vertex in its own angular sector so that the edges never cross
+func (b geoBox) polygon(latitudeFirst bool) []string {
+	radius := b.size()
+	center := b.point()
+	// the polygon keeps within the box
+	center.x = min(max(center.x, b.minX+radius), b.maxX-radius)
+	center.y = min(max(center.y, b.minY+radius), b.maxY-radius)
+
+	n := max(3, GeoPolygonVertices())
+	sector := 2 * math.Pi / float64(n)
+	vertices := make([]string, 0, n+1)
+	for i := range n {
+		angle := (float64(i) + 0.1 + rand.Float64()*0.8) * sector
+		distance := radius * (0.5 + rand.Float64()/2)
+		vertices = append(vertices, geoPoint{center.x + distance*math.Cos(angle), center.y + distance*math.Sin(angle)}.wkt(latitudeFirst))
+	}
+	return append(vertices, vertices[0])
+}
+
+func (p geoPoint) wkt(latitudeFirst bool) string {
+	x, y := formatCoordinate(p.x), formatCoordinate(p.y)
+	if latitudeFirst {
+		return y + " " + x
+	}
+	return x + " " + y
+}
+
+// formatCoordinate keeps 9 decimals, enough for the smallest boxes
+func formatCoordinate(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1e9)/1e9, 'f', -1, 64)
+}
@@ -0,0 +1,229 @@
+package generate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+var wktCallRe = regexp.MustCompile(`^ST_GeomFromText\('(\w+)\((.*)\)'(?:, (\d+))?\)$`)
+
+func TestRandomGeometry(t *testing.T) {
+	if err := SetGeoBox("2,48,3,49"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetGeoDistribution("clustered(2,0.01)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetGeoPolygonVertices("5"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		SetGeoBox("-180,-90,180,90")
+		SetGeoDistribution("uniform")
+		SetGeoPolygonVertices(DefaultGeoPolygonVertices)
+	})
+
+	tests := []struct {
+		field  db.Field
+		shape  string
+		srid   string
+		points int // 0 when it depends on the shape
+	}{
+		// SRID 4326 is written latitude first
+		{field: db.Field{ColumnName: "location", DataType: "geometry", GeometryType: "point", SRID: 4326, LatitudeFirst: true}, shape: "POINT", srid: "4326", points: 1},
+		{field: db.Field{ColumnName: "route", DataType: "geometry", GeometryType: "linestring"}, shape: "LINESTRING", points: 5},
+		// closed, the first vertex is repeated
+		{field: db.Field{ColumnName: "area", DataType: "geometry", GeometryType: "polygon", SRID: 3857}, shape: "POLYGON", srid: "3857", points: 6},
+		{field: db.Field{ColumnName: "shape", DataType: "geometry"}},
+	}
+	in := &Insert{}
+	for _, test := range tests {
+		for range 50 {
+			value := in.randomGeometry(test.field).String()
+			m := wktCallRe.FindStringSubmatch(value)
+			if m == nil {
+				t.Fatalf("%s %v is not inserted with ST_GeomFromText", test.field.ColumnName, value)
+			}
+			points := strings.Split(strings.Trim(m[2], "()"), ",")
+			for _, point := range points {
+				var x, y float64
+				if _, err := fmt.Sscanf(point, "%g %g", &x, &y); err != nil {
+					t.Fatalf("%s %v has an invalid point %q", test.field.ColumnName, value, point)
+				}
+				if test.field.LatitudeFirst {
+					x, y = y, x
+				}
+				if x < 2 || x > 3 || y < 48 || y > 49 {
+					t.Fatalf("%s %v is out of the bounding box", test.field.ColumnName, value)
+				}
+			}
+			if test.shape == "" {
+				continue
+			}
+			if m[1] != test.shape || m[3] != test.srid || len(points) != test.points {
+				t.Fatalf("%s %v should be a %s of %d points with SRID %q", test.field.ColumnName, value, test.shape, test.points, test.srid)
+			}
+			if test.shape == "POLYGON" && points[0] != points[len(points)-1] {
+				t.Fatalf("%s %v is not closed", test.field.ColumnName, value)
+			}
+		}
+	}
+}
+
+func TestRandomGeography(t *testing.T) {
+	field := db.Field{ColumnName: "location", DataType: "geography", GeometryType: "point", SRID: 4326}
+	value := (&Insert{}).randomGeometry(field).String()
+	if !regexp.MustCompile(`^ST_GeogFromText\('SRID=4326;POINT\([-0-9.]+ [-0-9.]+\)'\)$`).MatchString(value) {
+		t.Fatalf("expected a point inserted with ST_GeogFromText, got %s", value)
+	}
+}
+
+func TestSetGeoBox(t *testing.T) {
+	t.Cleanup(func() { SetGeoBox("-180,-90,180,90") })
+	tests := []struct {
+		spec     string
+		expected geoBox
+		err      bool
+	}{
+		{spec: "2,48,3,49", expected: geoBox{2, 48, 3, 49}},
+		{spec: " -10.5, -20 ,10.5,20 ", expected: geoBox{-10.5, -20, 10.5, 20}},
+		{spec: "0,0,500000,500000", expected: geoBox{0, 0, 500000, 500000}},
+
+		{spec: "3,48,2,49", err: true},
+		{spec: "2,49,3,49", err: true},
+		{spec: "2,48,3", err: true},
+		{spec: "2,48,3,49,5", err: true},
+		{spec: "a,48,3,49", err: true},
+		{spec: "", err: true},
+	}
+	for _, test := range tests {
+		GeoBox = geoBox{-180, -90, 180, 90}
+		err := SetGeoBox(test.spec)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("%q: expected an error", test.spec)
+		case !test.err && err != nil:
+			t.Errorf("%q: %v", test.spec, err)
+		case !test.err && GeoBox != test.expected:
+			t.Errorf("%q: expected %v, got %v", test.spec, test.expected, GeoBox)
+		}
+	}
+}
+
+func TestSetGeoDistribution(t *testing.T) {
+	t.Cleanup(func() { SetGeoDistribution("uniform") })
+	tests := []struct {
+		spec     string
+		hotspots int
+		radius   float64
+		err      bool
+	}{
+		{spec: "uniform"},
+		{spec: "UNIFORM"},
+		{spec: "clustered", hotspots: 5},
+		{spec: "clustered(3,0.5)", hotspots: 3, radius: 0.5},
+		{spec: "Clustered( 1 , 2 )", hotspots: 1, radius: 2},
+
+		{spec: "uniform(1,2)", err: true},
+		{spec: "clustered(0,1)", err: true},
+		{spec: "clustered(2,0)", err: true},
+		{spec: "clustered(-1,1)", err: true},
+		{spec: "clustered(2,-1)", err: true},
+		{spec: "clustered(2)", err: true},
+		{spec: "normal", err: true},
+		{spec: "", err: true},
+	}
+	for _, test := range tests {
+		err := SetGeoDistribution(test.spec)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("%q: expected an error", test.spec)
+		case !test.err && err != nil:
+			t.Errorf("%q: %v", test.spec, err)
+		case !test.err && (len(geoHotspots) != test.hotspots || test.hotspots > 0 && geoRadius != test.radius):
+			t.Errorf("%q: expected %d hotspots of radius %v, got %d of radius %v", test.spec, test.hotspots, test.radius, len(geoHotspots), geoRadius)
+		}
+	}
+}
+
+func TestSetGeoPolygonVertices(t *testing.T) {
+	t.Cleanup(func() { SetGeoPolygonVertices(DefaultGeoPolygonVertices) })
+	tests := []struct {
+		spec      string
+		low, high int64
+		err       bool
+	}{
+		{spec: "3", low: 3, high: 3},
+		{spec: "12", low: 12, high: 12},
+		{spec: "uniform(3,8)", low: 3, high: 8},
+		// polygons draw at least 3 of them
+		{spec: "uniform(2,8)", low: 2, high: 8},
+		{spec: "poisson(6)", low: 0, high: 1 << 62},
+
+		{spec: "2", err: true},
+		{spec: "-4", err: true},
+		{spec: "uniform(1,2)", err: true},
+		{spec: "uniform(-3,8)", err: true},
+		{spec: "poisson(-1)", err: true},
+		{spec: "many", err: true},
+	}
+	for _, test := range tests {
+		err := SetGeoPolygonVertices(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		for range 100 {
+			if n := GeoPolygonVertices(); n < test.low || n > test.high {
+				t.Fatalf("%q: %d vertices, expected %d to %d", test.spec, n, test.low, test.high)
+			}
+		}
+	}
+}
+
+// a box beyond the longitudes and latitudes is clamped for geographic columns, the hotspots must stay within it
+func TestRandomGeographyClustered(t *testing.T) {
+	if err := SetGeoBox("170,80,400,200"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetGeoDistribution("clustered(3,0.01)"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		SetGeoBox("-180,-90,180,90")
+		SetGeoDistribution("uniform")
+	})
+
+	field := db.Field{ColumnName: "location", DataType: "geography", GeometryType: "point", SRID: 4326}
+	pointRe := regexp.MustCompile(`POINT\(([-0-9.]+) ([-0-9.]+)\)`)
+	onEdge := 0
+	for range 200 {
+		value := (&Insert{}).randomGeometry(field).String()
+		m := pointRe.FindStringSubmatch(value)
+		if m == nil {
+			t.Fatalf("%s is not a point", value)
+		}
+		var x, y float64
+		fmt.Sscan(m[1], &x)
+		fmt.Sscan(m[2], &y)
+		if x < 170 || x > 180 || y < 80 || y > 90 {
+			t.Fatalf("%s is out of the clamped box", value)
+		}
+		if x == 180 || y == 90 {
+			onEdge++
+		}
+	}
+	if onEdge > 20 {
+		t.Errorf("%d points out of 200 collapsed onto the edge of the box", onEdge)
+	}
+}
@@ -37,6 +37,20 @@ func (_ *Null) IsQuotable() bool {
 	return false
 }
 
+// Literal is a value computed in advance, like enumerated unique values or values allowed by a CHECK constraint
+type Literal struct {
+	value    string
+	quotable bool
+}
+
+func (l *Literal) String() string {
+	return l.value
+}
+
+func (l *Literal) IsQuotable() bool {
+	return l.quotable
+}
+
 type InsertValues []Getter
 
 func (iv InsertValues) String() string {
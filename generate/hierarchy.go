@@ -0,0 +1,256 @@
+package generate
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+// Hierarchy is the shape of the tree built by a self-referencing foreign key.
+// Roots are inserted first with a NULL parent, then every level picks its parents among the rows of the previous level only
+type Hierarchy struct {
+	Column    string
+	Depth     int     // number of levels, roots included. 0 inserts levels until the rows are exhausted
+	Roots     float64 // fraction of the rows being roots, 0 to derive it from the depth and the branching
+	Branching string  // number of children per parent: n, poisson(mean) or uniform(min,max)
+	children  func() int64
+	mean      float64
+}
+
+const defaultBranching = "poisson(2)"
+
+var branchingRe = regexp.MustCompile(`^(?i)(poisson|uniform)\(\s*([0-9.]+)\s*(?:,\s*([0-9]+)\s*)?\)$`)
+
+// ParseHierarchy reads a spec like "depth:6,branching:poisson(4)" or "roots:5%,branching:3" for the column holding the parent
+func ParseHierarchy(column, spec string) (*Hierarchy, error) {
+	h := &Hierarchy{Column: column, Branching: defaultBranching}
+	for _, option := range splitOptions(spec) {
+		key, value, ok := strings.Cut(option, ":")
+		if !ok {
+			return nil, errors.Errorf("hierarchy option %q should be key:value", option)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "depth":
+			depth, err := strconv.Atoi(value)
+			if err != nil || depth < 1 {
+				return nil, errors.Errorf("hierarchy depth %q should be a positive integer", value)
+			}
+			h.Depth = depth
+		case "roots":
+			roots, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil || roots <= 0 || roots > 100 {
+				return nil, errors.Errorf("hierarchy roots %q should be a percentage of the rows", value)
+			}
+			h.Roots = roots / 100
+		case "branching":
+			h.Branching = value
+		default:
+			return nil, errors.Errorf("unknown hierarchy option %s, expected depth, roots or branching", key)
+		}
+	}
+
+	var err error
+	h.children, h.mean, err = parseBranching(h.Branching)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// splitOptions splits on commas outside of parentheses
+func splitOptions(spec string) []string {
+	options := []string{}
+	depth, start := 0, 0
+	for i, c := range spec {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				options = append(options, spec[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if strings.TrimSpace(spec[start:]) != "" {
+		options = append(options, spec[start:])
+	}
+	return options
+}
+
+func parseBranching(branching string) (func() int64, float64, error) {
+	return parseCount("hierarchy branching", branching, 1)
+}
+
+// parseCount reads n, poisson(mean) or uniform(min,max), n and max being at least minimum
+func parseCount(name, spec string, minimum int64) (func() int64, float64, error) {
+	if n, err := strconv.ParseInt(spec, 10, 64); err == nil {
+		if n < minimum {
+			return nil, 0, errors.Errorf("%s %q should be at least %d", name, spec, minimum)
+		}
+		return func() int64 { return n }, float64(n), nil
+	}
+	matches := branchingRe.FindStringSubmatch(spec)
+	if matches == nil {
+		return nil, 0, errors.Errorf("%s %q should be n, poisson(mean) or uniform(min,max)", name, spec)
+	}
+	switch strings.ToLower(matches[1]) {
+	case "poisson":
+		mean, err := strconv.ParseFloat(matches[2], 64)
+		if err != nil || mean <= 0 || matches[3] != "" {
+			return nil, 0, errors.Errorf("%s %q should have a positive mean", name, spec)
+		}
+		return func() int64 { return poisson(mean) }, mean, nil
+	}
+	from, errFrom := strconv.ParseInt(matches[2], 10, 64)
+	to, errTo := strconv.ParseInt(matches[3], 10, 64)
+	if errFrom != nil || errTo != nil || from > to || to < max(minimum, 1) {
+		return nil, 0, errors.Errorf("%s %q should be uniform(min,max) with 0 <= min <= max, max being at least %d", name, spec, max(minimum, 1))
+	}
+	return func() int64 { return from + rand.Int63n(to-from+1) }, float64(from+to) / 2, nil
+}
+
+// poisson uses Knuth's algorithm, and a normal approximation for large means
+func poisson(mean float64) int64 {
+	if mean > 30 {
+		return max(0, int64(math.Round(mean+math.Sqrt(mean)*rand.NormFloat64())))
+	}
+	limit, p := math.Exp(-mean), 1.0
+	var k int64
+	for {
+		p *= rand.Float64()
+		if p <= limit {
+			return k
+		}
+		k++
+	}
+}
+
+// RootCount returns how many of the rows are roots.
+// Without a roots percentage, a full tree of Depth levels with the mean branching holds the rows
+func (h *Hierarchy) RootCount(rows int64) int64 {
+	if rows < 1 {
+		return 0
+	}
+	if h.Roots > 0 {
+		return max(1, int64(math.Round(float64(rows)*h.Roots)))
+	}
+	if h.Depth == 0 {
+		return 1
+	}
+	var treeSize, levelSize float64 = 0, 1
+	for i := 0; i < h.Depth; i++ {
+		treeSize += levelSize
+		levelSize *= h.mean
+	}
+	return min(rows, max(1, int64(float64(rows)/treeSize)))
+}
+
+func (h *Hierarchy) String() string {
+	s := fmt.Sprintf("%s, branching %s", h.Column, h.Branching)
+	if h.Depth > 0 {
+		s += fmt.Sprintf(", depth %d", h.Depth)
+	}
+	if h.Roots > 0 {
+		s += fmt.Sprintf(", %g%% roots", h.Roots*100)
+	}
+	return s
+}
+
+// levelParents hands out the parents of the level being inserted, every parent once per child.
+// Retried inserts wrap around the parents of the level
+type levelParents struct {
+	constraint *db.Constraint
+	mutex      sync.Mutex
+	keys       []Getter
+	next       int
+}
+
+func (p *levelParents) fill(values [][]Getter) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for i := range values {
+		values[i][0] = p.keys[p.next%len(p.keys)]
+		p.next++
+	}
+}
+
+// HierarchyConstraint returns the self-referencing foreign key holding the parent in column
+func HierarchyConstraint(table *db.Table, column string) (*db.Constraint, error) {
+	for _, c := range table.Constraints {
+		if !c.References(table) || len(c.ColumnsName) != 1 || !strings.EqualFold(c.ColumnsName[0], column) {
+			continue
+		}
+		// rows of a level are read back as the last ones inserted
+		if !c.ReferencedFields[0].AutoIncrement {
+			return nil, errors.Errorf("%s.%s references %s, which is not auto-incremented", table.Name, column, c.ReferencedColumnsName[0])
+		}
+		return c, nil
+	}
+	return nil, errors.Errorf("%s.%s is not a single column self-referencing foreign key", table.Name, column)
+}
+
+// RunLevels inserts count rows below the roots already inserted, level by level.
+// Every row of a level gets a parent from the previous level, each parent getting a number of children drawn from the branching
+func (in *Insert) RunLevels(h *Hierarchy, roots, count, bulksize int64) error {
+	constraint, err := HierarchyConstraint(in.table, h.Column)
+	if err != nil {
+		return err
+	}
+	refField := constraint.ReferencedFields[0]
+	lastKeys := func(n int) ([][]Getter, error) {
+		return scanKeys(fmt.Sprintf("SELECT %s FROM %s.%s WHERE %s IS NOT NULL ORDER BY 1 DESC LIMIT %d",
+			db.Escape(refField.ColumnName), db.Escape(in.table.Schema), db.Escape(in.table.Name), db.Escape(refField.ColumnName), n), []db.Field{refField})
+	}
+
+	previous, err := lastKeys(int(roots))
+	if err != nil {
+		return err
+	}
+	level := 1
+	for count > 0 && len(previous) > 0 && (h.Depth == 0 || level < h.Depth) {
+		parents := []Getter{}
+	PARENTS:
+		for _, key := range previous {
+			for n := h.children(); n > 0; n-- {
+				if int64(len(parents)) == count {
+					break PARENTS
+				}
+				parents = append(parents, key[0])
+			}
+		}
+		if len(parents) == 0 {
+			break
+		}
+
+		in.parents = &levelParents{constraint: constraint, keys: parents}
+		err = in.run(int64(len(parents)), bulksize, false)
+		in.parents = nil
+		if err != nil {
+			return errors.Wrapf(err, "level %d", level)
+		}
+		log.Debug().Str("table", in.table.Name).Int("level", level).Int("rows", len(parents)).Int("parents", len(previous)).Msg("hierarchy level inserted")
+
+		if previous, err = lastKeys(len(parents)); err != nil {
+			return err
+		}
+		count -= int64(len(parents))
+		level++
+	}
+	if count > 0 {
+		log.Warn().Str("table", in.table.Name).Int("levels", level).Int64("rows left", count).Msg("the hierarchy stopped growing before using every row: increase the depth, the branching or the roots")
+	}
+	return nil
+}
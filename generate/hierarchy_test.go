@@ -0,0 +1,58 @@
+package generate
+
+import "testing"
+
+func TestParseCount(t *testing.T) {
+	tests := []struct {
+		spec    string
+		minimum int64
+		mean    float64
+		low     int64 // bounds of the drawn counts
+		high    int64
+		err     bool
+	}{
+		{spec: "4", minimum: 1, mean: 4, low: 4, high: 4},
+		{spec: "0", minimum: 0, mean: 0, low: 0, high: 0},
+		{spec: "uniform(0,5)", minimum: 0, mean: 2.5, low: 0, high: 5},
+		{spec: "UNIFORM( 3 , 8 )", minimum: 3, mean: 5.5, low: 3, high: 8},
+		{spec: "uniform(2,2)", minimum: 1, mean: 2, low: 2, high: 2},
+		{spec: "poisson(4)", minimum: 1, mean: 4, low: 0, high: 1 << 62},
+		{spec: "poisson(0.5)", minimum: 0, mean: 0.5, low: 0, high: 1 << 62},
+
+		{spec: "0", minimum: 1, err: true},
+		{spec: "-1", minimum: 0, err: true},
+		{spec: "2", minimum: 3, err: true},
+		{spec: "uniform(1,2)", minimum: 3, err: true},
+		{spec: "uniform(0,0)", minimum: 0, err: true},
+		{spec: "uniform(5,3)", minimum: 0, err: true},
+		{spec: "uniform(-1,3)", minimum: 0, err: true},
+		{spec: "uniform(1.5,3)", minimum: 0, err: true},
+		{spec: "uniform(3)", minimum: 0, err: true},
+		{spec: "poisson(0)", minimum: 0, err: true},
+		{spec: "poisson(-2)", minimum: 0, err: true},
+		{spec: "poisson(2,3)", minimum: 0, err: true},
+		{spec: "normal(2)", minimum: 0, err: true},
+		{spec: "", minimum: 0, err: true},
+	}
+	for _, test := range tests {
+		count, mean, err := parseCount("count", test.spec, test.minimum)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.spec, err)
+			continue
+		}
+		if mean != test.mean {
+			t.Errorf("%s: expected a mean of %v, got %v", test.spec, test.mean, mean)
+		}
+		for range 100 {
+			if n := count(); n < test.low || n > test.high {
+				t.Fatalf("%s: %d is out of [%d, %d]", test.spec, n, test.low, test.high)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package generate
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+// HintStatus tells whether the rdl: annotation of a column comment sets its values
+type HintStatus struct {
+	Column     string
+	Annotation string
+	Applied    bool
+	Reason     string
+}
+
+// StringGenerators are the names gen() accepts, each one picks the generator of the columns named like it
+var StringGenerators = []string{"email", "first_name", "last_name", "name", "phone", "ssn", "zip", "color", "city", "country", "ip_address", "address", "product", "description", "feature", "material", "currency", "company", "language"}
+
+// buildHints turns the column hints into generators. Unique columns, partition keys and CHECK constraints have priority over them
+func (in *Insert) buildHints() {
+	in.hintStatuses = []HintStatus{}
+	in.hintGenerators = map[string]func() Getter{}
+
+	sampled := map[string]bool{}
+	for _, field := range in.table.ConstraintsToSample().Fields() {
+		sampled[strings.ToLower(field.ColumnName)] = true
+	}
+	for _, field := range in.table.Fields {
+		h := field.Hints
+		// null() is applied with the frequencies
+		if h == nil || h.Generator == "" && h.Zipf == 0 && h.Min == "" && len(h.Values) == 0 {
+			continue
+		}
+		key := strings.ToLower(field.ColumnName)
+		status := HintStatus{Column: field.ColumnName, Annotation: h.Annotation}
+		switch {
+		case field.Skip || field.Generated:
+			continue
+		case sampled[key]:
+			status.Reason = "sampled from a foreign key"
+		case in.isUnique(field):
+			status.Reason = "unique column, its values are enumerated"
+		case in.isPartitionKey(field):
+			status.Reason = "partition key, its values are picked in the partitions"
+		case in.checkGenerators[key] != nil:
+			status.Reason = "a CHECK constraint sets its values"
+		default:
+			gen, err := in.hintGenerator(field, *h)
+			if err != nil {
+				status.Reason = err.Error()
+				break
+			}
+			in.hintGenerators[key] = gen
+			status.Applied = true
+		}
+		in.hintStatuses = append(in.hintStatuses, status)
+	}
+}
+
+func (in *Insert) hintGenerator(field db.Field, h db.Hints) (func() Getter, error) {
+	switch {
+	case len(h.Values) > 0:
+		if isNumericType(field.DataType) {
+			for _, v := range h.Values {
+				if _, err := strconv.ParseFloat(v, 64); err != nil {
+					return nil, errors.Errorf("values(): %s is not a number", v)
+				}
+			}
+		}
+		if field.DataType == "enum" {
+			for _, v := range h.Values {
+				if !slices.Contains(field.SetEnumVals, v) {
+					return nil, errors.Errorf("values(): %s is not a value of the enum", v)
+				}
+			}
+		}
+		quotable := !isNumericType(field.DataType)
+		pick := func() uint64 { return uint64(rand.Intn(len(h.Values))) }
+		if h.Zipf != 0 {
+			pick = zipfPicker(h.Zipf, uint64(len(h.Values)-1))
+		}
+		return func() Getter { return &Literal{h.Values[pick()], quotable} }, nil
+
+	case h.Generator != "":
+		if !isUniqueStringField(field) {
+			return nil, errors.Errorf("gen() only applies to strings, not %s", field.DataType)
+		}
+		if !slices.Contains(StringGenerators, h.Generator) {
+			return nil, errors.Errorf("gen(): unknown generator %s, use one of %s", h.Generator, strings.Join(StringGenerators, ", "))
+		}
+		if h.Zipf != 0 || h.Min != "" {
+			return nil, errors.New("gen() cannot be combined with zipf() or range()")
+		}
+		maxSize := min(in.maxTextSize, field.CharacterMaximumLength.Int64)
+		return func() Getter { return NewRandomString(h.Generator, maxSize) }, nil
+
+	case h.Zipf != 0:
+		lo, hi, err := hintIntRange(field, h)
+		if err != nil {
+			return nil, err
+		}
+		pick := zipfPicker(h.Zipf, uint64(hi-lo))
+		return func() Getter { return &Literal{strconv.FormatInt(lo+int64(pick()), 10), false} }, nil
+	}
+
+	if !isNumericType(field.DataType) && !slices.Contains([]string{"date", "datetime", "timestamp", "time"}, field.DataType) {
+		return nil, errors.Errorf("range() only applies to numbers, dates and times, not %s", field.DataType)
+	}
+	gen, err := boundedGenerator(field, []checkBound{{value: h.Min}}, []checkBound{{value: h.Max}})
+	return gen, errors.Wrap(err, "range()")
+}
+
+// hintIntRange returns the bounds of range(), or the range of the integer type
+func hintIntRange(field db.Field, h db.Hints) (int64, int64, error) {
+	if !isUniqueIntField(field) && field.DataType != "year" {
+		return 0, 0, errors.Errorf("zipf() needs values() or an integer column, not %s", field.DataType)
+	}
+	if h.Min == "" {
+		lo, hi := intRange(field)
+		return lo, hi, nil
+	}
+	lo, errMin := strconv.ParseInt(h.Min, 10, 64)
+	hi, errMax := strconv.ParseInt(h.Max, 10, 64)
+	if errMin != nil || errMax != nil || lo > hi {
+		return 0, 0, errors.Errorf("range(%s,%s) should be 2 ordered integers", h.Min, h.Max)
+	}
+	return lo, hi, nil
+}
+
+// zipfPicker returns values from 0 to imax, 0 being the most frequent. It is safe for the concurrent workers
+func zipfPicker(s float64, imax uint64) func() uint64 {
+	var mutex sync.Mutex
+	zipf := rand.NewZipf(rand.New(rand.NewSource(time.Now().UnixNano())), s, 1, imax)
+	return func() uint64 {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return zipf.Uint64()
+	}
+}
+
+// HintStatuses reports the columns having hints, and whether they set their values
+func (in *Insert) HintStatuses() []HintStatus {
+	return in.hintStatuses
+}
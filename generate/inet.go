@@ -0,0 +1,64 @@
+package generate
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+type RandomInet struct {
+	value string
+}
+
+func (r *RandomInet) String() string {
+	return r.value
+}
+
+func (r *RandomInet) IsQuotable() bool {
+	return true
+}
+
+func NewRandomInet(v6 bool) *RandomInet {
+	if v6 {
+		return &RandomInet{gofakeit.IPv6Address()}
+	}
+	return &RandomInet{gofakeit.IPv4Address()}
+}
+
+// RandomCIDR generates an IPv4 network, its host bits are zero as cidr requires
+type RandomCIDR struct {
+	value string
+}
+
+func (r *RandomCIDR) String() string {
+	return r.value
+}
+
+func (r *RandomCIDR) IsQuotable() bool {
+	return true
+}
+
+func NewRandomCIDR() *RandomCIDR {
+	prefix := 8 + rand.Intn(25)
+	ip := net.ParseIP(gofakeit.IPv4Address()).To4()
+	network := ip.Mask(net.CIDRMask(prefix, 32))
+	return &RandomCIDR{fmt.Sprintf("%s/%d", network, prefix)}
+}
+
+type RandomMacAddr struct {
+	value string
+}
+
+func (r *RandomMacAddr) String() string {
+	return r.value
+}
+
+func (r *RandomMacAddr) IsQuotable() bool {
+	return true
+}
+
+func NewRandomMacAddr() *RandomMacAddr {
+	return &RandomMacAddr{gofakeit.MacAddress()}
+}
@@ -2,7 +2,10 @@ package generate
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
+
+	"github.com/ylacancellera/random-data-load/db"
 )
 
 type RandomInt struct {
@@ -33,7 +36,38 @@ func (r *RandomIntRange) IsQuotable() bool {
 	return false
 }
 
+// NewRandomIntRange returns a value from min to max included, the range can be as wide as int64
 func NewRandomIntRange(min, max int64) *RandomIntRange {
-	limit := max - min + 1
-	return &RandomIntRange{min + rand.Int63n(limit)}
+	width := uint64(max - min)
+	if width == math.MaxUint64 {
+		return &RandomIntRange{int64(rand.Uint64())}
+	}
+	return &RandomIntRange{min + int64(rand.Uint64()%(width+1))}
+}
+
+// intRange returns the lowest and highest values of an integer type.
+// MySQL column types and PostgreSQL integers span their whole range. The declared types of SQLite do not bound the values,
+// so they keep to positive values below maxValues. Unsigned bigints are capped at the highest signed bigint
+func intRange(field db.Field) (int64, int64) {
+	bits := map[string]uint{"tinyint": 8, "smallint": 16, "mediumint": 24, "int": 32, "integer": 32, "bigint": 64}[field.DataType]
+	switch {
+	case field.DataType == "year":
+		return 1901, 2155
+	case bits == 0:
+		return 0, 0
+	case !field.FullIntRange && field.DataType == "tinyint":
+		return 0, math.MaxInt8
+	case !field.FullIntRange:
+		return 0, maxValues[field.DataType]
+	case field.DataType == "tinyint" && field.DisplayWidth == 1:
+		// booleans of MySQL
+		return 0, 1
+	case bits == 64 && field.Unsigned:
+		return 0, math.MaxInt64
+	case bits == 64:
+		return math.MinInt64, math.MaxInt64
+	case field.Unsigned:
+		return 0, 1<<bits - 1
+	}
+	return -1 << (bits - 1), 1<<(bits-1) - 1
 }
@@ -0,0 +1,64 @@
+package generate
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+func TestIntRange(t *testing.T) {
+	tests := []struct {
+		field  db.Field
+		lo, hi int64
+	}{
+		// SQLite and memdb columns keep to positive values
+		{field: db.Field{DataType: "tinyint"}, lo: 0, hi: math.MaxInt8},
+		{field: db.Field{DataType: "smallint"}, lo: 0, hi: 0xFF},
+		{field: db.Field{DataType: "int"}, lo: 0, hi: 0x7FFFFFFF},
+		{field: db.Field{DataType: "integer"}, lo: 0, hi: 0x7FFFFFFF},
+		{field: db.Field{DataType: "bigint"}, lo: 0, hi: math.MaxInt64},
+		{field: db.Field{DataType: "year"}, lo: 1901, hi: 2155},
+		// PostgreSQL integers span their type
+		{field: db.Field{DataType: "smallint", FullIntRange: true}, lo: math.MinInt16, hi: math.MaxInt16},
+		{field: db.Field{DataType: "integer", FullIntRange: true}, lo: math.MinInt32, hi: math.MaxInt32},
+		{field: db.Field{DataType: "bigint", FullIntRange: true}, lo: math.MinInt64, hi: math.MaxInt64},
+		// MySQL columns span their type
+		{field: db.Field{DataType: "tinyint", FullIntRange: true}, lo: math.MinInt8, hi: math.MaxInt8},
+		{field: db.Field{DataType: "tinyint", DisplayWidth: 1, FullIntRange: true}, lo: 0, hi: 1},
+		{field: db.Field{DataType: "smallint", Unsigned: true, FullIntRange: true}, lo: 0, hi: math.MaxUint16},
+		{field: db.Field{DataType: "mediumint", FullIntRange: true}, lo: -1 << 23, hi: 1<<23 - 1},
+		{field: db.Field{DataType: "int", Unsigned: true, FullIntRange: true}, lo: 0, hi: math.MaxUint32},
+		{field: db.Field{DataType: "bigint", FullIntRange: true}, lo: math.MinInt64, hi: math.MaxInt64},
+		{field: db.Field{DataType: "bigint", Unsigned: true, FullIntRange: true}, lo: 0, hi: math.MaxInt64},
+	}
+	for _, test := range tests {
+		lo, hi := intRange(test.field)
+		if lo != test.lo || hi != test.hi {
+			t.Errorf("%+v: expected %d to %d, got %d to %d", test.field, test.lo, test.hi, lo, hi)
+		}
+	}
+}
+
+func TestRandomGetterInt(t *testing.T) {
+	in := &Insert{}
+	negative := false
+	for range 200 {
+		flag := in.randomGetter(db.Field{DataType: "tinyint"}).String()
+		if flag != "0" && flag != "1" {
+			t.Fatalf("tinyint %s is not 0 or 1 out of MySQL", flag)
+		}
+		value := in.randomGetter(db.Field{DataType: "smallint"}).(*RandomInt).value
+		if value < 0 || value >= 0xFF {
+			t.Fatalf("smallint %d is out of 0 to 0xFF on SQLite", value)
+		}
+		value = in.randomGetter(db.Field{DataType: "smallint", FullIntRange: true}).(*RandomIntRange).value
+		if value < math.MinInt16 || value > math.MaxInt16 {
+			t.Fatalf("smallint %d is out of its range on PostgreSQL", value)
+		}
+		negative = negative || value < 0
+	}
+	if !negative {
+		t.Error("expected negative smallints on PostgreSQL")
+	}
+}
@@ -0,0 +1,23 @@
+package generate
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// RandomInterval generates a PostgreSQL interval of up to a year, e.g. '12 days 03:25:41'
+type RandomInterval struct {
+	value string
+}
+
+func (r *RandomInterval) String() string {
+	return r.value
+}
+
+func (r *RandomInterval) IsQuotable() bool {
+	return true
+}
+
+func NewRandomInterval() *RandomInterval {
+	return &RandomInterval{fmt.Sprintf("%d days %02d:%02d:%02d", rand.Intn(366), rand.Intn(24), rand.Intn(60), rand.Intn(60))}
+}
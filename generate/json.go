@@ -0,0 +1,375 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/pkg/errors"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+// RandomJSON generates a small flat object, enough to satisfy json_valid() and json columns
+type RandomJSON struct {
+	value string
+}
+
+func (r *RandomJSON) String() string {
+	return r.value
+}
+
+func (r *RandomJSON) IsQuotable() bool {
+	return true
+}
+
+func NewRandomJSON() *RandomJSON {
+	doc := map[string]any{
+		"id":     gofakeit.IntRange(1, 1000000),
+		"name":   gofakeit.Word(),
+		"active": gofakeit.Bool(),
+		"tags":   []string{gofakeit.Word(), gofakeit.Word()},
+	}
+	return newJSONLiteral(doc)
+}
+
+func newJSONLiteral(doc any) *RandomJSON {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		// generated documents only hold strings, numbers, booleans, maps and slices
+		panic(err)
+	}
+	return &RandomJSON{strings.ReplaceAll(strings.TrimSuffix(b.String(), "\n"), "'", "''")}
+}
+
+// JSONSchemas maps "schema.table.column" to the shape of the documents of JSON columns, see SetJSONSchema
+var JSONSchemas = map[string]*JSONSchema{}
+
+// SetJSONSchema shapes the documents generated for a JSON column
+func SetJSONSchema(table *db.Table, column string, schema *JSONSchema) {
+	JSONSchemas[strings.ToLower(table.FullName()+"."+column)] = schema
+}
+
+// ColumnJSONSchema returns the shape of the documents of a JSON column, nil when the column has none
+func ColumnJSONSchema(table *db.Table, column string) *JSONSchema {
+	return JSONSchemas[strings.ToLower(table.FullName()+"."+column)]
+}
+
+// JSONSchema is the subset of JSON Schema shaping the generated documents.
+// It is read from a --json-schema file, or built from the keys the query reads
+type JSONSchema struct {
+	Type       JSONTypes              `json:"type,omitempty"`
+	Properties map[string]*JSONSchema `json:"properties,omitempty"`
+	Required   []string               `json:"required,omitempty"`
+	Items      *JSONSchema            `json:"items,omitempty"`
+	Enum       []any                  `json:"enum,omitempty"`
+	Const      any                    `json:"const,omitempty"`
+	AnyOf      []*JSONSchema          `json:"anyOf,omitempty"`
+	OneOf      []*JSONSchema          `json:"oneOf,omitempty"`
+	Format     string                 `json:"format,omitempty"` // email, date, date-time, time, uuid, ipv4, uri
+	Minimum    *float64               `json:"minimum,omitempty"`
+	Maximum    *float64               `json:"maximum,omitempty"`
+	MinLength  *int                   `json:"minLength,omitempty"`
+	MaxLength  *int                   `json:"maxLength,omitempty"`
+	MinItems   *int                   `json:"minItems,omitempty"`
+	MaxItems   *int                   `json:"maxItems,omitempty"`
+
+	Source string `json:"-"` // where the schema comes from, for reporting
+}
+
+// JSONTypes is the type keyword, a single type or a list of them
+type JSONTypes []string
+
+func (t *JSONTypes) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*t = JSONTypes{single}
+		return nil
+	}
+	var types []string
+	if err := json.Unmarshal(b, &types); err != nil {
+		return errors.New("type should be a string or a list of strings")
+	}
+	*t = types
+	return nil
+}
+
+var jsonSchemaTypes = []string{"object", "array", "string", "integer", "number", "boolean", "null"}
+
+// LoadJSONSchema reads a JSON Schema file. $ref, patternProperties and the other keywords are not supported, they are ignored
+func LoadJSONSchema(filename string) (*JSONSchema, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	schema := &JSONSchema{}
+	if err := json.Unmarshal(b, schema); err != nil {
+		return nil, errors.Wrapf(err, "invalid JSON Schema %s", filename)
+	}
+	if err := schema.validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid JSON Schema %s", filename)
+	}
+	return schema, nil
+}
+
+func (s *JSONSchema) validate() error {
+	for _, t := range s.Type {
+		if !slices.Contains(jsonSchemaTypes, t) {
+			return errors.Errorf("unknown type %s, use one of %s", t, strings.Join(jsonSchemaTypes, ", "))
+		}
+	}
+	if s.Minimum != nil && s.Maximum != nil && *s.Minimum > *s.Maximum {
+		return errors.New("minimum is greater than maximum")
+	}
+	for _, child := range s.children() {
+		if err := child.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *JSONSchema) children() []*JSONSchema {
+	children := slices.Concat(s.AnyOf, s.OneOf)
+	for _, child := range s.Properties {
+		children = append(children, child)
+	}
+	if s.Items != nil {
+		children = append(children, s.Items)
+	}
+	return slices.DeleteFunc(children, func(c *JSONSchema) bool { return c == nil })
+}
+
+// a path segment: .key, ."key", [0] or [*]
+var jsonPathSegmentRe = regexp.MustCompile(`^(?:\.([A-Za-z_$][\w$]*)|\."((?:[^"\\]|\\.)*)"|\[(\d+|\*)\])`)
+
+// AddPath makes the key of the path required, creating the objects and arrays leading to it.
+// The type is only set when the schema does not have one already
+func (s *JSONSchema) AddPath(path, valueType string) error {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return errors.Errorf("JSON path %s should start with $", path)
+	}
+	node := s
+	for rest != "" {
+		m := jsonPathSegmentRe.FindStringSubmatch(rest)
+		if m == nil {
+			return errors.Errorf("unsupported JSON path %s", path)
+		}
+		rest = rest[len(m[0]):]
+		if m[3] != "" {
+			node.setType("array")
+			if node.Items == nil {
+				node.Items = &JSONSchema{}
+			}
+			// the index read has to exist, or at least an element
+			minItems := 1
+			if i, err := strconv.Atoi(m[3]); err == nil {
+				minItems = i + 1
+			}
+			if node.MinItems == nil || *node.MinItems < minItems {
+				node.MinItems = &minItems
+			}
+			node = node.Items
+			continue
+		}
+		key := m[1]
+		if m[2] != "" {
+			key = strings.ReplaceAll(m[2], `\"`, `"`)
+		}
+		node.setType("object")
+		if node.Properties == nil {
+			node.Properties = map[string]*JSONSchema{}
+		}
+		if node.Properties[key] == nil {
+			node.Properties[key] = &JSONSchema{}
+		}
+		if !slices.Contains(node.Required, key) {
+			node.Required = append(node.Required, key)
+		}
+		node = node.Properties[key]
+	}
+	if valueType != "" {
+		node.setType(valueType)
+	}
+	return nil
+}
+
+func (s *JSONSchema) setType(t string) {
+	if len(s.Type) == 0 {
+		s.Type = JSONTypes{t}
+	}
+}
+
+// randomJSON generates a document following the schema of the column, values of the query being injected at their paths
+func (in *Insert) randomJSON(field db.Field, schema *JSONSchema) Getter {
+	inject := func(path string) (string, bool) {
+		return in.frequencies.InjectIndexValue(field.ColumnName + path)
+	}
+	return newJSONLiteral(schema.generate("$", inject))
+}
+
+func (s *JSONSchema) generate(path string, inject func(path string) (string, bool)) any {
+	switch {
+	case len(s.AnyOf) > 0:
+		return s.AnyOf[rand.Intn(len(s.AnyOf))].generate(path, inject)
+	case len(s.OneOf) > 0:
+		return s.OneOf[rand.Intn(len(s.OneOf))].generate(path, inject)
+	case s.Const != nil:
+		return jsonSafeValue(s.Const)
+	case len(s.Enum) > 0:
+		return jsonSafeValue(s.Enum[rand.Intn(len(s.Enum))])
+	}
+
+	t := ""
+	if len(s.Type) > 0 {
+		t = s.Type[rand.Intn(len(s.Type))]
+	}
+	if value, ok := inject(path); ok && t != "object" && t != "array" {
+		return typedJSONValue(value, t)
+	}
+	switch {
+	case t == "object", t == "" && s.Properties != nil:
+		doc := map[string]any{}
+		for key, property := range s.Properties {
+			// optional keys are there half of the time
+			if !slices.Contains(s.Required, key) && rand.Intn(2) == 0 {
+				continue
+			}
+			doc[key] = property.generate(path+jsonKeySegment(key), inject)
+		}
+		return doc
+	case t == "array", t == "" && s.Items != nil:
+		length := int(ArrayLength())
+		if s.MinItems != nil {
+			length = max(length, *s.MinItems)
+		}
+		if s.MaxItems != nil {
+			length = min(length, *s.MaxItems)
+		}
+		items := s.Items
+		if items == nil {
+			items = &JSONSchema{}
+		}
+		doc := make([]any, 0, length)
+		anyElement := path + "[*]"
+		for i := range length {
+			// the query can read an element by its index
+			element := path + "[" + strconv.Itoa(i) + "]"
+			elementInject := func(p string) (string, bool) {
+				if value, ok := inject(element + strings.TrimPrefix(p, anyElement)); ok {
+					return value, true
+				}
+				return inject(p)
+			}
+			doc = append(doc, items.generate(anyElement, elementInject))
+		}
+		return doc
+	case t == "integer":
+		lo, hi := s.bounds(0, 1000)
+		return gofakeit.IntRange(int(math.Ceil(lo)), int(math.Floor(hi)))
+	case t == "number":
+		lo, hi := s.bounds(0, 1000)
+		return math.Round(gofakeit.Float64Range(lo, hi)*100) / 100
+	case t == "boolean":
+		return gofakeit.Bool()
+	case t == "null":
+		return nil
+	}
+	return s.randomString()
+}
+
+func (s *JSONSchema) bounds(lo, hi float64) (float64, float64) {
+	switch {
+	case s.Minimum != nil && s.Maximum != nil:
+		return *s.Minimum, *s.Maximum
+	case s.Minimum != nil:
+		return *s.Minimum, *s.Minimum + hi - lo
+	case s.Maximum != nil:
+		return *s.Maximum - hi + lo, *s.Maximum
+	}
+	return lo, hi
+}
+
+func (s *JSONSchema) randomString() string {
+	var value string
+	switch s.Format {
+	case "email":
+		value = gofakeit.Email()
+	case "date":
+		value = gofakeit.Date().Format(time.DateOnly)
+	case "date-time":
+		value = gofakeit.Date().Format(time.RFC3339)
+	case "time":
+		value = gofakeit.Date().Format(time.TimeOnly)
+	case "uuid":
+		value = gofakeit.UUID()
+	case "ipv4":
+		value = gofakeit.IPv4Address()
+	case "uri":
+		value = gofakeit.URL()
+	default:
+		value = gofakeit.Word()
+		if s.MinLength != nil {
+			for len(value) < *s.MinLength {
+				value += " " + gofakeit.Word()
+			}
+		}
+	}
+	if s.MaxLength != nil && len(value) > *s.MaxLength {
+		value = value[:*s.MaxLength]
+	}
+	return jsonSafeString(value)
+}
+
+// typedJSONValue turns a value of the query into the type of the key
+func typedJSONValue(value, t string) any {
+	switch t {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return jsonSafeString(value)
+}
+
+func jsonSafeValue(value any) any {
+	if s, ok := value.(string); ok {
+		return jsonSafeString(s)
+	}
+	return value
+}
+
+// jsonSafeString removes what JSON would escape with a backslash, MySQL reading backslashes in string literals
+func jsonSafeString(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '"' || r == '\\' || r < ' ' {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+var jsonSimpleKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonKeySegment writes a key of a path like the query analysis does, so that the values of the query are found
+func jsonKeySegment(key string) string {
+	if jsonSimpleKeyRe.MatchString(key) {
+		return "." + key
+	}
+	return `."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
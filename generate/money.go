@@ -0,0 +1,23 @@
+package generate
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// RandomMoney generates an amount with cents, quoted so that PostgreSQL reads it as money
+type RandomMoney struct {
+	value string
+}
+
+func (r *RandomMoney) String() string {
+	return r.value
+}
+
+func (r *RandomMoney) IsQuotable() bool {
+	return true
+}
+
+func NewRandomMoney() *RandomMoney {
+	return &RandomMoney{fmt.Sprintf("%d.%02d", rand.Int63n(1000000), rand.Intn(100))}
+}
@@ -0,0 +1,396 @@
+package generate
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+const (
+	PartitionEven         = "even"
+	PartitionProportional = "proportional"
+	PartitionRecent       = "recent"
+)
+
+// PartitionDistribution is how rows are spread over the partitions: even, proportional to the rows they already hold, or skewed toward the last range partitions
+var PartitionDistribution = PartitionEven
+
+// PartitionStatus tells how rows are spread over the partitions of the table
+type PartitionStatus struct {
+	Method       string
+	Column       string
+	Expression   string
+	Distribution string
+	Supported    bool
+	Reason       string
+	Partitions   []PartitionShare
+}
+
+// PartitionShare is the expected fraction of the rows inserted into a partition
+type PartitionShare struct {
+	Name   string
+	Bounds string
+	Share  float64
+}
+
+type partitionTarget struct {
+	name   string
+	weight float64
+	order  float64 // lower bound, to sort range partitions
+	gen    func() Getter
+}
+
+type partitionKey struct {
+	column  string
+	targets []partitionTarget
+	total   float64
+	null    bool // a partition accepts NULL
+}
+
+const (
+	oneDay = int64(24 * 60 * 60)
+	// unbounded range partitions get the average width of the others, or these
+	defaultPartitionWidth     = 1000
+	defaultPartitionTimeWidth = oneYear
+)
+
+// TO_DAYS('1970-01-01'), to convert MySQL TO_DAYS and TO_SECONDS bounds
+const mysqlEpochDays = 719528
+
+// buildPartitions prepares the generator of the partition key, so that every row fits in a partition
+func (in *Insert) buildPartitions() {
+	p := in.table.Partitioning
+	if p == nil {
+		return
+	}
+	in.partitionStatus = &PartitionStatus{
+		Method:       p.Method,
+		Column:       p.Column,
+		Expression:   p.Expression,
+		Distribution: PartitionDistribution,
+		Supported:    true,
+	}
+	for _, partition := range p.Partitions {
+		in.partitionStatus.Partitions = append(in.partitionStatus.Partitions, PartitionShare{Name: partition.Name, Bounds: partitionBounds(p.Method, partition)})
+	}
+
+	key, err := in.newPartitionKey(p)
+	if err != nil {
+		in.partitionStatus.Supported = false
+		in.partitionStatus.Reason = err.Error()
+		return
+	}
+	in.partition = key
+	for i, share := range in.partitionStatus.Partitions {
+		for _, target := range key.targets {
+			if target.name == share.Name {
+				in.partitionStatus.Partitions[i].Share = target.weight / key.total
+			}
+		}
+	}
+}
+
+func (in *Insert) newPartitionKey(p *db.Partitioning) (*partitionKey, error) {
+	if p.Method == db.PartitionHash {
+		return nil, errors.New("hash partitions are filled evenly by random values")
+	}
+	if p.Column == "" {
+		return nil, errors.Errorf("partition key %s is not a single column", p.Expression)
+	}
+	field := in.table.FieldByName(p.Column)
+	if field == nil {
+		return nil, errors.Errorf("unknown column %s", p.Column)
+	}
+	if !slices.ContainsFunc(in.table.FieldsToGenerate(), func(f db.Field) bool { return f.ColumnName == field.ColumnName }) {
+		return nil, errors.Errorf("%s is not generated", field.ColumnName)
+	}
+	if in.isUnique(*field) {
+		return nil, errors.Errorf("%s is generated by a unique index", field.ColumnName)
+	}
+
+	key := &partitionKey{column: field.ColumnName}
+	var err error
+	switch p.Method {
+	case db.PartitionList:
+		key.targets, key.null = listPartitionTargets(*field, p.Partitions)
+	case db.PartitionRange:
+		key.targets, err = rangePartitionTargets(*field, p.Function, p.Partitions)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(key.targets) == 0 {
+		return nil, errors.New("no partition can be targeted")
+	}
+
+	switch PartitionDistribution {
+	case PartitionProportional:
+		var rows float64
+		for _, partition := range p.Partitions {
+			rows += float64(partition.Rows)
+		}
+		if rows > 0 {
+			for i := range key.targets {
+				idx := slices.IndexFunc(p.Partitions, func(partition db.Partition) bool { return partition.Name == key.targets[i].name })
+				key.targets[i].weight = float64(p.Partitions[idx].Rows)
+			}
+		}
+	case PartitionRecent:
+		if p.Method == db.PartitionRange {
+			for i := range key.targets {
+				key.targets[i].weight = float64((i + 1) * (i + 1))
+			}
+		}
+	}
+	for _, target := range key.targets {
+		key.total += target.weight
+	}
+	if key.total == 0 {
+		return nil, errors.New("every partition is empty, cannot spread rows proportionally")
+	}
+	return key, nil
+}
+
+func listPartitionTargets(field db.Field, partitions []db.Partition) ([]partitionTarget, bool) {
+	targets := []partitionTarget{}
+	acceptsNull := false
+	quotable := !isNumericType(field.DataType)
+	for _, partition := range partitions {
+		if partition.Null {
+			acceptsNull = true
+		}
+		if len(partition.Values) == 0 {
+			continue
+		}
+		values := partition.Values
+		targets = append(targets, partitionTarget{
+			name:   partition.Name,
+			weight: 1,
+			gen:    func() Getter { return &Literal{values[rand.Intn(len(values))], quotable} },
+		})
+	}
+	return targets, acceptsNull
+}
+
+// rangePartitionTargets generates values in [From, To) of each partition.
+// Numbers are handled as float64, times as unix seconds
+func rangePartitionTargets(field db.Field, function string, partitions []db.Partition) ([]partitionTarget, error) {
+	var (
+		parse  func(string) (float64, error)
+		format func(lo, hi float64) func() Getter
+		isTime bool
+	)
+
+	switch field.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year":
+		if function != "" {
+			return nil, errors.Errorf("%s(%s) is not supported", function, field.ColumnName)
+		}
+		parse = func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }
+		format = func(lo, hi float64) func() Getter {
+			from, to := int64(math.Ceil(lo)), int64(math.Ceil(hi))-1
+			if from > to {
+				return nil
+			}
+			return func() Getter { return NewRandomIntRange(from, to) }
+		}
+
+	case "float", "decimal", "double", "numeric":
+		if function != "" {
+			return nil, errors.Errorf("%s(%s) is not supported", function, field.ColumnName)
+		}
+		scale := 2
+		if field.NumericPrecision.Int64 > 0 && field.NumericScale.Valid {
+			scale = int(field.NumericScale.Int64)
+		}
+		step := math.Pow10(-scale)
+		parse = func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }
+		format = func(lo, hi float64) func() Getter {
+			from, to := math.Ceil(lo/step), math.Ceil(hi/step)-1
+			if from > to {
+				return nil
+			}
+			return func() Getter {
+				v := (from + math.Floor(rand.Float64()*(to-from+1))) * step
+				return &Literal{strconv.FormatFloat(v, 'f', scale, 64), false}
+			}
+		}
+
+	case "date", "datetime", "timestamp":
+		isTime = true
+		parse = func(s string) (float64, error) {
+			return partitionBoundSeconds(function, s)
+		}
+		format = func(lo, hi float64) func() Getter {
+			from, to := int64(math.Ceil(lo)), int64(math.Ceil(hi))-1
+			if field.DataType == "date" {
+				from, to = ceilDiv(from, oneDay), ceilDiv(to+1, oneDay)-1
+				if from > to {
+					return nil
+				}
+				return func() Getter {
+					day := from + rand.Int63n(to-from+1)
+					return &Literal{time.Unix(day*oneDay, 0).UTC().Format("2006-01-02"), true}
+				}
+			}
+			// timestamps depend on the time zone of the session, values too close to the bounds could move to another partition
+			margin := min(oneDay, (to-from)/4)
+			from, to = from+margin, to-margin
+			if from > to {
+				return nil
+			}
+			return func() Getter {
+				return &Literal{time.Unix(from+rand.Int63n(to-from+1), 0).UTC().Format("2006-01-02 15:04:05"), true}
+			}
+		}
+
+	default:
+		return nil, errors.Errorf("range partitions on %s columns are not supported", field.DataType)
+	}
+
+	type bounds struct {
+		name         string
+		lo, hi       float64
+		hasLo, hasHi bool
+	}
+	parsed := []bounds{}
+	width := float64(defaultPartitionWidth)
+	if isTime {
+		width = float64(defaultPartitionTimeWidth)
+	}
+	var widths, count float64
+	for _, partition := range partitions {
+		if partition.Default {
+			continue
+		}
+		b := bounds{name: partition.Name}
+		var err error
+		if partition.From != "" {
+			if b.lo, err = parse(partition.From); err != nil {
+				return nil, err
+			}
+			b.hasLo = true
+		}
+		if partition.To != "" {
+			if b.hi, err = parse(partition.To); err != nil {
+				return nil, err
+			}
+			b.hasHi = true
+		}
+		if b.hasLo && b.hasHi {
+			widths += b.hi - b.lo
+			count++
+		}
+		parsed = append(parsed, b)
+	}
+	if count > 0 {
+		width = widths / count
+	}
+
+	targets := []partitionTarget{}
+	for _, b := range parsed {
+		switch {
+		case !b.hasLo && !b.hasHi && isTime:
+			b.lo, b.hi = float64(time.Now().Unix())-width, float64(time.Now().Unix())
+		case !b.hasLo && !b.hasHi:
+			b.lo, b.hi = 0, width
+		case !b.hasLo:
+			b.lo = b.hi - width
+		case !b.hasHi:
+			b.hi = b.lo + width
+		}
+		gen := format(b.lo, b.hi)
+		if gen == nil {
+			continue
+		}
+		targets = append(targets, partitionTarget{name: b.name, weight: 1, order: b.lo, gen: gen})
+	}
+	slices.SortStableFunc(targets, func(a, b partitionTarget) int {
+		switch {
+		case a.order < b.order:
+			return -1
+		case a.order > b.order:
+			return 1
+		}
+		return 0
+	})
+	return targets, nil
+}
+
+// partitionBoundSeconds converts a bound to unix seconds, the bound being the result of the MySQL function when there is one
+func partitionBoundSeconds(function, bound string) (float64, error) {
+	if function == "" {
+		t, err := parseCheckTime(bound)
+		return float64(t.Unix()), err
+	}
+	n, err := strconv.ParseInt(bound, 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("%s(...) bound %s is not an integer", function, bound)
+	}
+	switch function {
+	case "year":
+		return float64(time.Date(int(n), time.January, 1, 0, 0, 0, 0, time.UTC).Unix()), nil
+	case "to_days":
+		return float64((n - mysqlEpochDays) * oneDay), nil
+	case "to_seconds":
+		return float64(n - mysqlEpochDays*oneDay), nil
+	}
+	// unix_timestamp
+	return float64(n), nil
+}
+
+func ceilDiv(a, b int64) int64 {
+	return int64(math.Ceil(float64(a) / float64(b)))
+}
+
+func partitionBounds(method string, partition db.Partition) string {
+	switch {
+	case partition.Default:
+		return "DEFAULT"
+	case method == db.PartitionList:
+		values := slices.Clone(partition.Values)
+		if partition.Null {
+			values = append(values, NULL)
+		}
+		return "IN (" + strings.Join(values, ", ") + ")"
+	case method == db.PartitionRange:
+		from, to := partition.From, partition.To
+		if from == "" {
+			from = "MINVALUE"
+		}
+		if to == "" {
+			to = "MAXVALUE"
+		}
+		return fmt.Sprintf("[%s, %s)", from, to)
+	}
+	return ""
+}
+
+// next returns a partition key value, picking the partition according to the distribution
+func (key *partitionKey) next() Getter {
+	r := rand.Float64() * key.total
+	for _, target := range key.targets {
+		if r < target.weight {
+			return target.gen()
+		}
+		r -= target.weight
+	}
+	return key.targets[len(key.targets)-1].gen()
+}
+
+func (in *Insert) isPartitionKey(field db.Field) bool {
+	return in.partition != nil && strings.EqualFold(field.ColumnName, in.partition.column)
+}
+
+// PartitionStatus reports how rows are spread over the partitions, nil when the table is not partitioned
+func (in *Insert) PartitionStatus() *PartitionStatus {
+	return in.partitionStatus
+}
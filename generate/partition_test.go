@@ -0,0 +1,211 @@
+package generate
+
+import (
+	"database/sql"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+func TestPartitionKey(t *testing.T) {
+	rangePartitions := &db.Partitioning{Method: db.PartitionRange, Expression: "n", Column: "n", Partitions: []db.Partition{
+		{Name: "p_old", To: "100"},
+		{Name: "p_100", From: "100", To: "110"},
+		{Name: "p_110", From: "110"},
+	}}
+	listPartitions := &db.Partitioning{Method: db.PartitionList, Expression: "country", Column: "country", Partitions: []db.Partition{
+		{Name: "p_eu", Values: []string{"fr", "de"}},
+		{Name: "p_us", Values: []string{"us"}},
+	}}
+	datePartitions := &db.Partitioning{Method: db.PartitionRange, Expression: "created", Column: "created", Partitions: []db.Partition{
+		{Name: "p2020", From: "2020-01-01", To: "2021-01-01"},
+		{Name: "p2021", From: "2021-01-01", To: "2022-01-01"},
+	}}
+	rangeOf := func(value string) string {
+		n, _ := strconv.Atoi(value)
+		switch {
+		case n < 100:
+			return "p_old"
+		case n < 110:
+			return "p_100"
+		}
+		return "p_110"
+	}
+
+	tests := []struct {
+		name         string
+		partitioning *db.Partitioning
+		distribution string
+		partitionOf  func(value string) string // empty when the value fits no partition
+		minShare     map[string]float64
+	}{
+		{name: "range", partitioning: rangePartitions, distribution: PartitionEven,
+			partitionOf: rangeOf,
+			minShare:    map[string]float64{"p_old": 0.2, "p_100": 0.2, "p_110": 0.2},
+		},
+		{name: "range skewed to recent partitions", partitioning: rangePartitions, distribution: PartitionRecent,
+			partitionOf: rangeOf,
+			minShare:    map[string]float64{"p_110": 0.5},
+		},
+		{name: "list", partitioning: listPartitions, distribution: PartitionEven,
+			partitionOf: func(value string) string {
+				switch value {
+				case "fr", "de":
+					return "p_eu"
+				case "us":
+					return "p_us"
+				}
+				return ""
+			},
+			minShare: map[string]float64{"p_eu": 0.3, "p_us": 0.3},
+		},
+		{name: "range on dates", partitioning: datePartitions, distribution: PartitionEven,
+			partitionOf: func(value string) string {
+				if value < "2020-01-01" || value >= "2022-01-01" {
+					return ""
+				}
+				return "p" + value[:4]
+			},
+			minShare: map[string]float64{"p2020": 0.3, "p2021": 0.3},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			PartitionDistribution = test.distribution
+			t.Cleanup(func() { PartitionDistribution = PartitionEven })
+			table := &db.Table{Name: "t1", Partitioning: test.partitioning, Fields: []db.Field{
+				{ColumnName: "n", DataType: "int"},
+				{ColumnName: "country", DataType: "varchar", CharacterMaximumLength: sql.NullInt64{Int64: 2, Valid: true}},
+				{ColumnName: "created", DataType: "date"},
+			}}
+			in := New(table, ForeignKeyLinks{}, 1, 20, 4, nil)
+			if in.partition == nil {
+				t.Fatalf("expected a partition key, got %+v", in.PartitionStatus())
+			}
+
+			counts := map[string]int{}
+			rows := 300
+			for range rows {
+				value := in.partition.next().String()
+				partition := test.partitionOf(value)
+				if partition == "" {
+					t.Fatalf("%s fits no partition", value)
+				}
+				counts[partition]++
+			}
+			for partition, share := range test.minShare {
+				if float64(counts[partition]) < share*float64(rows) {
+					t.Errorf("expected at least %.0f%% of the rows in %s, got %d/%d", share*100, partition, counts[partition], rows)
+				}
+			}
+		})
+	}
+}
+
+func TestPartitionBoundSeconds(t *testing.T) {
+	jan2020 := float64(1577836800)
+	tests := []struct {
+		function string
+		bound    string
+		seconds  float64
+		err      bool
+	}{
+		{bound: "2020-01-01", seconds: jan2020},
+		{bound: "2020-01-01 00:00:10", seconds: jan2020 + 10},
+		{function: "year", bound: "2020", seconds: jan2020},
+		{function: "to_days", bound: "737790", seconds: jan2020},
+		{function: "to_seconds", bound: "63745056000", seconds: jan2020},
+		{function: "unix_timestamp", bound: "1577836800", seconds: jan2020},
+		{bound: "yesterday", err: true},
+		{bound: "2020-13-01", err: true},
+		{function: "year", bound: "'2020'", err: true},
+		{function: "to_days", bound: "737790.5", err: true},
+	}
+	for _, test := range tests {
+		seconds, err := partitionBoundSeconds(test.function, test.bound)
+		if test.err != (err != nil) {
+			t.Errorf("%s(%s): expected error: %v, got %v", test.function, test.bound, test.err, err)
+			continue
+		}
+		if err == nil && seconds != test.seconds {
+			t.Errorf("%s(%s): expected %v, got %v", test.function, test.bound, test.seconds, seconds)
+		}
+	}
+}
+
+func TestRangePartitionTargets(t *testing.T) {
+	tests := []struct {
+		name       string
+		field      db.Field
+		function   string
+		partitions []db.Partition
+		targets    []string // in the order of their lower bounds
+		err        bool
+	}{
+		{name: "unbounded partitions get the average width",
+			field:      db.Field{DataType: "int"},
+			partitions: []db.Partition{{Name: "p_max", From: "20"}, {Name: "p_min", To: "10"}, {Name: "p_10", From: "10", To: "20"}},
+			targets:    []string{"p_min", "p_10", "p_max"},
+		},
+		{name: "default and empty partitions are not targeted",
+			field:      db.Field{DataType: "int"},
+			partitions: []db.Partition{{Name: "p_empty", From: "5", To: "5"}, {Name: "p_5", From: "5", To: "6"}, {Name: "p_default", Default: true}},
+			targets:    []string{"p_5"},
+		},
+		{name: "decimals below the scale",
+			field:      db.Field{DataType: "decimal", NumericPrecision: sql.NullInt64{Int64: 5, Valid: true}, NumericScale: sql.NullInt64{Int64: 0, Valid: true}},
+			partitions: []db.Partition{{Name: "p_empty", From: "0.2", To: "0.8"}, {Name: "p_1", From: "0.8", To: "1.5"}},
+			targets:    []string{"p_1"},
+		},
+		{name: "MySQL functions on dates",
+			field:      db.Field{DataType: "date"},
+			function:   "to_days",
+			partitions: []db.Partition{{Name: "p2021", From: "738156", To: "738521"}, {Name: "p2020", To: "738156"}},
+			targets:    []string{"p2020", "p2021"},
+		},
+		{name: "function on a number", field: db.Field{DataType: "int"}, function: "year", partitions: []db.Partition{{Name: "p", To: "10"}}, err: true},
+		{name: "unsupported type", field: db.Field{DataType: "varchar"}, partitions: []db.Partition{{Name: "p", To: "m"}}, err: true},
+		{name: "bound is not a number", field: db.Field{DataType: "int"}, partitions: []db.Partition{{Name: "p", To: "ten"}}, err: true},
+		{name: "bound is not a date", field: db.Field{DataType: "date"}, partitions: []db.Partition{{Name: "p", From: "2020-01-01", To: "soon"}}, err: true},
+	}
+	for _, test := range tests {
+		targets, err := rangePartitionTargets(test.field, test.function, test.partitions)
+		if test.err != (err != nil) {
+			t.Errorf("%s: expected error: %v, got %v", test.name, test.err, err)
+			continue
+		}
+		names := []string{}
+		for _, target := range targets {
+			names = append(names, target.name)
+		}
+		if err == nil && !slices.Equal(names, test.targets) {
+			t.Errorf("%s: expected targets %v, got %v", test.name, test.targets, names)
+		}
+	}
+}
+
+func TestPartitionKeyUnsupported(t *testing.T) {
+	tests := []struct {
+		name         string
+		partitioning *db.Partitioning
+	}{
+		{name: "hash", partitioning: &db.Partitioning{Method: db.PartitionHash, Column: "n"}},
+		{name: "expression", partitioning: &db.Partitioning{Method: db.PartitionRange, Expression: "n % 10", Partitions: []db.Partition{{Name: "p", To: "5"}}}},
+		{name: "unknown column", partitioning: &db.Partitioning{Method: db.PartitionRange, Column: "nope", Partitions: []db.Partition{{Name: "p", To: "5"}}}},
+		{name: "unique column", partitioning: &db.Partitioning{Method: db.PartitionList, Column: "code", Partitions: []db.Partition{{Name: "p", Values: []string{"a", "b"}}}}},
+		{name: "no target", partitioning: &db.Partitioning{Method: db.PartitionList, Column: "n", Partitions: []db.Partition{{Name: "p", Null: true}}}},
+	}
+	for _, test := range tests {
+		// unique indexes are planned once per table name
+		table := &db.Table{Name: "unsupported " + test.name, Partitioning: test.partitioning, Fields: []db.Field{
+			{ColumnName: "code", DataType: "varchar", CharacterMaximumLength: sql.NullInt64{Int64: 10, Valid: true}},
+			{ColumnName: "n", DataType: "int"},
+		}, Indexes: map[string]db.Index{"code": {Name: "code", Columns: []string{"code"}}}}
+		in := New(table, ForeignKeyLinks{}, 1, 20, 4, nil)
+		if status := in.PartitionStatus(); in.partition != nil || status.Supported || status.Reason == "" {
+			t.Errorf("%s: expected the partitions not to be supported, got %+v", test.name, status)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package generate
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+// RandomRange generates a non-empty PostgreSQL range, including its lower bound and excluding its upper one
+type RandomRange struct {
+	value string
+}
+
+func (r *RandomRange) String() string {
+	return r.value
+}
+
+func (r *RandomRange) IsQuotable() bool {
+	return true
+}
+
+// rangeKind tells how the bounds of a range type are generated and written
+type rangeKind struct {
+	time   bool
+	layout string  // of dates and timestamps, bounds are seconds since the epoch
+	width  int64   // maximum width, in units or in seconds
+	scale  float64 // numrange bounds have cents
+}
+
+var rangeKinds = map[string]rangeKind{
+	"int4range": {width: 100},
+	"int8range": {width: 100},
+	"numrange":  {width: 100, scale: 100},
+	"daterange": {time: true, layout: "2006-01-02", width: 30 * 24 * 3600},
+	"tsrange":   {time: true, layout: "2006-01-02 15:04:05", width: 72 * 3600},
+	"tstzrange": {time: true, layout: "2006-01-02 15:04:05Z07:00", width: 72 * 3600},
+}
+
+func (k rangeKind) format(bound int64) string {
+	switch {
+	case k.time:
+		return time.Unix(bound, 0).UTC().Format(k.layout)
+	case k.scale > 0:
+		return fmt.Sprintf("%.2f", float64(bound)/k.scale)
+	}
+	return fmt.Sprint(bound)
+}
+
+// step is a random width, whole days for dates
+func (k rangeKind) step() int64 {
+	if k.layout == "2006-01-02" {
+		return (1 + rand.Int63n(k.width/(24*3600))) * 24 * 3600
+	}
+	return 1 + rand.Int63n(k.width)
+}
+
+func (k rangeKind) scaled(width int64) int64 {
+	if k.scale > 0 {
+		return width * int64(k.scale)
+	}
+	return width
+}
+
+func NewRandomRange(dataType string) *RandomRange {
+	k := rangeKinds[dataType]
+	lower := rand.Int63n(1000000)
+	if k.time {
+		lower = time.Now().Unix() - rand.Int63n(oneYear)
+		if k.layout == "2006-01-02" {
+			lower -= lower % (24 * 3600)
+		}
+	}
+	return &RandomRange{fmt.Sprintf("[%s,%s)", k.format(lower), k.format(lower+k.scaled(k.step())))}
+}
+
+// rangeCursor hands out consecutive ranges, so that an exclusion constraint using && never rejects them
+type rangeCursor struct {
+	mutex sync.Mutex
+	kind  rangeKind
+	next  int64
+}
+
+func (c *rangeCursor) Range() *RandomRange {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	lower := c.next
+	c.next += c.kind.scaled(c.kind.step())
+	return &RandomRange{fmt.Sprintf("[%s,%s)", c.kind.format(lower), c.kind.format(c.next))}
+}
+
+// startRangeCursors starts the ranges of the columns under an exclusion constraint after the ones already in the table.
+// It reads the table, so it runs with the inserts rather than in New, which the plan calls too
+func (in *Insert) startRangeCursors() error {
+	if in.rangeCursors != nil {
+		return nil
+	}
+	cursors := map[string]*rangeCursor{}
+	for _, field := range in.table.Fields {
+		k, ok := rangeKinds[field.DataType]
+		if !ok || !field.NoOverlap || field.Skip || field.Generated {
+			continue
+		}
+		c := &rangeCursor{kind: k}
+		if k.time {
+			c.next = time.Now().Unix()
+		}
+		upper, found, err := db.MaxRangeUpper(in.table, field.ColumnName, k.time)
+		if err != nil {
+			return errors.Wrapf(err, "cannot read the highest upper bound of %s.%s", in.table.Name, field.ColumnName)
+		}
+		if found {
+			c.next = int64(math.Ceil(upper * max(k.scale, 1)))
+			if k.layout == "2006-01-02" {
+				c.next += 24*3600 - 1
+				c.next -= c.next % (24 * 3600)
+			}
+		}
+		cursors[strings.ToLower(field.ColumnName)] = c
+	}
+	in.rangeCursors = cursors
+	return nil
+}
@@ -0,0 +1,30 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+func TestRangeCursor(t *testing.T) {
+	c := &rangeCursor{kind: rangeKinds["int4range"], next: 10}
+	previous := "[10,"
+	for range 20 {
+		r := c.Range().String()
+		if r[:len(previous)] != previous {
+			t.Fatalf("expected %s to start where the previous range ended, %s", r, previous)
+		}
+		previous = "[" + r[len(previous):len(r)-1] + ","
+	}
+}
+
+// the highest upper bound is read when inserting, not when planning
+func TestNewDoesNotReadRanges(t *testing.T) {
+	table := &db.Table{Name: "bookings", Fields: []db.Field{
+		{ColumnName: "during", DataType: "tsrange", NoOverlap: true},
+	}}
+	in := New(table, ForeignKeyLinks{}, 1, 20, 4, nil)
+	if in.rangeCursors != nil {
+		t.Fatalf("expected no range cursor before the inserts, got %v", in.rangeCursors)
+	}
+}
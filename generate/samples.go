@@ -38,7 +38,7 @@ func (s *sampleCommon) query(query string, values [][]Getter) error {
 		scannedValuesInterface := make([]interface{}, len(s.fields))
 		scannedGetter := make([]ScannerGetter, len(s.fields))
 		for fieldIdx, field := range s.fields {
-			getter := s.getterFromField(field)
+			getter := getterFromField(field)
 			scannedGetter[fieldIdx] = getter
 			scannedValuesInterface[fieldIdx] = getter
 		}
@@ -73,14 +73,23 @@ func (s *sampleCommon) query(query string, values [][]Getter) error {
 	return nil
 }
 
-func (s *sampleCommon) getterFromField(f db.Field) ScannerGetter {
+// getterFromField returns a getter scanning a value of the field, nil when the datatype is not handled
+func getterFromField(f db.Field) ScannerGetter {
 
 	switch f.DataType {
 	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year":
 		return NewScannedInt()
 	case "char", "varchar", "blob", "text", "mediumtext",
-		"mediumblob", "longblob", "longtext":
+		"mediumblob", "longblob", "longtext", "uuid", "inet4", "inet6", "json",
+		"inet", "cidr", "macaddr", "interval", "money", "bitstring", "varbit", "citext", "xml", "tsvector",
+		"int4range", "int8range", "numrange", "daterange", "tsrange", "tstzrange":
 		return NewScannedString()
+	case "bytea":
+		return NewScannedBytea()
+	case "enum", "set":
+		return NewScannedString()
+	case "bit":
+		return NewScannedBit()
 	case "binary", "varbinary":
 		return NewScannedBinary()
 	case "float", "decimal", "double":
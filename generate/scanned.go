@@ -1,7 +1,9 @@
 package generate
 
 import (
+	"encoding/hex"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/ylacancellera/random-data-load/db"
@@ -88,6 +90,65 @@ func NewScannedBinary() *ScannedBinary {
 	return &ScannedBinary{}
 }
 
+// ScannedBytea holds the bytes decoded by the driver, written back as a hex literal
+type ScannedBytea struct {
+	value []byte
+}
+
+func (s *ScannedBytea) String() string {
+	return `\x` + hex.EncodeToString(s.value)
+}
+
+func (s *ScannedBytea) IsQuotable() bool {
+	return true
+}
+
+func (s *ScannedBytea) Scan(src any) (err error) {
+	switch x := src.(type) {
+	case []byte:
+		s.value = slices.Clone(x)
+	default:
+		err = fmt.Errorf("unsupported scan type %T", src)
+	}
+	return
+}
+
+func NewScannedBytea() *ScannedBytea {
+	return &ScannedBytea{}
+}
+
+// ScannedBit reads the bytes of a MySQL bit column, written back as an integer
+type ScannedBit struct {
+	value uint64
+}
+
+func (s *ScannedBit) String() string {
+	return fmt.Sprintf("%d", s.value)
+}
+
+func (s *ScannedBit) IsQuotable() bool {
+	return false
+}
+
+func (s *ScannedBit) Scan(src any) (err error) {
+	switch x := src.(type) {
+	case []byte:
+		s.value = 0
+		for _, b := range x {
+			s.value = s.value<<8 | uint64(b)
+		}
+	case int64:
+		s.value = uint64(x)
+	default:
+		err = fmt.Errorf("unsupported scan type %T", src)
+	}
+	return
+}
+
+func NewScannedBit() *ScannedBit {
+	return &ScannedBit{}
+}
+
 type ScannedDecimal struct {
 	value float64
 }
@@ -104,6 +165,9 @@ func (s *ScannedDecimal) Scan(src any) (err error) {
 	switch x := src.(type) {
 	case float64:
 		s.value = x
+	case int64:
+		// sqlite returns integral values of numeric columns as integers
+		s.value = float64(x)
 	default:
 		err = fmt.Errorf("unsupported scan type %T", src)
 	}
@@ -0,0 +1,27 @@
+package generate
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// RandomTsvector generates a few words, PostgreSQL turns them into lexemes
+type RandomTsvector struct {
+	value string
+}
+
+func (r *RandomTsvector) String() string {
+	return r.value
+}
+
+func (r *RandomTsvector) IsQuotable() bool {
+	return true
+}
+
+func NewRandomTsvector() *RandomTsvector {
+	words := make([]string, 1+rand.Intn(8))
+	for i := range words {
+		words[i] = plainWord()
+	}
+	return &RandomTsvector{strings.Join(words, " ")}
+}
@@ -0,0 +1,474 @@
+package generate
+
+import (
+	"math"
+	"math/bits"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ylacancellera/random-data-load/db"
+)
+
+// unique indexes are honoured by generating values from a known domain instead of hoping random values do not collide
+// a group covers the generated columns of one unique index, it is shared by every worker and pass on the table
+
+const (
+	uniqueSequence     = "sequence"
+	uniqueShuffled     = "shuffled range"
+	uniqueDedup        = "dedup set"
+	uniqueCombinations = "combinations"
+
+	// realistic values are tried this many times before falling back to the domain
+	uniqueDedupTries = 10
+)
+
+var (
+	uniqueIntMax = map[string]int64{
+		"tinyint":   math.MaxInt8,
+		"smallint":  math.MaxInt16,
+		"mediumint": 1<<23 - 1,
+		"int":       math.MaxInt32,
+		"integer":   math.MaxInt32,
+		"bigint":    math.MaxInt64,
+	}
+	// base36 keeps strings printable, 12 characters already make 36^12 values
+	uniqueAlphabet       = "0123456789abcdefghijklmnopqrstuvwxyz"
+	uniqueMaxStringWidth = 12
+)
+
+// uniqueDomain enumerates every distinct value a column can hold
+type uniqueDomain interface {
+	size() uint64
+	at(uint64) Getter
+}
+
+type intDomain struct {
+	start int64
+	count uint64
+}
+
+func (d intDomain) size() uint64 { return d.count }
+func (d intDomain) at(i uint64) Getter {
+	return &RandomInt{d.start + int64(i)}
+}
+
+type stringDomain struct {
+	width int
+	count uint64
+}
+
+func (d stringDomain) size() uint64 { return d.count }
+func (d stringDomain) at(i uint64) Getter {
+	b := make([]byte, d.width)
+	for pos := d.width - 1; pos >= 0; pos-- {
+		b[pos] = uniqueAlphabet[i%uint64(len(uniqueAlphabet))]
+		i /= uint64(len(uniqueAlphabet))
+	}
+	return &Literal{string(b), true}
+}
+
+type listDomain []string
+
+func (d listDomain) size() uint64 { return uint64(len(d)) }
+func (d listDomain) at(i uint64) Getter {
+	return &Literal{d[i], true}
+}
+
+// timeDomain goes back in time from base, one unit at a time
+type timeDomain struct {
+	base   time.Time
+	unit   time.Duration
+	count  uint64
+	layout string
+}
+
+func (d timeDomain) size() uint64 { return d.count }
+func (d timeDomain) at(i uint64) Getter {
+	return &Literal{d.base.Add(-time.Duration(i) * d.unit).Format(d.layout), true}
+}
+
+// permutation shuffles [0, n) with an affine function: a and n are coprime, so every value is reached once
+type permutation struct {
+	a, b, n uint64
+}
+
+func newPermutation(n uint64, shuffle bool) permutation {
+	if !shuffle || n < 2 {
+		return permutation{a: 1, n: n}
+	}
+	p := permutation{n: n, b: rand.Uint64() % n}
+	for {
+		p.a = 1 + rand.Uint64()%(n-1)
+		if gcd(p.a, n) == 1 {
+			return p
+		}
+	}
+}
+
+func (p permutation) at(i uint64) uint64 {
+	hi, lo := bits.Mul64(p.a, i%p.n)
+	r := bits.Rem64(hi, lo, p.n)
+	r, carry := bits.Add64(r, p.b, 0)
+	if carry != 0 || r >= p.n {
+		r -= p.n
+	}
+	return r
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+type uniqueGroup struct {
+	index    string
+	columns  []string
+	strategy string
+
+	fields     []db.Field
+	domains    []uniqueDomain // nil for columns without a known domain, like json
+	enumerable bool           // every column has a domain
+	partial    bool           // some columns of the index are not generated
+	size       uint64         // number of distinct tuples, capped at math.MaxUint64
+	random     func(db.Field) Getter
+
+	mutex   sync.Mutex
+	perm    permutation
+	next    uint64
+	seen    map[string]struct{}
+	wrapped bool
+}
+
+var storedUniqueGroups = map[string][]*uniqueGroup{}
+var storedUniqueGroupsMutex = sync.Mutex{}
+
+// PrepareUniqueness plans how the unique indexes of the table will be honoured for this run.
+// It fails when an index cannot hold the requested number of rows
+func PrepareUniqueness(table *db.Table, rows int64, maxTextSize int64) error {
+	groups, err := newUniqueGroups(table, rows, maxTextSize, nil)
+	if err != nil {
+		return err
+	}
+	storedUniqueGroupsMutex.Lock()
+	defer storedUniqueGroupsMutex.Unlock()
+	storedUniqueGroups[table.FullName()] = groups
+	return nil
+}
+
+// uniqueGroupsFor returns the groups planned by PrepareUniqueness, shared between passes of the same table
+func (in *Insert) uniqueGroupsFor() []*uniqueGroup {
+	storedUniqueGroupsMutex.Lock()
+	defer storedUniqueGroupsMutex.Unlock()
+	groups, ok := storedUniqueGroups[in.table.FullName()]
+	if !ok {
+		var err error
+		groups, err = newUniqueGroups(in.table, 0, in.maxTextSize, in.randomGetter)
+		if err != nil {
+			log.Error().Err(err).Str("table", in.table.Name).Msg("cannot plan unique indexes, duplicates will be retried")
+		}
+		storedUniqueGroups[in.table.FullName()] = groups
+	}
+	for _, g := range groups {
+		if g.random == nil {
+			g.random = in.randomGetter
+		}
+	}
+	return groups
+}
+
+func newUniqueGroups(table *db.Table, rows int64, maxTextSize int64, random func(db.Field) Getter) ([]*uniqueGroup, error) {
+	generated := map[string]db.Field{}
+	for _, field := range table.FieldsToGenerate() {
+		generated[strings.ToLower(field.ColumnName)] = field
+	}
+	autoFilled := map[string]bool{}
+	for _, field := range table.Fields {
+		if field.AutoIncrement && !table.ExplicitKeys {
+			autoFilled[strings.ToLower(field.ColumnName)] = true
+		}
+	}
+
+	groups := []*uniqueGroup{}
+	// an index containing every column of a planned group is already unique
+	satisfied := func(index db.Index) bool {
+		for _, g := range groups {
+			if !slices.ContainsFunc(g.columns, func(column string) bool {
+				return !slices.ContainsFunc(index.Columns, func(c string) bool { return strings.EqualFold(c, column) })
+			}) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// smaller groups first, they satisfy the indexes containing them
+	indexes := table.UniqueIndexes()
+	countGenerated := func(index db.Index) int {
+		n := 0
+		for _, column := range index.Columns {
+			if _, ok := generated[strings.ToLower(column)]; ok {
+				n++
+			}
+		}
+		return n
+	}
+	slices.SortStableFunc(indexes, func(a, b db.Index) int { return countGenerated(a) - countGenerated(b) })
+
+INDEXES:
+	for _, index := range indexes {
+		if satisfied(index) {
+			continue
+		}
+		g := &uniqueGroup{index: index.Name, random: random}
+		for _, column := range index.Columns {
+			key := strings.ToLower(column)
+			if autoFilled[key] {
+				continue INDEXES
+			}
+			field, ok := generated[key]
+			if !ok {
+				// sampled or defaulted columns can make rows distinct on their own
+				g.partial = true
+				continue
+			}
+			if field.DataType == "uuid" {
+				continue INDEXES
+			}
+			g.columns = append(g.columns, field.ColumnName)
+			g.fields = append(g.fields, field)
+		}
+		if len(g.fields) == 0 {
+			log.Debug().Str("table", table.Name).Str("index", index.Name).Msg("no generated column in unique index, duplicates will be retried")
+			continue
+		}
+
+		if err := g.plan(table, index, rows, maxTextSize); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+		log.Debug().Str("table", table.Name).Str("index", index.Name).Strs("columns", g.columns).Str("strategy", g.strategy).Uint64("domain", g.size).Msg("unique index planned")
+	}
+	return groups, nil
+}
+
+func (g *uniqueGroup) plan(table *db.Table, index db.Index, rows int64, maxTextSize int64) error {
+	g.size = 1
+	knownDomains := true
+	for _, field := range g.fields {
+		d, err := newUniqueDomain(table, field, maxTextSize)
+		if err != nil {
+			return err
+		}
+		g.domains = append(g.domains, d)
+		if d == nil {
+			knownDomains = false
+			continue
+		}
+		hi, lo := bits.Mul64(g.size, d.size())
+		if hi != 0 {
+			lo = math.MaxUint64
+		}
+		g.size = lo
+	}
+
+	if knownDomains && !g.partial && uint64(rows) > g.size {
+		more := ""
+		if d, ok := g.domains[0].(intDomain); ok && len(g.domains) == 1 && d.start > 1 {
+			more = " more"
+		}
+		return errors.Errorf("cannot insert %d rows into %s: unique index %s on (%s) only allows %d%s distinct values",
+			rows, table.Name, index.Name, strings.Join(g.columns, ", "), g.size, more)
+	}
+
+	switch {
+	case len(g.fields) > 1 && knownDomains:
+		g.strategy = uniqueCombinations
+	case !knownDomains:
+		g.strategy = uniqueDedup
+	case isUniqueIntField(g.fields[0]) && (index.Primary || g.fields[0].ColumnKey == "PRI"):
+		g.strategy = uniqueSequence
+	case isUniqueStringField(g.fields[0]) && uint64(rows)*2 < g.size:
+		// realistic values while collisions are unlikely
+		g.strategy = uniqueDedup
+	default:
+		g.strategy = uniqueShuffled
+	}
+
+	g.enumerable = knownDomains && g.size > 0
+	if g.enumerable {
+		g.perm = newPermutation(g.size, g.strategy != uniqueSequence)
+	}
+	if g.strategy == uniqueDedup {
+		g.seen = map[string]struct{}{}
+	}
+	return nil
+}
+
+func isUniqueIntField(field db.Field) bool {
+	switch field.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		return true
+	}
+	return false
+}
+
+func isUniqueStringField(field db.Field) bool {
+	switch field.DataType {
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext", "citext":
+		return true
+	}
+	return false
+}
+
+// newUniqueDomain returns nil when the values of the column cannot be enumerated
+func newUniqueDomain(table *db.Table, field db.Field, maxTextSize int64) (uniqueDomain, error) {
+	switch field.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		// values already in the table are not generated again
+		highest, err := db.MaxInt(table, field.ColumnName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot get the highest value of %s.%s", table.Name, field.ColumnName)
+		}
+		highestValue := uniqueIntMax[field.DataType]
+		if field.FullIntRange {
+			_, highestValue = intRange(field)
+		}
+		start := max(highest+1, 1)
+		if start > highestValue {
+			return intDomain{start, 0}, nil
+		}
+		return intDomain{start, uint64(highestValue-start) + 1}, nil
+	case "float", "decimal", "double", "numeric":
+		count := uint64(maxValues["decimal"])
+		if digits := field.NumericPrecision.Int64 - field.NumericScale.Int64; field.NumericPrecision.Int64 > 0 && digits < 10 {
+			count = uint64(math.Pow10(int(digits))) - 1
+		}
+		return intDomain{1, count}, nil
+	case "year":
+		return intDomain{1901, 255}, nil
+	case "bit":
+		return intDomain{0, uint64(1) << min(max(field.NumericPrecision.Int64, 1), 63)}, nil
+	case "bool", "boolean":
+		return listDomain{"true", "false"}, nil
+	case "enum", "set":
+		return listDomain(field.SetEnumVals), nil
+	case "date":
+		today := time.Now().Truncate(24 * time.Hour)
+		return timeDomain{today, 24 * time.Hour, 365 * 200, "2006-01-02"}, nil
+	case "datetime", "timestamp":
+		// timestamps start in 1970 on mysql, 30 years is safe
+		now := time.Now().Truncate(time.Second)
+		return timeDomain{now, time.Second, uint64(30 * oneYear), "2006-01-02 15:04:05"}, nil
+	case "time":
+		midnight := time.Date(2000, 1, 1, 23, 59, 59, 0, time.UTC)
+		return timeDomain{midnight, time.Second, 24 * 60 * 60, "15:04:05"}, nil
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext", "citext":
+		width := int64(uniqueMaxStringWidth)
+		if field.CharacterMaximumLength.Valid && field.CharacterMaximumLength.Int64 > 0 {
+			width = min(width, field.CharacterMaximumLength.Int64)
+		}
+		if maxTextSize > 0 {
+			width = min(width, maxTextSize)
+		}
+		count := uint64(1)
+		for i := int64(0); i < width; i++ {
+			count *= uint64(len(uniqueAlphabet))
+		}
+		return stringDomain{int(width), count}, nil
+	}
+	return nil, nil
+}
+
+// nextTuple returns a tuple of values never returned before, one per column of the group
+func (g *uniqueGroup) nextTuple() []Getter {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.strategy != uniqueDedup && g.enumerable {
+		return g.enumerate()
+	}
+
+	for try := 0; try < uniqueDedupTries; try++ {
+		tuple := g.randomTuple()
+		if g.remember(tuple) {
+			return tuple
+		}
+	}
+	// columns without a known domain have nothing to fall back to, the database will reject duplicates
+	if !g.enumerable {
+		log.Warn().Str("index", g.index).Msg("could not generate a distinct value")
+		return g.randomTuple()
+	}
+	for {
+		tuple := g.enumerate()
+		if g.remember(tuple) || g.wrapped {
+			return tuple
+		}
+	}
+}
+
+// enumerate decodes the next shuffled position in the mixed radix of the column domains
+func (g *uniqueGroup) enumerate() []Getter {
+	if g.next >= g.size && !g.wrapped {
+		g.wrapped = true
+		// when other columns are sampled, rows can still be distinct
+		if !g.partial {
+			log.Warn().Str("index", g.index).Uint64("values", g.size).Msg("every distinct value of the unique index has been generated, duplicates will be rejected")
+		}
+	}
+	pos := g.perm.at(g.next)
+	g.next++
+
+	tuple := make([]Getter, len(g.domains))
+	for i, d := range g.domains {
+		tuple[i] = d.at(pos % d.size())
+		pos /= d.size()
+	}
+	return tuple
+}
+
+func (g *uniqueGroup) randomTuple() []Getter {
+	tuple := make([]Getter, len(g.fields))
+	for i, field := range g.fields {
+		tuple[i] = g.random(field)
+	}
+	return tuple
+}
+
+// remember reports whether the tuple is new. Case is ignored, as most collations do
+func (g *uniqueGroup) remember(tuple []Getter) bool {
+	parts := make([]string, len(tuple))
+	for i, v := range tuple {
+		parts[i] = strings.ToLower(v.String())
+	}
+	key := strings.Join(parts, "\x00")
+	if _, ok := g.seen[key]; ok {
+		return false
+	}
+	g.seen[key] = struct{}{}
+	return true
+}
+
+// uniqueColumn locates a generated column in the group making it unique
+type uniqueColumn struct {
+	group *uniqueGroup
+	pos   int
+}
+
+func (in *Insert) uniqueColumns() map[string]uniqueColumn {
+	columns := map[string]uniqueColumn{}
+	for _, g := range in.uniqueGroupsFor() {
+		for pos, column := range g.columns {
+			columns[strings.ToLower(column)] = uniqueColumn{g, pos}
+		}
+	}
+	return columns
+}
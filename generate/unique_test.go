@@ -0,0 +1,206 @@
+package generate
+
+import (
+	"database/sql"
+	"math"
+	"math/big"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ylacancellera/random-data-load/db"
+	"github.com/ylacancellera/random-data-load/db/memdb"
+	"github.com/ylacancellera/random-data-load/query"
+)
+
+func TestPermutation(t *testing.T) {
+	for _, n := range []uint64{1, 2, 3, 7, 10, 36, 64, 97, 1000} {
+		for _, shuffle := range []bool{false, true} {
+			p := newPermutation(n, shuffle)
+			seen := make([]bool, n)
+			for i := range n {
+				v := p.at(i)
+				if v >= n || seen[v] {
+					t.Fatalf("n=%d shuffle=%v: %d is out of range or already seen, permutation %+v", n, shuffle, v, p)
+				}
+				seen[v] = true
+			}
+			if !shuffle && p.at(n-1) != n-1 {
+				t.Errorf("n=%d: expected the identity when not shuffled, got %+v", n, p)
+			}
+		}
+	}
+
+	// a*i overflows 64 bits for large domains
+	for _, n := range []uint64{math.MaxUint64, math.MaxUint64 - 1, 1 << 63, 36 * 36 * 36 * 36 * 36 * 36 * 36 * 36 * 36 * 36 * 36 * 36} {
+		p := newPermutation(n, true)
+		for range 1000 {
+			i := rand.Uint64() % n
+			expected := new(big.Int).Mul(new(big.Int).SetUint64(p.a), new(big.Int).SetUint64(i))
+			expected.Add(expected, new(big.Int).SetUint64(p.b))
+			expected.Mod(expected, new(big.Int).SetUint64(n))
+			if v := p.at(i); v != expected.Uint64() {
+				t.Fatalf("n=%d: permutation %+v of %d gives %d, expected %s", n, p, i, v, expected)
+			}
+		}
+	}
+}
+
+func TestNewUniqueDomain(t *testing.T) {
+	today := time.Now().Truncate(24 * time.Hour)
+	tests := []struct {
+		field       db.Field
+		maxTextSize int64
+		size        uint64
+		first, last string // values at 0 and at size-1
+	}{
+		{field: db.Field{DataType: "varchar", CharacterMaximumLength: sql.NullInt64{Int64: 2, Valid: true}}, size: 36 * 36, first: "00", last: "zz"},
+		{field: db.Field{DataType: "text"}, maxTextSize: 3, size: 36 * 36 * 36, first: "000", last: "zzz"},
+		{field: db.Field{DataType: "enum", SetEnumVals: []string{"a", "b", "c"}}, size: 3, first: "a", last: "c"},
+		{field: db.Field{DataType: "bool"}, size: 2, first: "true", last: "false"},
+		{field: db.Field{DataType: "bit", NumericPrecision: sql.NullInt64{Int64: 3, Valid: true}}, size: 8, first: "0", last: "7"},
+		{field: db.Field{DataType: "decimal", NumericPrecision: sql.NullInt64{Int64: 5, Valid: true}, NumericScale: sql.NullInt64{Int64: 2, Valid: true}}, size: 999, first: "1", last: "999"},
+		{field: db.Field{DataType: "year"}, size: 255, first: "1901", last: "2155"},
+		{field: db.Field{DataType: "date"}, size: 365 * 200, first: today.Format("2006-01-02"), last: today.AddDate(0, 0, -365*200+1).Format("2006-01-02")},
+		{field: db.Field{DataType: "time"}, size: 24 * 60 * 60, first: "23:59:59", last: "00:00:00"},
+	}
+	for _, test := range tests {
+		d, err := newUniqueDomain(&db.Table{Name: "t1"}, test.field, test.maxTextSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.size() != test.size {
+			t.Errorf("%s: expected %d values, got %d", test.field.DataType, test.size, d.size())
+			continue
+		}
+		if first, last := d.at(0).String(), d.at(d.size()-1).String(); first != test.first || last != test.last {
+			t.Errorf("%s: expected values from %s to %s, got %s to %s", test.field.DataType, test.first, test.last, first, last)
+		}
+	}
+
+	for _, dataType := range []string{"json", "uuid", "geometry"} {
+		if d, err := newUniqueDomain(&db.Table{Name: "t1"}, db.Field{DataType: dataType}, 0); d != nil || err != nil {
+			t.Errorf("%s: expected no domain, got %v, %v", dataType, d, err)
+		}
+	}
+}
+
+// integers start after the highest value of the table
+func TestNewUniqueDomainInt(t *testing.T) {
+	d := memdb.New(t.Name())
+	db.Register(db.EngineInfo{Name: "memory", Dialect: query.DialectPostgres, Engine: d})
+	d.CreateTable("", "t1", []db.Field{{ColumnName: "id", DataType: "tinyint"}, {ColumnName: "n", DataType: "int"}})
+	if _, err := db.Connect(db.Config{Engine: "memory", Database: t.Name()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB.Exec(`INSERT INTO "public"."t1" ("id","n") VALUES (100,-5)`); err != nil {
+		t.Fatal(err)
+	}
+	table := &db.Table{Schema: "public", Name: "t1"}
+
+	tests := []struct {
+		field db.Field
+		start int64
+		size  uint64
+	}{
+		{field: db.Field{ColumnName: "id", DataType: "tinyint"}, start: 101, size: 27},
+		{field: db.Field{ColumnName: "id", DataType: "tinyint", Unsigned: true, FullIntRange: true}, start: 101, size: 155},
+		// values below 1 are not generated
+		{field: db.Field{ColumnName: "n", DataType: "int"}, start: 1, size: math.MaxInt32},
+	}
+	for _, test := range tests {
+		domain, err := newUniqueDomain(table, test.field, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if domain.size() != test.size || domain.at(0).String() != strconv.FormatInt(test.start, 10) {
+			t.Errorf("%+v: expected %d values from %d, got %d from %s", test.field, test.size, test.start, domain.size(), domain.at(0))
+		}
+	}
+}
+
+func TestUniqueEnumerate(t *testing.T) {
+	g := &uniqueGroup{
+		index:    "u",
+		strategy: uniqueCombinations,
+		domains:  []uniqueDomain{listDomain{"a", "b", "c"}, intDomain{1, 4}, listDomain{"x"}},
+		size:     12,
+		perm:     newPermutation(12, true),
+	}
+	g.enumerable = true
+
+	seen := map[string]bool{}
+	for i := range 12 {
+		key := tupleKey(g.enumerate())
+		if seen[key] {
+			t.Fatalf("tuple %s repeated after %d tuples", key, i)
+		}
+		seen[key] = true
+	}
+	if g.wrapped {
+		t.Fatal("expected the group not to wrap before its size")
+	}
+	// every tuple was generated, the next ones repeat them
+	if key := tupleKey(g.enumerate()); !seen[key] || !g.wrapped {
+		t.Errorf("expected %s to repeat a tuple once wrapped", key)
+	}
+}
+
+func TestUniqueDedup(t *testing.T) {
+	g := &uniqueGroup{
+		index:      "u",
+		strategy:   uniqueDedup,
+		fields:     []db.Field{{ColumnName: "c", DataType: "varchar"}},
+		domains:    []uniqueDomain{listDomain{"a", "b", "c"}},
+		size:       3,
+		enumerable: true,
+		perm:       newPermutation(3, true),
+		seen:       map[string]struct{}{},
+		// realistic values always collide, the domain is the fallback
+		random: func(db.Field) Getter { return &Literal{"A", true} },
+	}
+
+	values := []string{}
+	for range 3 {
+		values = append(values, tupleKey(g.nextTuple()))
+	}
+	slices.Sort(values)
+	// case is ignored, the realistic A stands for a
+	if !slices.Equal(values, []string{"A", "b", "c"}) {
+		t.Fatalf("expected A, b and c, got %v", values)
+	}
+	if g.wrapped {
+		t.Fatal("expected the group not to wrap before its size")
+	}
+	// nothing new is left, a duplicate is returned instead of looping forever
+	g.nextTuple()
+	if !g.wrapped {
+		t.Error("expected the group to wrap")
+	}
+
+	// without a domain, the random values are returned as is
+	g = &uniqueGroup{
+		index:    "u",
+		strategy: uniqueDedup,
+		fields:   []db.Field{{ColumnName: "doc", DataType: "json"}},
+		domains:  []uniqueDomain{nil},
+		seen:     map[string]struct{}{},
+		random:   func(db.Field) Getter { return &Literal{"{}", true} },
+	}
+	for range 3 {
+		if key := tupleKey(g.nextTuple()); key != "{}" {
+			t.Fatalf("expected the random value, got %s", key)
+		}
+	}
+}
+
+func tupleKey(tuple []Getter) string {
+	parts := []string{}
+	for _, v := range tuple {
+		parts = append(parts, v.String())
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,35 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// RandomXML generates a small well-formed document
+type RandomXML struct {
+	value string
+}
+
+func (r *RandomXML) String() string {
+	return r.value
+}
+
+func (r *RandomXML) IsQuotable() bool {
+	return true
+}
+
+func NewRandomXML() *RandomXML {
+	return &RandomXML{fmt.Sprintf("<item><id>%d</id><name>%s</name></item>", gofakeit.IntRange(1, 1000000), plainWord())}
+}
+
+// plainWord is a word that needs no escaping, neither in XML nor in a quoted literal
+func plainWord() string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>&'"\`, r) {
+			return -1
+		}
+		return r
+	}, gofakeit.Word())
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"runtime/pprof"
+	"strings"
 
 	"net/http"
 	_ "net/http/pprof"
@@ -12,6 +14,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/ylacancellera/random-data-load/cmd"
+	"github.com/ylacancellera/random-data-load/db"
 	"github.com/ylacancellera/random-data-load/frequency"
 	"github.com/ylacancellera/random-data-load/generate"
 	"github.com/ylacancellera/random-data-load/query"
@@ -32,6 +35,7 @@ var buildInfo = fmt.Sprintf("%s\nVersion %s\nBuild: %s using %s\nCommit: %s", to
 
 var cli struct {
 	Run         cmd.RunCmd   `cmd:"run" help:"Starts the insert process"`
+	Plan        cmd.PlanCmd  `cmd:"plan" help:"Shows what the insert process would do: insertion order, foreign keys, skipped fields, generators and row counts. Nothing is inserted"`
 	Query       cmd.QueryCmd `cmd:"query" help:"Providing a query will analyze its schema usage, insert recursively into tables, and identify implicit joins"`
 	Version     kong.VersionFlag
 	Profile     bool   `name:"pprof" help:"generate pprof trace at --cpu-prof-path. Also opens port 6060 for pprof go tool"`
@@ -44,13 +48,15 @@ func main() {
 		kong.Name(toolname),
 		kong.Description("Load random data into a table"),
 		kong.UsageOnError(),
-		kong.ValueMapper(&cli.Run.AddForeignKeys, query.VirtualJoins{}),
-		kong.ValueMapper(&cli.Run.NullFreqMap, &frequency.FrequencyNullParameter{}),
-		kong.ValueMapper(&cli.Run.ValuesFreqMap, &frequency.FrequencyIndexValuesParameter{}),
+		// type mappers rather than value mappers, so that commands embedding RunCmd get them too
+		kong.TypeMapper(reflect.TypeOf(query.VirtualJoins{}), query.VirtualJoins{}),
+		kong.TypeMapper(reflect.TypeOf(frequency.FrequencyNullParameter{}), &frequency.FrequencyNullParameter{}),
+		kong.TypeMapper(reflect.TypeOf(frequency.FrequencyIndexValuesParameter{}), &frequency.FrequencyIndexValuesParameter{}),
 		kong.Vars{
 			"version":        buildInfo,
 			"SequentialFlag": generate.SequentialFlag,
 			"BinomialFlag":   generate.BinomialFlag,
+			"engines":        strings.Join(db.EngineNames(), ","),
 		},
 		kong.ConfigureHelp(kong.HelpOptions{
 			Compact: false,
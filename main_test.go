@@ -6,6 +6,8 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -14,24 +16,67 @@ import (
 
 var toolExecutable = "./random-data-load"
 
-var testsdb map[string]struct {
-	resource *dockertest.Resource
+type testdb struct {
+	resource *dockertest.Resource // nil for sqlite
 	db       *sql.DB
 	port     string
+	connArgs []string // connection flags given to the tool
 }
 
+var testsdb map[string]testdb
+
 func TestMain(m *testing.M) {
 
+	sqlitePath := filepath.Join(os.TempDir(), fmt.Sprintf("random-data-load-test-%d.db", os.Getpid()))
+	sqlitedb, err := sql.Open("sqlite", sqlitePath)
+	if err != nil {
+		log.Panicf("Could not open sqlite database: %s", err)
+	}
+	testsdb = map[string]testdb{
+		"sqlite": {
+			db:       sqlitedb,
+			connArgs: []string{"--database=" + sqlitePath},
+		},
+	}
+
+	// sqlite tests are always run, docker is only needed for pg and mysql
+	resources, err := startDockerDatabases()
+	if err != nil {
+		log.Printf("Docker is not available, only running sqlite tests: %s", err)
+	}
+
+	// run tests
+	code := m.Run()
+
+	if code != 0 && keepDB() {
+		log.Printf("Keeping database running because tests failed and KEEP_DB=1")
+		return
+	}
+	os.Remove(sqlitePath)
+	for _, resource := range resources {
+		if err := resource.pool.Purge(resource.resource); err != nil {
+			log.Panicf("Could not purge resource: %s", err)
+		}
+	}
+}
+
+type dockerResource struct {
+	pool     *dockertest.Pool
+	resource *dockertest.Resource
+}
+
+func startDockerDatabases() ([]dockerResource, error) {
+
 	// uses a sensible default on windows (tcp/http) and linux/osx (socket)
 	// DOCKER_HOST=unix:///run/user/1000/docker.sock go test .
 	pool, err := dockertest.NewPool("")
 	if err != nil {
-		log.Panicf("Could not construct pool: %s", err)
+		return nil, fmt.Errorf("Could not construct pool: %s", err)
 	}
 
 	err = pool.Client.Ping()
 	if err != nil {
-		log.Panicf("Could not connect to Docker: %s", err)
+		return nil, fmt.Errorf("Could not connect to Docker: %s", err)
 	}
 
 	pgresource, err := pool.Run("postgres", "17", []string{"POSTGRES_PASSWORD=dockertest", "POSTGRES_USER=dockertest", "POSTGRES_DB=test"})
@@ -42,14 +87,10 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		log.Panicf("Could not start mysql resource: %s", err)
 	}
-	/*	defer func() {
-			for _, resource := range []*dockertest.Resource{pgresource, mysqlresource} {
-				if err := pool.Purge(resource); err != nil {
-					log.Panicf("Could not purge resource: %s", err)
-				}
-			}
-		}()
-	*/
+	mariadbresource, err := pool.Run("mariadb", "11.4", []string{"MARIADB_ROOT_PASSWORD=dockertest", "MARIADB_PASSWORD=dockertest", "MARIADB_DATABASE=test", "MARIADB_USER=dockertest"})
+	if err != nil {
+		log.Panicf("Could not start mariadb resource: %s", err)
+	}
 
 	var pgdb *sql.DB
 	if err = pool.Retry(func() error {
@@ -73,43 +114,39 @@ func TestMain(m *testing.M) {
 		log.Panicf("Could not connect to mysql docker: %s", err)
 	}
 
-	testsdb = map[string]struct {
-		resource *dockertest.Resource
-		db       *sql.DB
-		port     string
-	}{
-		"pg": struct {
-			resource *dockertest.Resource
-			db       *sql.DB
-			port     string
-		}{
-			resource: pgresource,
-			db:       pgdb,
-			port:     pgresource.GetPort("5432/tcp"),
-		},
-		"mysql": struct {
-			resource *dockertest.Resource
-			db       *sql.DB
-			port     string
-		}{
-			resource: mysqlresource,
-			db:       mysqldb,
-			port:     mysqlresource.GetPort("3306/tcp"),
-		},
+	var mariadb *sql.DB
+	if err = pool.Retry(func() error {
+		mariadb, err = sql.Open("mysql", fmt.Sprintf("dockertest:dockertest@(localhost:%s)/test?multiStatements=true", mariadbresource.GetPort("3306/tcp")))
+		if err != nil {
+			return err
+		}
+		return mariadb.Ping()
+	}); err != nil {
+		log.Panicf("Could not connect to mariadb docker: %s", err)
 	}
 
-	// run tests
-	code := m.Run()
-
-	if code != 0 && keepDB() {
-		log.Printf("Keeping database running because tests failed and KEEP_DB=1")
-		return
+	commonArgs := []string{"--host=127.0.0.1", "--user=dockertest", "--password=dockertest", "--database=test"}
+	testsdb["pg"] = testdb{
+		resource: pgresource,
+		db:       pgdb,
+		port:     pgresource.GetPort("5432/tcp"),
+		connArgs: append(slices.Clone(commonArgs), "--port="+pgresource.GetPort("5432/tcp")),
 	}
-	for _, resource := range []*dockertest.Resource{pgresource, mysqlresource} {
-		if err := pool.Purge(resource); err != nil {
-			log.Panicf("Could not purge resource: %s", err)
-		}
+	testsdb["mysql"] = testdb{
+		resource: mysqlresource,
+		db:       mysqldb,
+		port:     mysqlresource.GetPort("3306/tcp"),
+		connArgs: append(slices.Clone(commonArgs), "--port="+mysqlresource.GetPort("3306/tcp")),
 	}
+
+	testsdb["mariadb"] = testdb{
+		resource: mariadbresource,
+		db:       mariadb,
+		port:     mariadbresource.GetPort("3306/tcp"),
+		connArgs: append(slices.Clone(commonArgs), "--port="+mariadbresource.GetPort("3306/tcp")),
+	}
+
+	return []dockerResource{{pool, pgresource}, {pool, mysqlresource}, {pool, mariadbresource}}, nil
 }
 
 func TestRun(t *testing.T) {
@@ -125,7 +162,7 @@ func TestRun(t *testing.T) {
 		{
 			name:       "basic",
 			checkQuery: "select count(*) = 10 from t1;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "mariadb", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=10", "--table=t1"}},
 		},
 
@@ -144,7 +181,7 @@ func TestRun(t *testing.T) {
 		{
 			name:       "pk",
 			checkQuery: "select count(*) = 100 from t1;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
 		},
 		{
@@ -153,25 +190,141 @@ func TestRun(t *testing.T) {
 			engines:    []string{"mysql"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
 		},
+		{
+			// the second run inserts default keys, they must not collide with the explicit ones of the first run
+			name:       "explicit_keys",
+			checkQuery: "select count(*) = 200 from t1;",
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--explicit-keys"}, []string{"--rows=100", "--table=t1"}},
+		},
 
+		{
+			name:       "bulk_load",
+			checkQuery: "select count(*) = 100 from t1;",
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--bulk-load-mode", "--defer-indexes"}},
+		},
+		{
+			// the indexes are created again and the table is logged after the load
+			name:       "bulk_load_unlogged",
+			checkQuery: "select count(*) = 100 and (select count(*) = 2 from pg_indexes where tablename = 't1' and indexname like 't1_c1%') and (select relpersistence = 'p' from pg_class where relname = 't1') from t1;",
+			engines:    []string{"pg"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--bulk-load-mode", "--unlogged", "--defer-indexes", "--session-var=work_mem=64MB"}},
+		},
+		{
+			// enum labels, checks of a domain and of its base domain, composite row literals
+			name:       "user_defined_types",
+			checkQuery: "select count(*) = 100 and bool_and(qty > 0 and qty < 100) and count(product) > 0 and bool_and((product).name is not null) from t1;",
+			engines:    []string{"pg"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
+		},
+		{
+			name:       "arrays",
+			checkQuery: "select count(*) = 100 and bool_and(cardinality(ids) between 1 and 3 and cardinality(ids) = (select count(distinct x) from unnest(ids) x)) and bool_and(coalesce(cardinality(tags), 1) between 1 and 3) and count(codes) > 0 from t1;",
+			engines:    []string{"pg"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--array-length=uniform(1,3)", "--array-unique-elements", "--null-freq=0"}},
+		},
+		{
+			// the second run starts its ranges after the ones of the first run, the exclusion constraints reject overlaps
+			name:       "postgres_types",
+			checkQuery: "select count(*) = 200 and bool_and(not isempty(slot) and not isempty(during)) and bool_and(length(raw) > 0) from t1;",
+			engines:    []string{"pg"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}, []string{"--rows=100", "--table=t1"}},
+		},
+		{
+			name:       "mysql_types",
+			checkQuery: "select count(*) = 200 and min(s) < 0 and max(u) > 127 and sum(tags like '%,%') > 0 and sum(bit_count(b) > 0) > 0 from t1;",
+			engines:    []string{"mysql"},
+			cmds:       [][]string{[]string{"--rows=200", "--table=t1", "--null-freq=0"}},
+		},
+		{
+			name:       "bulk_load_sqlite_indexes",
+			checkQuery: "select count(*) = 100 and (select count(*) = 2 from sqlite_master where type = 'index' and name like 't1_c1%') from t1;",
+			engines:    []string{"sqlite"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--defer-indexes", "--session-var=cache_size=-20000"}},
+		},
+		{
+			// rdl: annotations of the comments, the table null(1) applies to note only
+			name:       "hints",
+			checkQuery: "select count(*) = 100 and sum(case when status in ('new', 'done') and qty between 10 and 20 and note is null then 1 else 0 end) = 100 from t1;",
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
+		},
+		{
+			// the documents hold the keys read by the query, with its values
+			name:       "json",
+			checkQuery: "select count(*) = 100 and bool_and(doc->>'status' = 'paid' and doc->'customer'->>'id' = '42' and doc @> '{\"tags\": [\"web\"]}') from t1;",
+			inputQuery: `select id from t1 where t1.doc->>'status' = 'paid' and doc->'customer'->>'id' = '42' and doc @> '{"tags": ["web"]}'`,
+			engines:    []string{"pg"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--query-param-freq=1"}},
+		},
+		{
+			name:       "json",
+			checkQuery: "select count(*) = 100 and sum(doc->>'$.status' = 'paid' and doc->>'$.customer.id' = 42) = 100 from t1;",
+			inputQuery: `select id from t1 where t1.doc->>'status' = 'paid' and doc->'customer'->>'id' = '42'`,
+			engines:    []string{"mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--query-param-freq=1"}},
+		},
+		{
+			// points around hotspots of Paris, latitude first as SRID 4326 defines it
+			name:       "geometry",
+			checkQuery: "select count(*) = 100 and sum(ST_IsValid(location) and ST_IsValid(route) and ST_IsValid(area) and ST_IsValid(shape)) = 100 and sum(ST_Longitude(location) between 2 and 3 and ST_Latitude(location) between 48 and 49) = 100 and sum(ST_NumPoints(ST_ExteriorRing(area)) = 7) = 100 from t1;",
+			engines:    []string{"mysql"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--null-freq=0", "--geo-bbox=2,48,3,49", "--geo-distribution=clustered(3,0.02)", "--geo-polygon-vertices=6"}},
+		},
 		{
 			name:       "pk_varchar",
 			checkQuery: "select count(*) = 100 from t1;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
 		},
 
+		{
+			name:       "unique_indexes",
+			checkQuery: "select (count(*) = 127) and (count(distinct id) = 127) and (count(distinct code) = 127) from t1;",
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=127", "--table=t1"}},
+		},
+
+		{
+			name:       "generated_columns",
+			checkQuery: "select (count(*) = 100) and (sum(CASE WHEN total = price * 2 THEN 1 ELSE 0 END) = 100) from t1;",
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
+		},
+
+		{
+			name:       "partitions",
+			checkQuery: "select (count(*) = 300) and (sum(CASE WHEN created < '2021-01-01' THEN 1 ELSE 0 END) between 60 and 140) from t1;",
+			engines:    []string{"pg", "mysql"},
+			cmds:       [][]string{[]string{"--rows=300", "--table=t1"}},
+		},
+
+		{
+			name:       "check_constraints",
+			checkQuery: "select (count(*) = 500) and (min(price) > 0) and (max(price) <= 100) and (count(distinct status) = 2) and (min(length(code)) = 3) and (max(length(code)) = 3) and (sum(CASE WHEN ended >= started THEN 1 ELSE 0 END) = 500) from t1;",
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=500", "--table=t1"}},
+		},
+
 		{
 			name:       "bool",
 			checkQuery: "select (count(*) = 100) and (sum(CASE WHEN c1 THEN 1 ELSE 0 END) between 1 and 99) from t1;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
+		},
+
+		{
+			name:       "mariadb_types",
+			checkQuery: "select (count(*) = 100) and (count(distinct id) = 100) and (sum(json_valid(doc)) = 100) and (sum(v1 = id * 2) = 100) from t1;",
+			engines:    []string{"mariadb"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
 		},
 
 		{
 			name:       "fk_uniform",
 			checkQuery: "select count(*) = 100 from t1 join t2 on t1.id = t2.t1_id;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}, []string{"--rows=100", "--table=t2", "--default-relationship=sequential"}},
 		},
 
@@ -179,7 +332,7 @@ func TestRun(t *testing.T) {
 		{
 			name:       "fk_binomial",
 			checkQuery: "select count(distinct t1.id) between 1 and 99 from t1 join t2 on t1.id = t2.t1_id;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}, []string{"--rows=100", "--table=t2", "--default-relationship=binomial", "--coin-flip-percent=60"}},
 		},
 
@@ -187,14 +340,14 @@ func TestRun(t *testing.T) {
 		{
 			name:       "fk_binomial_looping_chunks",
 			checkQuery: "select count(distinct t1.id) between 1 and 999 from t1 join t2 on t1.id = t2.t1_id;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=1000", "--table=t1"}, []string{"--rows=1000", "--table=t2", "--default-relationship=binomial", "--coin-flip-percent=5", "--bulk-size=100"}},
 		},
 
 		{
 			name:       "fk_multicol",
 			checkQuery: "select count(*) = 100 from t1 join t2 on t1.id = t2.t1_id and t1.id2 = t2.t1_id2;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}, []string{"--rows=100", "--table=t2", "--default-relationship=sequential"}},
 		},
 
@@ -202,7 +355,7 @@ func TestRun(t *testing.T) {
 			name:       "fk_pivot_varchar_integer",
 			checkQuery: "select count(*) = 100 from t1 join t3 on t1.order_id=t3.order_id join t2 on t2.id = t3.product_no;",
 			inputQuery: "select sum(t2.price), count(t1.*) from t1 join t3 on t1.order_id=t3.order_id join t2 on t2.id = t3.product_no where t1.currency='EUR';",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--default-relationship=sequential"}},
 		},
 
@@ -210,7 +363,7 @@ func TestRun(t *testing.T) {
 			name:       "basic_query",
 			checkQuery: "select (count(*) = 100) and (sum(CASE WHEN c2 IS NULL THEN 1 ELSE 0 END) = 100)  from t1 where c1 is not null;",
 			inputQuery: "select c1 from t1;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--null-freq=0"}},
 		},
 
@@ -218,7 +371,7 @@ func TestRun(t *testing.T) {
 			name:       "identifiers_skip_not_null_nodefaults",
 			checkQuery: "select (count(*) = 100) and (sum(CASE WHEN c2 <> '' THEN 1 ELSE 0 END) = 100)  from t1 where c1 is not null;",
 			inputQuery: "select c1 from t1;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
 		},
 
@@ -226,7 +379,7 @@ func TestRun(t *testing.T) {
 			name:       "identifiers_skip_not_null_defaults",
 			checkQuery: "select (count(*) = 100) and (sum(CASE WHEN c2 <> 'test' THEN 1 ELSE 0 END) = 0)  from t1 where c1 is not null;",
 			inputQuery: "select c1 from t1;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
 		},
 
@@ -239,12 +392,21 @@ func TestRun(t *testing.T) {
 				cmds:       [][]string{[]string{"--rows=100", "--table=t1"}, []string{"--rows=100", "--table=t2", "--default-relationship=sequential"}},
 			},
 		*/
+		{
+			name: "fk_loop",
+			// t1.t3_id is left NULL to break the loop, then updated
+			checkQuery: "select (select count(*) from t1 join t3 on t3.id = t1.t3_id) = 100 and (select count(*) from t2 join t1 on t1.id = t2.t1_id) = 100 and (select count(*) from t3 join t2 on t2.id = t3.t2_id) = 100;",
+			inputQuery: "select * from t1 join t2 on t1.id = t2.t1_id join t3 on t2.id = t3.t2_id join t1 t on t.t3_id = t3.id;",
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=100", "--default-relationship=sequential"}},
+		},
+
 		{
 			name: "fk_cascade_recursive",
 			// t1 alone, t2 dep on t1, t3 dep on t2 and t4 dep on t2+t3
 			checkQuery: "select count(*) = 100 from t1 join t2 on t1.id = t2.t1_id join t3 on t2.id = t3.t2_id join t4 on t3.id = t4.t3_id and t2.id = t4.t2_id;",
 			inputQuery: "select count(*) = 100 from t1 join t2 on t1.id = t2.t1_id join t3 on t2.id = t3.t2_id join t4 on t3.id = t4.t3_id and t2.id = t4.t2_id;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--default-relationship=sequential"}},
 		},
 		{
@@ -252,7 +414,7 @@ func TestRun(t *testing.T) {
 			name:       "fk_cascade_recursive_reversed",
 			checkQuery: "select count(*) = 100 from t4 join t2 on t2.id = t4.t2_id join t3 on t3.id = t4.t3_id and t3.t2_id = t2.id join t1 on t1.id = t2.t1_id;",
 			inputQuery: "select count(*) = 100 from t4 join t2 on t2.id = t4.t2_id join t3 on t3.id = t4.t3_id and t3.t2_id = t2.id join t1 on t1.id = t2.t1_id;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--default-relationship=sequential"}},
 		},
 
@@ -260,22 +422,53 @@ func TestRun(t *testing.T) {
 			name:       "fk_virtual",
 			checkQuery: "select count(*) = 100 from t1 join t2 on t1.id = t2.t1_id;",
 			inputQuery: "select * from t1 join t2 on t1.id = t2.t1_id;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}, []string{"--rows=100", "--table=t2", "--default-relationship=sequential"}},
 		},
 
+		{
+			name:       "triggers",
+			checkQuery: "select (select count(*) = 100 from t1) and (select count(*) = 100 from t2);",
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
+		},
+		{
+			name:       "triggers_disabled",
+			checkQuery: "select (select count(*) = 100 from t1) and (select count(*) = 0 from t2);",
+			engines:    []string{"pg"},
+			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--triggers=disable"}},
+		},
+
+		{
+			name: "schemas",
+			// t1 is qualified by its schema, the public one must be left alone
+			checkQuery: "select (select count(*) = 100 from s2.t1 join t2 on t1.id = t2.t1_id) and (select count(*) = 0 from public.t1);",
+			inputQuery: "select * from s2.t1 join t2 on t1.id = t2.t1_id;",
+			engines:    []string{"pg"},
+			cmds:       [][]string{[]string{"--rows=100", "--default-relationship=sequential"}},
+		},
+
+		{
+			name: "views",
+			// the view is expanded to t1 and t2, its join becomes a virtual foreign key
+			checkQuery: "select count(*) = 100 from t1 join t2 on t1.id = t2.t1_id;",
+			inputQuery: "select * from v;",
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=100", "--default-relationship=sequential"}},
+		},
+
 		{
 			name:       "fk_virtual_cascade_table_per_table",
 			checkQuery: "select count(*) = 100 from t1 join t2 on t1.id = t2.t1_id join t3 on t2.id = t3.t2_id join t4 on t3.id = t4.t3_id;",
 			inputQuery: "select * from t1 join t2 on t1.id = t2.t1_id join t3 on t2.id = t3.t2_id join t4 on t3.id = t4.t3_id;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}, []string{"--rows=100", "--table=t2", "--default-relationship=sequential"}, []string{"--rows=100", "--table=t3", "--default-relationship=sequential"}, []string{"--rows=100", "--table=t4", "--default-relationship=sequential"}},
 		},
 		{
 			name:       "fk_virtual_cascade_recursive",
 			checkQuery: "select count(*) = 100 from t1 join t2 on t1.id = t2.t1_id join t3 on t2.id = t3.t2_id join t4 on t3.id = t4.t3_id;",
 			inputQuery: "select count(*) = 100 from t1 join t2 on t1.id = t2.t1_id join t3 on t2.id = t3.t2_id join t4 on t3.id = t4.t3_id;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--default-relationship=sequential"}},
 		},
 
@@ -283,14 +476,14 @@ func TestRun(t *testing.T) {
 			name:       "star_query",
 			checkQuery: "select (count(*) = 100) and (sum(CASE WHEN c2 IS NOT NULL THEN 1 ELSE 0 END) = 100)  from t1 where c1 is not null;",
 			inputQuery: "select * from t1;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1"}},
 		},
 
 		{
 			name:       "text_max_size",
 			checkQuery: "select (count(*) = 100) from t1 where length(data) < 10;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100", "--table=t1", "--max-text-size=9"}},
 		},
 
@@ -342,14 +535,14 @@ func TestRun(t *testing.T) {
 		{
 			name:       "null_map",
 			checkQuery: "select (count(*) = 100000) AND (sum(CASE WHEN c1 IS NULL THEN 1 ELSE 0 END) between 19500 and 20500) AND (sum(CASE WHEN c2 IS NULL THEN 1 ELSE 0 END) between 39500 and 40500) AND (sum(CASE WHEN c3 IS NULL THEN 1 ELSE 0 END) between 89500 and 90500) from t1;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100000", "--table=t1", "--null-freq=0.2", "--null-freq-map=t1.c2=0.4;t1.c3=0.9"}},
 		},
 
 		{
 			name:       "values_freq_map",
 			checkQuery: "select (count(*) = 100000) AND (sum(CASE WHEN c1 = 42 THEN 1 ELSE 0 END) between 79500 and 80500) AND (sum(CASE WHEN c1 = 7 THEN 1 ELSE 0 END) between 4500 and 5500) AND (sum(CASE WHEN c2 = 'pg' THEN 1 ELSE 0 END) between 36500 and 37500) AND (sum(CASE WHEN c2 = 'mysql' THEN 1 ELSE 0 END) between 33500 and 34500) AND (sum(CASE WHEN c2 = 'other' THEN 1 ELSE 0 END) between 400 and 600) from t1;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100000", "--table=t1", "--null-freq=0", "--values-freq-map=t1.c1=42:0.8,7:0.05;t1.c2=pg:0.37,mysql:0.34,other:0.005"}},
 		},
 
@@ -357,37 +550,54 @@ func TestRun(t *testing.T) {
 			name:       "query_params",
 			checkQuery: "select (count(*) = 100000) AND (sum(CASE WHEN c2 = 'it' THEN 1 ELSE 0 END) between 9500 and 10500) AND (sum(CASE WHEN c2 = 'should' THEN 1 ELSE 0 END) between 9500 and 10500) AND (sum(CASE WHEN c2 = 'work' THEN 1 ELSE 0 END) between 9500 and 10500) from t1;",
 			inputQuery: "select * from t1 where t1.c2 in ('it', 'should', 'work')",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100000", "--table=t1", "--null-freq=0", "--query-param-freq=0.1"}},
 		},
 		{
 			name:       "query_params_no_infix",
 			checkQuery: "select (count(*) = 100000) AND (sum(CASE WHEN c2 = 'it' THEN 1 ELSE 0 END) between 9500 and 10500) AND (sum(CASE WHEN c2 = 'should' THEN 1 ELSE 0 END) between 9500 and 10500) AND (sum(CASE WHEN c2 = 'work' THEN 1 ELSE 0 END) between 9500 and 10500) from t1;",
 			inputQuery: "select * from t1 where c2 in ('it', 'should', 'work')",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=100000", "--table=t1", "--null-freq=0", "--query-param-freq=0.1"}},
 		},
 
 		{
 			name:       "fk_self_referencing",
 			checkQuery: "select count(*) = 500 from t1 join t1 t1_2 on t1.id = t1_2.t1_id;",
-			engines:    []string{"pg", "mysql"},
+			engines:    []string{"pg", "mysql", "sqlite"},
 			cmds:       [][]string{[]string{"--rows=1000", "--table=t1", "--default-relationship=sequential"}},
 		},
+		{
+			name: "fk_self_referencing_hierarchy",
+			// 1 root, then 4, 16, 64 and 256 rows: every row of the last level is 4 levels below its root
+			checkQuery: "select count(*) = 256 from t1 l4 join t1 l3 on l3.id = l4.t1_id join t1 l2 on l2.id = l3.t1_id join t1 l1 on l1.id = l2.t1_id join t1 l0 on l0.id = l1.t1_id where l0.t1_id is null;",
+			engines:    []string{"pg", "mysql", "sqlite"},
+			cmds:       [][]string{[]string{"--rows=341", "--table=t1", "--hierarchy=t1.t1_id=depth:5,branching:4"}},
+		},
 	}
 
 	for _, test := range tests {
 		for _, engine := range test.engines {
-			errlog := fmt.Sprintf("engine: %s, container: %s, testname: %s", engine, testsdb[engine].resource.Container.Name, test.name)
+			tdb, available := testsdb[engine]
+			if !available {
+				t.Logf("skipping %s on %s, database not available", test.name, engine)
+				continue
+			}
+
+			errlog := fmt.Sprintf("engine: %s, testname: %s", engine, test.name)
 			if !keepDB() {
-				errlog = fmt.Sprintf("to repeat the test and keep the container running, use KEEP_DB=1 go test .\n%s", errlog)
+				errlog = fmt.Sprintf("to repeat the test and keep the database, use KEEP_DB=1 go test .\n%s", errlog)
 			}
 
 			switch engine {
 			case "mysql":
-				errlog += fmt.Sprintf("\ndocker exec -it %s mysql -u dockertest -pdockertest test", testsdb[engine].resource.Container.Name)
+				errlog += fmt.Sprintf("\ndocker exec -it %s mysql -u dockertest -pdockertest test", tdb.resource.Container.Name)
+			case "mariadb":
+				errlog += fmt.Sprintf("\ndocker exec -it %s mariadb -u dockertest -pdockertest test", tdb.resource.Container.Name)
 			case "pg":
-				errlog += fmt.Sprintf("\ndocker exec -it %s bash -c 'PGPASSWORD=dockertest psql -U dockertest test'", testsdb[engine].resource.Container.Name)
+				errlog += fmt.Sprintf("\ndocker exec -it %s bash -c 'PGPASSWORD=dockertest psql -U dockertest test'", tdb.resource.Container.Name)
+			case "sqlite":
+				errlog += fmt.Sprintf("\nsqlite3 %s", strings.TrimPrefix(tdb.connArgs[0], "--database="))
 			}
 			errlog += "\n"
 
@@ -400,7 +610,8 @@ func TestRun(t *testing.T) {
 
 			// calling tool with args directly
 			for _, cmd := range test.cmds {
-				args := []string{"run", "--engine=" + engine, "--host=127.0.0.1", "--user=dockertest", "--password=dockertest", "--database=test", "--port=" + testsdb[engine].port}
+				args := []string{"run", "--engine=" + engine}
+				args = append(args, tdb.connArgs...)
 				args = append(args, cmd...)
 
 				if test.inputQuery != "" {
@@ -414,7 +625,7 @@ func TestRun(t *testing.T) {
 				}
 			}
 
-			row := testsdb[engine].db.QueryRow(test.checkQuery)
+			row := tdb.db.QueryRow(test.checkQuery)
 			var ok bool
 			err := row.Scan(&ok)
 			if err != nil {
@@ -16,11 +16,26 @@ type VirtualJoin struct {
 }
 
 type VirtualJoinPart struct {
+	Schema  string // empty when the table is not qualified
 	Table   string
 	Columns []string
 }
 
-var ErrMalformedForeignKey = errors.New("malformed foreign-key, the format is 'parent_table.col1[,col2]=child_table.col1[,col2];other_parent.colx=other_child.coly'")
+// NewVirtualJoinPart splits the schema from a table name written schema.table
+func NewVirtualJoinPart(name string, columns []string) VirtualJoinPart {
+	schema, table := SplitTableName(name)
+	return VirtualJoinPart{Schema: schema, Table: table, Columns: columns}
+}
+
+// Name returns the table name, prefixed by its schema when it has one
+func (p VirtualJoinPart) Name() string {
+	if p.Schema == "" {
+		return p.Table
+	}
+	return p.Schema + "." + p.Table
+}
+
+var ErrMalformedForeignKey = errors.New("malformed foreign-key, the format is '[schema.]parent_table.col1[,col2]=[schema.]child_table.col1[,col2];other_parent.colx=other_child.coly'")
 
 func (v VirtualJoins) Decode(ctx *kong.DecodeContext, target reflect.Value) error {
 	var value string
@@ -38,30 +53,22 @@ func (v VirtualJoins) Decode(ctx *kong.DecodeContext, target reflect.Value) erro
 			return ErrMalformedForeignKey
 		}
 
-		left := strings.Split(parts[0], ".")
-		right := strings.Split(parts[1], ".")
-		if len(left) != 2 || len(right) != 2 {
+		// the columns follow the last dot, the table can be qualified by its schema
+		parentTable, parentColsRaw := SplitTableName(parts[0])
+		childTable, childColsRaw := SplitTableName(parts[1])
+		if parentTable == "" || childTable == "" || strings.Count(parentTable, ".") > 1 || strings.Count(childTable, ".") > 1 {
 			return ErrMalformedForeignKey
 		}
 
-		parentTable := left[0]
-		childTable := right[0]
-
-		parentCols := strings.Split(left[1], ",")
-		childCols := strings.Split(right[1], ",")
+		parentCols := strings.Split(parentColsRaw, ",")
+		childCols := strings.Split(childColsRaw, ",")
 		if len(parentCols) != len(childCols) {
 			return errors.Wrap(ErrMalformedForeignKey, "parent table and child table should have the same amount of columns")
 		}
 
 		vfk := VirtualJoin{
-			Left: VirtualJoinPart{
-				Table:   parentTable,
-				Columns: parentCols,
-			},
-			Right: VirtualJoinPart{
-				Table:   childTable,
-				Columns: childCols,
-			},
+			Left:  NewVirtualJoinPart(parentTable, parentCols),
+			Right: NewVirtualJoinPart(childTable, childCols),
 		}
 		vfks = append(vfks, vfk)
 	}
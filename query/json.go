@@ -0,0 +1,357 @@
+package query
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gitlab.com/dalibo/transqlate/ast"
+	"gitlab.com/dalibo/transqlate/lexer"
+)
+
+// JSONPath is a key of a JSON column read by the query, it has to exist in the generated documents
+type JSONPath struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Path   string `json:"path"`           // $.a.b[0], [*] being any element of an array
+	Type   string `json:"type,omitempty"` // string, integer, number, boolean, array or object, guessed from the predicates
+}
+
+// JSON accessors the parser does not know, rewritten as JSON_EXTRACT calls
+var jsonOperators = []string{"->", "->>", "#>", "#>>"}
+
+// functions taking a JSON column and paths: JSON_EXTRACT(doc, '$.a'), jsonb_extract_path_text(doc, 'a', 'b')
+var (
+	jsonPathFunctions = []string{"JSON_EXTRACT", "JSON_VALUE"}
+	jsonKeyFunctions  = []string{"JSON_EXTRACT_PATH", "JSON_EXTRACT_PATH_TEXT", "JSONB_EXTRACT_PATH", "JSONB_EXTRACT_PATH_TEXT"}
+	jsonUnquote       = "JSON_UNQUOTE"
+	jsonContains      = "JSON_CONTAINS"
+)
+
+var jsonSimpleKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// rewriteJSONOperators turns doc->'a'->>'b', doc#>>'{a,b}' or doc->>'$.a.b' into JSON_EXTRACT(doc, '$.a.b'), so that they can be parsed.
+// Only accessors applied to a column are rewritten, with constant keys
+func rewriteJSONOperators(query, dialect string) (string, error) {
+	tokens, err := lexQuery(query, dialect)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Type != lexer.Identifier {
+			sb.WriteString(token.Prefix + token.Raw + token.Suffix)
+			continue
+		}
+		// t.doc, schema.t.doc
+		end := i
+		for end+2 < len(tokens) && isOperator(tokens[end+1], ".") && tokens[end+2].Type == lexer.Identifier {
+			end += 2
+		}
+		path := "$"
+		accessors := end
+		for accessors+2 < len(tokens) && slices.ContainsFunc(jsonOperators, func(op string) bool { return isOperator(tokens[accessors+1], op) }) {
+			segment, ok := jsonOperatorPath(tokens[accessors+1].Str, tokens[accessors+2])
+			if !ok {
+				break
+			}
+			path += segment
+			accessors += 2
+		}
+		if accessors == end {
+			sb.WriteString(token.Prefix + token.Raw + token.Suffix)
+			continue
+		}
+		column := ""
+		for _, t := range tokens[i : end+1] {
+			column += t.Raw
+		}
+		sb.WriteString(token.Prefix + "JSON_EXTRACT(" + column + ", '" + strings.ReplaceAll(path, "'", "''") + "')" + tokens[accessors].Suffix)
+		i = accessors
+	}
+	return sb.String(), nil
+}
+
+// lexQuery splits the query into tokens. The lexer takes a # after a space for a MySQL comment, and a # right after a name for a part of it:
+// PostgreSQL #> and #>> are lexed again as operators
+func lexQuery(query, dialect string) ([]lexer.Token, error) {
+	tokens := []lexer.Token{}
+	offset := 0
+	l := lexer.New("", query)
+	for {
+		token := l.Next()
+		if token.Type == lexer.Error {
+			return nil, token.Error
+		}
+		token.Normalize()
+		restart := false
+		if i := strings.Index(token.Suffix, "#>"); dialect == DialectPostgres && i >= 0 && strings.TrimSpace(token.Suffix[:i]) == "" {
+			token.Suffix = token.Suffix[:i]
+			restart = true
+		}
+		rest := query[offset+len(token.Prefix+token.Raw):]
+		if dialect == DialectPostgres && token.Type == lexer.Identifier && token.Suffix == "" && len(token.Raw) > 1 && strings.HasSuffix(token.Raw, "#") && strings.HasPrefix(rest, ">") {
+			token.Raw = strings.TrimSuffix(token.Raw, "#")
+			token.Normalize()
+			restart = true
+		}
+		tokens = append(tokens, token)
+		if token.Type == lexer.EOF {
+			return tokens, nil
+		}
+		offset += len(token.Prefix + token.Raw + token.Suffix)
+		if restart {
+			l = lexer.New("", query[offset:])
+		}
+	}
+}
+
+func isOperator(token lexer.Token, op string) bool {
+	return token.Type == lexer.Operator && token.Str == op
+}
+
+// jsonOperatorPath returns the path segment of an accessor: a key or an index for -> and ->>, a '{a,b}' list for #> and #>>, or a MySQL path
+func jsonOperatorPath(operator string, key lexer.Token) (string, bool) {
+	switch key.Type {
+	case lexer.Integer:
+		return "[" + key.Str + "]", operator == "->" || operator == "->>"
+	case lexer.String:
+	default:
+		return "", false
+	}
+	if strings.HasPrefix(operator, "#") {
+		list := strings.TrimSpace(key.Str)
+		if !strings.HasPrefix(list, "{") || !strings.HasSuffix(list, "}") {
+			return "", false
+		}
+		segments := ""
+		for _, k := range strings.Split(list[1:len(list)-1], ",") {
+			segments += jsonKeySegment(strings.Trim(strings.TrimSpace(k), `"`))
+		}
+		return segments, true
+	}
+	if strings.HasPrefix(key.Str, "$") {
+		return strings.TrimPrefix(key.Str, "$"), true
+	}
+	return jsonKeySegment(key.Str), true
+}
+
+// jsonKeySegment writes a key of a path, array indexes being numbers
+func jsonKeySegment(key string) string {
+	if _, err := strconv.Atoi(key); err == nil {
+		return "[" + key + "]"
+	}
+	if jsonSimpleKeyRe.MatchString(key) {
+		return "." + key
+	}
+	return `."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
+
+// jsonAccessor returns the column and the path of JSON_EXTRACT(doc, '$.a') and alike, JSON_UNQUOTE and casts being ignored
+func jsonAccessor(n ast.Node) (ast.Node, string, bool) {
+	n = unwrapParens(n)
+	if cast, ok := n.(ast.Infix); ok && cast.Is("::") {
+		return jsonAccessor(cast.Left)
+	}
+	call, ok := n.(ast.Call)
+	if !ok || len(call.Args) == 0 {
+		return nil, "", false
+	}
+	name := functionName(call)
+	args := call.Args
+	switch {
+	case name == jsonUnquote && len(args) == 1:
+		return jsonAccessor(args[0].Expression)
+	case slices.Contains(jsonPathFunctions, name) && len(args) >= 2:
+		path, ok := args[1].Expression.(ast.Leaf)
+		if !ok || path.Token.Type != lexer.String || !strings.HasPrefix(path.Token.Str, "$") {
+			return nil, "", false
+		}
+		return args[0].Expression, path.Token.Str, true
+	case slices.Contains(jsonKeyFunctions, name) && len(args) >= 2:
+		path := "$"
+		for _, arg := range args[1:] {
+			key, ok := arg.Expression.(ast.Leaf)
+			if !ok || key.Token.Type != lexer.String {
+				return nil, "", false
+			}
+			path += jsonKeySegment(key.Token.Str)
+		}
+		return args[0].Expression, path, true
+	}
+	return nil, "", false
+}
+
+func functionName(call ast.Call) string {
+	return strings.ToUpper(strings.TrimSpace(lexer.Write(call.Function)))
+}
+
+// traverseJSONPaths lists the keys of JSON columns read by the query, and the containment predicates (@>, JSON_CONTAINS) as parameters
+func (an *analyzer) traverseJSONPaths(n ast.Node) ([]JSONPath, []Parameter) {
+	paths := []JSONPath{}
+	params := []Parameter{}
+
+	addPath := func(column ast.Node, path, valueType string) (string, string, bool) {
+		table, col := an.getTableColumnFromInfixOrLeaf(column)
+		if table == "" {
+			return "", "", false
+		}
+		paths = append(paths, JSONPath{Table: table, Column: col, Path: path, Type: valueType})
+		return table, col, true
+	}
+	// the candidate document is flattened into the keys it needs and their values
+	addContained := func(clause ast.Node, column ast.Node, path string, candidate ast.Node) {
+		leaf, ok := candidate.(ast.Leaf)
+		var doc any
+		if !ok || leaf.Token.Type != lexer.String || json.Unmarshal([]byte(leaf.Token.Str), &doc) != nil {
+			an.addDiagnostic(DiagnosticPredicate, ReasonUnsupportedNode, clause)
+			return
+		}
+		// a scalar is contained in an array holding it
+		switch doc.(type) {
+		case map[string]any, []any:
+		default:
+			doc = []any{doc}
+		}
+		table, col, ok := addPath(column, path, jsonType(doc))
+		if !ok {
+			an.addDiagnostic(DiagnosticPredicate, ReasonUnsupportedNode, clause)
+			return
+		}
+		flattenJSON(doc, path, func(path string, value any) {
+			paths = append(paths, JSONPath{Table: table, Column: col, Path: path, Type: jsonType(value)})
+			switch value.(type) {
+			case map[string]any, []any, nil:
+				return
+			}
+			params = append(params, Parameter{Table: table, Column: col, Path: path, Operator: "=", Values: []string{jsonScalar(value)}})
+		})
+	}
+
+	traverser := func(n ast.Node) bool {
+		switch n := n.(type) {
+		case ast.Call:
+			if functionName(n) == jsonContains && len(n.Args) >= 2 {
+				path := "$"
+				if len(n.Args) == 3 {
+					leaf, ok := n.Args[2].Expression.(ast.Leaf)
+					if !ok || leaf.Token.Type != lexer.String {
+						an.addDiagnostic(DiagnosticPredicate, ReasonUnsupportedNode, n)
+						return true
+					}
+					path = leaf.Token.Str
+				}
+				addContained(n, n.Args[0].Expression, path, n.Args[1].Expression)
+				return true
+			}
+			column, path, ok := jsonAccessor(n)
+			if !ok {
+				return true
+			}
+			if _, _, ok := addPath(column, path, ""); !ok {
+				log.Debug().Type("node", column).Str("path", path).Msg("JSON accessor on an unresolved column, skipping")
+			}
+			// JSON_UNQUOTE(JSON_EXTRACT()) is already handled
+			return false
+		case ast.Infix:
+			if !n.Is("@>") {
+				return true
+			}
+			column, path, ok := jsonAccessor(n.Left)
+			if !ok {
+				column, path = n.Left, "$"
+			}
+			addContained(n, column, path, n.Right)
+		}
+		return true
+	}
+	n.Traverse(traverser)
+	return paths, params
+}
+
+// flattenJSON calls f on every value of the document, the elements of arrays being at [*]
+func flattenJSON(doc any, path string, f func(path string, value any)) {
+	switch doc := doc.(type) {
+	case map[string]any:
+		for key, value := range doc {
+			child := path + jsonKeySegment(key)
+			f(child, value)
+			flattenJSON(value, child, f)
+		}
+	case []any:
+		for _, value := range doc {
+			child := path + "[*]"
+			f(child, value)
+			flattenJSON(value, child, f)
+		}
+	}
+}
+
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return ""
+}
+
+// predicateType guesses the type of a key compared to values
+func predicateType(param Parameter) string {
+	if param.Operator == "LIKE" || param.Operator == "NOT LIKE" {
+		return "string"
+	}
+	guessed := ""
+	for _, v := range param.Values {
+		t := "string"
+		_, errInt := strconv.ParseInt(v, 10, 64)
+		_, errFloat := strconv.ParseFloat(v, 64)
+		switch {
+		case errInt == nil:
+			t = "integer"
+		case errFloat == nil:
+			t = "number"
+		case strings.EqualFold(v, "true") || strings.EqualFold(v, "false"):
+			t = "boolean"
+		}
+		if guessed != "" && guessed != t {
+			return "string"
+		}
+		guessed = t
+	}
+	return guessed
+}
+
+func jsonScalar(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+// JSONColumns maps "table.column" to the paths of the JSON columns read by the query, the table keeps the schema the query qualifies it with
+func (a *Analysis) JSONColumns() map[string][]JSONPath {
+	paths := map[string][]JSONPath{}
+	for _, path := range a.JSONPaths {
+		id := path.Table + "." + path.Column
+		paths[id] = append(paths[id], path)
+	}
+	return paths
+}
@@ -0,0 +1,138 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRewriteJSONOperators(t *testing.T) {
+	tests := []struct {
+		query, dialect, expected string
+	}{
+		{
+			query:    "select * from t1 where t1.doc->'a'->>'b' = 'x'",
+			dialect:  DialectPostgres,
+			expected: "select * from t1 where JSON_EXTRACT(t1.doc, '$.a.b') = 'x'",
+		},
+		{
+			query:    "select * from t1 where doc->'a'->0->>'my key' = 'x'",
+			dialect:  DialectPostgres,
+			expected: `select * from t1 where JSON_EXTRACT(doc, '$.a[0]."my key"') = 'x'`,
+		},
+		{
+			query:    "select * from t1 where t1.doc#>>'{a,b}' = 'x' and t1.doc #> '{c,0}' = '1'",
+			dialect:  DialectPostgres,
+			expected: "select * from t1 where JSON_EXTRACT(t1.doc, '$.a.b') = 'x' and JSON_EXTRACT(t1.doc, '$.c[0]') = '1'",
+		},
+		{
+			query:    "select * from t1 where t1.doc->'tags' @> '\"x\"'",
+			dialect:  DialectPostgres,
+			expected: "select * from t1 where JSON_EXTRACT(t1.doc, '$.tags') @> '\"x\"'",
+		},
+		{
+			query:    "select * from t1 where t1.doc->>'$.a.b' = 'x' and t1.doc->'$[1]' = 2",
+			dialect:  DialectMySQL,
+			expected: "select * from t1 where JSON_EXTRACT(t1.doc, '$.a.b') = 'x' and JSON_EXTRACT(t1.doc, '$[1]') = 2",
+		},
+		// operators within strings are left as is
+		{
+			query:    "select * from t1 where t1.note = 'doc->''a''' and t1.doc->>'a' = 'x->>y'",
+			dialect:  DialectPostgres,
+			expected: "select * from t1 where t1.note = 'doc->''a''' and JSON_EXTRACT(t1.doc, '$.a') = 'x->>y'",
+		},
+		{
+			query:    "select * from t1 where t1.note = 'doc#>>''{a}'' @> x'",
+			dialect:  DialectPostgres,
+			expected: "select * from t1 where t1.note = 'doc#>>''{a}'' @> x'",
+		},
+		{
+			query:    "select * from t1 where t1.note = 'doc->>''$.a'''",
+			dialect:  DialectMySQL,
+			expected: "select * from t1 where t1.note = 'doc->>''$.a'''",
+		},
+		// keys must be constants
+		{
+			query:    "select * from t1 where t1.doc->>t1.key = 'x'",
+			dialect:  DialectPostgres,
+			expected: "select * from t1 where t1.doc->>t1.key = 'x'",
+		},
+	}
+	for _, test := range tests {
+		rewritten, err := rewriteJSONOperators(test.query, test.dialect)
+		if err != nil {
+			t.Errorf("%s: %s", test.query, err)
+			continue
+		}
+		if rewritten != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.query, test.expected, rewritten)
+		}
+	}
+}
+
+func TestAnalyzeJSONPaths(t *testing.T) {
+	tests := []struct {
+		query, dialect string
+		paths          map[string]string // the type of every path, empty when it is not guessed
+		params         map[string][]string
+	}{
+		{
+			query:   "select * from t1 where t1.doc->'a'->>'b' = 'x' and t1.doc->'c'->>0 in ('1', '2')",
+			dialect: DialectPostgres,
+			paths:   map[string]string{"t1.doc$.a.b": "string", "t1.doc$.c[0]": "integer"},
+			params:  map[string][]string{"t1.doc$.a.b": {"x"}, "t1.doc$.c[0]": {"1", "2"}},
+		},
+		{
+			query:   "select * from t1 where t1.doc#>>'{a,b}' like 'x%'",
+			dialect: DialectPostgres,
+			paths:   map[string]string{"t1.doc$.a.b": "string"},
+			params:  map[string][]string{},
+		},
+		{
+			query:   `select * from t1 where t1.doc @> '{"a": {"b": 1}, "tags": ["x"]}'`,
+			dialect: DialectPostgres,
+			paths: map[string]string{
+				"t1.doc$": "object", "t1.doc$.a": "object", "t1.doc$.a.b": "integer", "t1.doc$.tags": "array", "t1.doc$.tags[*]": "string",
+			},
+			params: map[string][]string{"t1.doc$.a.b": {"1"}, "t1.doc$.tags[*]": {"x"}},
+		},
+		{
+			query:   "select * from t1 where JSON_UNQUOTE(JSON_EXTRACT(t1.doc, '$.a[0]')) = 'x' and JSON_EXTRACT(t1.doc, '$.n') = 1.5",
+			dialect: DialectMySQL,
+			paths:   map[string]string{"t1.doc$.a[0]": "string", "t1.doc$.n": "number"},
+			params:  map[string][]string{"t1.doc$.a[0]": {"x"}, "t1.doc$.n": {"1.5"}},
+		},
+		{
+			query:   "select * from t1 where JSON_CONTAINS(t1.doc, '[1]', '$.ids') and t1.doc->>'$.ok' = true",
+			dialect: DialectMySQL,
+			paths:   map[string]string{"t1.doc$.ids": "array", "t1.doc$.ids[*]": "integer", "t1.doc$.ok": "boolean"},
+			params:  map[string][]string{"t1.doc$.ids[*]": {"1"}, "t1.doc$.ok": {"TRUE"}},
+		},
+		// accessors within strings are plain values
+		{
+			query:   "select * from t1 where t1.note = 'doc->>''a''' and t1.memo = 'JSON_EXTRACT(doc, ''$.a'')'",
+			dialect: DialectMySQL,
+			paths:   map[string]string{},
+			params:  map[string][]string{"t1.note": {"doc->>'a'"}, "t1.memo": {"JSON_EXTRACT(doc, '$.a')"}},
+		},
+	}
+	for _, test := range tests {
+		analysis, err := Analyze(test.query, test.dialect, false)
+		if err != nil {
+			t.Errorf("%s: %s", test.query, err)
+			continue
+		}
+		paths := map[string]string{}
+		for _, path := range analysis.JSONPaths {
+			id := path.Table + "." + path.Column + path.Path
+			if path.Type != "" || paths[id] == "" {
+				paths[id] = path.Type
+			}
+		}
+		if !reflect.DeepEqual(paths, test.paths) {
+			t.Errorf("%s: expected paths %v, got %v", test.query, test.paths, paths)
+		}
+		if params := analysis.QueryParams(); !reflect.DeepEqual(params, test.params) {
+			t.Errorf("%s: expected params %v, got %v", test.query, test.params, params)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package query
 
 import (
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"gitlab.com/dalibo/transqlate/ast"
@@ -10,42 +12,135 @@ import (
 	"gitlab.com/dalibo/transqlate/rewrite"
 )
 
-var aliases = map[string]string{} // only help to identify implicit joins
-var tables map[string]struct{}
+// analyzer holds the state of a single Analyze call, views being analyzed on their own
+type analyzer struct {
+	aliases     map[string]string // only help to identify implicit joins
+	tables      map[string]struct{}
+	diagnostics []Diagnostic
+}
+
+// Analysis holds everything that could be extracted from a query
+type Analysis struct {
+	Tables      map[string]struct{} `json:"tables"`
+	Aliases     map[string]string   `json:"aliases"`
+	Identifiers map[string]struct{} `json:"identifiers"`
+	Joins       []VirtualJoin       `json:"joins"`
+	Parameters  []Parameter         `json:"parameters"`
+	JSONPaths   []JSONPath          `json:"json_paths,omitempty"`
+	Columns     map[string]Column   `json:"columns,omitempty"`
+	Views       []string            `json:"views,omitempty"` // expanded to their base tables
+	Diagnostics []Diagnostic        `json:"diagnostics"`
+}
+
+// Parameter is a column compared to literal values in the query
+type Parameter struct {
+	Table string `json:"table"` // prefixed by its schema when the query qualifies it
+
+	Column   string   `json:"column"`
+	Path     string   `json:"path,omitempty"` // the key compared, for JSON columns
+	Operator string   `json:"operator"`
+	Values   []string `json:"values"`
+}
+
+// Diagnostic explains why a join or a predicate was skipped by the parser
+type Diagnostic struct {
+	Kind   string `json:"kind"` // join or predicate
+	Reason string `json:"reason"`
+	Clause string `json:"clause"`
+}
+
+const (
+	DiagnosticJoin      = "join"
+	DiagnosticPredicate = "predicate"
+
+	ReasonSubquery        = "subquery"
+	ReasonParenthesizedOn = "parenthesized ON"
+	ReasonAmbiguousColumn = "ambiguous column"
+	ReasonUnsupportedNode = "unsupported node type"
+)
+
+// Dialects are the parsers available, engines pick one of them
+var Dialects = []string{DialectMySQL, DialectPostgres}
+
+const (
+	DialectMySQL    = "mysql"
+	DialectPostgres = "pg"
+)
 
-// ParseQuery will return the list of tables, every raw identifiers used (including tables again), every joins it could detect, and a mapping of query parameters
-func ParseQuery(query, engine string, skipJoins bool) (map[string]struct{}, map[string]struct{}, []VirtualJoin, map[string][]string, error) {
+// Analyze parses the query and returns its tables, identifiers, joins and parameters, along with aliases, parameter operators and diagnostics
+func Analyze(query, dialect string, skipJoins bool) (*Analysis, error) {
 
 	var parsed ast.Node
-	var err error
+	query, err := rewriteJSONOperators(query, dialect)
+	if err != nil {
+		return nil, err
+	}
 
-	switch engine {
-	case "mysql":
+	switch dialect {
+	case DialectMySQL:
 		parsed, err = mysql.Engine().Parse("", query)
 		if err != nil {
-			return nil, nil, nil, nil, err
+			return nil, err
 		}
-	case "pg":
+	case DialectPostgres:
 		parse := func(source, input string) (ast.Node, error) {
 			return parser.Parse(lexer.New(source, input))
 		}
 		engine := rewrite.New("pg", rewrite.Parser(parse))
 		parsed, err = engine.Parse("", query)
 		if err != nil {
-			return nil, nil, nil, nil, err
+			return nil, err
 		}
 	default:
-		return nil, nil, nil, nil, errors.New("unimplemented engine")
+		return nil, errors.Errorf("unimplemented query dialect %s", dialect)
 	}
 
-	tables = traverseTables(parsed)
-	identifiers := traverseIdentifiers(parsed)
-	joins := []VirtualJoin{}
+	an := &analyzer{aliases: map[string]string{}, diagnostics: []Diagnostic{}}
+	an.tables = an.traverseTables(parsed)
+	analysis := &Analysis{
+		Tables:      an.tables,
+		Aliases:     an.aliases,
+		Identifiers: traverseIdentifiers(parsed),
+		Joins:       []VirtualJoin{},
+	}
 	if !skipJoins {
-		joins = traverseJoins(parsed)
+		analysis.Joins = an.traverseJoins(parsed)
+	}
+	analysis.Parameters = an.traverseQueryParameters(parsed)
+	jsonPaths, containments := an.traverseJSONPaths(parsed)
+	for _, param := range analysis.Parameters {
+		if param.Path != "" {
+			jsonPaths = append(jsonPaths, JSONPath{Table: param.Table, Column: param.Column, Path: param.Path, Type: predicateType(param)})
+		}
 	}
-	queryParams := traverseQueryParameters(parsed)
-	return tables, identifiers, joins, queryParams, nil
+	analysis.JSONPaths = jsonPaths
+	analysis.Parameters = append(analysis.Parameters, containments...)
+	analysis.Columns = an.traverseSelectColumns(parsed)
+	analysis.Diagnostics = an.diagnostics
+	return analysis, nil
+}
+
+// QueryParams maps "table.column" to the values used with = and IN operators, the only ones that can be injected as is.
+// The keys of JSON columns are mapped as "table.column$.path", the table keeps the schema the query qualifies it with
+func (a *Analysis) QueryParams() map[string][]string {
+	queryParams := map[string][]string{}
+	for _, param := range a.Parameters {
+		if param.Operator != "=" && param.Operator != "IN" {
+			continue
+		}
+		id := param.Table + "." + param.Column + param.Path
+		queryParams[id] = append(queryParams[id], param.Values...)
+	}
+	return queryParams
+}
+
+func (an *analyzer) addDiagnostic(kind, reason string, n ast.Node) {
+	clause := ""
+	if n != nil {
+		clause = strings.TrimSpace(lexer.Write(n))
+	}
+	log.Debug().Str("kind", kind).Str("reason", reason).Str("clause", clause).Type("node", n).Msg("skipped by the query parser")
+	an.diagnostics = append(an.diagnostics, Diagnostic{Kind: kind, Reason: reason, Clause: clause})
 }
 
 func traverseIdentifiers(n ast.Node) map[string]struct{} {
@@ -78,7 +173,7 @@ func traverseIdentifiers(n ast.Node) map[string]struct{} {
 	return identifiers
 }
 
-func traverseTables(n ast.Node) map[string]struct{} {
+func (an *analyzer) traverseTables(n ast.Node) map[string]struct{} {
 	tables := map[string]struct{}{}
 
 	// we want every mentioned table names
@@ -86,8 +181,8 @@ func traverseTables(n ast.Node) map[string]struct{} {
 	traverser := func(n ast.Node) bool {
 		switch n := n.(type) {
 		case ast.Join:
-			leftname := tableName(n.Left)
-			rightname := tableName(n.Right)
+			leftname := an.tableName(n.Left)
+			rightname := an.tableName(n.Right)
 			log.Debug().Str("leftname", leftname).Str("rightname", rightname).Type("node", n).Type("leftnode", n.Left).Type("rightnode", n.Right).Msg("tableTraverser")
 			if leftname != "" {
 				tables[leftname] = struct{}{}
@@ -97,7 +192,7 @@ func traverseTables(n ast.Node) map[string]struct{} {
 			}
 		case ast.From:
 			for _, item := range n.Tables {
-				tablename := tableName(item.Expression)
+				tablename := an.tableName(item.Expression)
 				log.Debug().Str("tablename", tablename).Type("node", n).Type("item", item.Expression).Msg("tableTraverser")
 				if tablename != "" {
 					tables[tablename] = struct{}{}
@@ -111,7 +206,7 @@ func traverseTables(n ast.Node) map[string]struct{} {
 	return tables
 }
 
-func traverseJoins(n ast.Node) []VirtualJoin {
+func (an *analyzer) traverseJoins(n ast.Node) []VirtualJoin {
 
 	joins := []VirtualJoin{}
 
@@ -125,33 +220,35 @@ func traverseJoins(n ast.Node) []VirtualJoin {
 			if n.Condition == nil {
 				return true
 			}
-			tmp := n.Condition.(ast.Where)
+			tmp, ok := n.Condition.(ast.Where)
+			if !ok {
+				// JOIN ... USING (col)
+				an.addDiagnostic(DiagnosticJoin, ReasonUnsupportedNode, n.Condition)
+				return true
+			}
 			for _, clause := range tmp.Conditions {
 
-				switch clause := clause.Expression.(type) {
-				//case ast.List
+				switch clause := unwrapParens(clause.Expression).(type) {
+				case ast.List:
+					an.addDiagnostic(DiagnosticJoin, ReasonParenthesizedOn, clause)
 				case ast.Infix:
 					//tmp := clause.Expression.(ast.Infix)
-					leftTable, leftCol := getTableColFromInfix(clause.Left)
-					rightTable, rightCol := getTableColFromInfix(clause.Right)
+					leftTable, leftCol := an.getTableColFromInfix(clause.Left)
+					rightTable, rightCol := an.getTableColFromInfix(clause.Right)
 					log.Debug().Str("left", leftTable).Str("right", rightTable).Type("clause", clause).Msg("JoinTraverser")
 					if leftTable == "" || rightTable == "" {
 						log.Debug().Type("left type", clause.Left).Type("right type", clause.Right).Str("left table", leftTable).Str("right table", rightTable).Str("left col", leftCol).Str("right col", rightCol).Msg("left or right side is empty in JoinTraverser, skipping")
+						an.addDiagnostic(DiagnosticJoin, an.skippedJoinReason(clause), clause)
 						continue
 					}
 
 					joins = append(joins, VirtualJoin{
-						Left: VirtualJoinPart{
-							Table:   leftTable,
-							Columns: []string{leftCol},
-						},
-						Right: VirtualJoinPart{
-							Table:   rightTable,
-							Columns: []string{rightCol},
-						},
+						Left:  NewVirtualJoinPart(leftTable, []string{leftCol}),
+						Right: NewVirtualJoinPart(rightTable, []string{rightCol}),
 					})
 				default:
 					log.Debug().Type("clause", clause).Msg("non-handled JoinTraverser")
+					an.addDiagnostic(DiagnosticJoin, ReasonUnsupportedNode, clause)
 				}
 			}
 		}
@@ -162,55 +259,139 @@ func traverseJoins(n ast.Node) []VirtualJoin {
 	return joins
 }
 
-func traverseQueryParameters(n ast.Node) map[string][]string {
+func (an *analyzer) traverseQueryParameters(n ast.Node) []Parameter {
 
-	queryParams := map[string][]string{}
+	params := []Parameter{}
+
+	addParam := func(clause ast.Node, column ast.Node, operator string, values []string) {
+		path := ""
+		if jsonColumn, jsonPath, ok := jsonAccessor(column); ok {
+			column, path = jsonColumn, jsonPath
+		}
+		table, col := an.getTableColumnFromInfixOrLeaf(column)
+		if table == "" {
+			reason := ReasonUnsupportedNode
+			switch {
+			case an.isAmbiguousLeaf(column):
+				reason = ReasonAmbiguousColumn
+			case an.isSubqueryColumn(column):
+				reason = ReasonSubquery
+			}
+			an.addDiagnostic(DiagnosticPredicate, reason, clause)
+			return
+		}
+		params = append(params, Parameter{Table: table, Column: col, Path: path, Operator: operator, Values: values})
+	}
 
 	traverser := func(n ast.Node) bool {
 		switch n := n.(type) {
 		case ast.Infix:
 			switch {
-			case n.Is("="):
-				leftTable, leftCol := getTableColumnFromInfixOrLeaf(n.Left)
-				id := leftTable + "." + leftCol
-				switch right := n.Right.(type) {
-				case ast.Leaf:
-					queryParams[id] = append(queryParams[id], []string{right.String()}...)
+			case n.Is("="), n.Is("<>"), n.Is("!="), n.Is("<"), n.Is(">"), n.Is("<="), n.Is(">="), n.Is("LIKE"), n.Is("NOT", "LIKE"):
+				right, ok := n.Right.(ast.Leaf)
+				if !ok || right.IsIdentifier() {
+					// comparing columns between themselves (joins in WHERE clauses) or expressions
+					return true
 				}
-			case n.Is("IN"):
-				leftTable, leftCol := getTableColumnFromInfixOrLeaf(n.Left)
-				id := leftTable + "." + leftCol
-				switch right := n.Right.(type) {
-				case ast.List:
-					values := []string{}
-					for _, item := range right.Items {
-						if val := getItemValue(item.Expression); val != "" {
-							values = append(values, val)
+				addParam(n, n.Left, operatorString(n), []string{right.String()})
+			case n.Is("IN"), n.Is("NOT", "IN"):
+				right, ok := n.Right.(ast.List)
+				if !ok {
+					// IN (SELECT ...)
+					an.addDiagnostic(DiagnosticPredicate, ReasonSubquery, n)
+					return true
+				}
+				values := []string{}
+				for _, item := range right.Items {
+					if val := getItemValue(item.Expression); val != "" {
+						values = append(values, val)
+					}
+				}
+				if len(values) == 0 {
+					reason := ReasonUnsupportedNode
+					if len(right.Items) == 1 {
+						if _, ok := right.Items[0].Expression.(ast.Select); ok {
+							reason = ReasonSubquery
 						}
 					}
-					queryParams[id] = append(queryParams[id], values...)
+					an.addDiagnostic(DiagnosticPredicate, reason, n)
+					return true
 				}
+				addParam(n, n.Left, operatorString(n), values)
+			}
+		case ast.Between:
+			start, okStart := n.Start.(ast.Leaf)
+			end, okEnd := n.End.(ast.Leaf)
+			if !okStart || !okEnd {
+				an.addDiagnostic(DiagnosticPredicate, ReasonUnsupportedNode, n)
+				return true
+			}
+			operator := "BETWEEN"
+			if len(n.Between) > 1 {
+				operator = "NOT BETWEEN"
 			}
+			addParam(n, n.Expression, operator, []string{start.String(), end.String()})
 		}
 		return true
 	}
 
 	n.Traverse(traverser)
-	return queryParams
+	return params
+}
+
+func operatorString(n ast.Infix) string {
+	ops := make([]string, 0, len(n.Op))
+	for _, op := range n.Op {
+		ops = append(ops, strings.ToUpper(op.Str))
+	}
+	return strings.Join(ops, " ")
+}
+
+// skippedJoinReason guesses why one side of a join clause could not be resolved to a table column
+func (an *analyzer) skippedJoinReason(clause ast.Infix) string {
+	for _, side := range []ast.Node{clause.Left, clause.Right} {
+		switch {
+		case an.isAmbiguousLeaf(side):
+			return ReasonAmbiguousColumn
+		case an.isSubqueryColumn(side):
+			return ReasonSubquery
+		}
+	}
+	return ReasonUnsupportedNode
+}
+
+// a bare column name when the query uses several tables
+func (an *analyzer) isAmbiguousLeaf(n ast.Node) bool {
+	leaf, ok := n.(ast.Leaf)
+	return ok && leaf.IsIdentifier() && len(an.tables) != 1
+}
+
+// alias.col where the alias points to a subquery
+func (an *analyzer) isSubqueryColumn(n ast.Node) bool {
+	infix, ok := n.(ast.Infix)
+	if !ok || !infix.Is(".") {
+		return false
+	}
+	left, ok := infix.Left.(ast.Leaf)
+	if !ok {
+		return false
+	}
+	realTableName, ok := an.aliases[left.Token.Str]
+	return ok && realTableName == ""
 }
 
 // Differs from ast.Tablename for how alias are handled,
 // JOINs are removed because they handled a layer above not to miss the left nodes
-func tableName(expr ast.Node) string {
+func (an *analyzer) tableName(expr ast.Node) string {
 	switch expr := expr.(type) {
 	case ast.Alias: // X.Y AS mytable, (SELECT ...) mytable, ...
-		aliases[expr.Name.Str] = tableName(expr.Expression)
-		return aliases[expr.Name.Str] // Return mytable.
+		an.aliases[expr.Name.Str] = an.tableName(expr.Expression)
+		return an.aliases[expr.Name.Str] // Return mytable.
 	case ast.Leaf: // plain SELECT FROM mytable
 		return expr.Token.Str // return mytable
 	case ast.Infix: // SELECT FROM namespace.mytable.
 		if expr.Is(".") {
-			return tableName(expr.Right) // Return mytable
+			return an.tableName(expr.Left) + "." + an.tableName(expr.Right) // Return namespace.mytable
 		}
 	default:
 		log.Debug().Type("node", expr).Msg("tableName unhandled type")
@@ -218,14 +399,37 @@ func tableName(expr ast.Node) string {
 	return "" // Anonymous table
 }
 
-func getTableColFromInfix(expr ast.Node) (string, string) {
+func (an *analyzer) getTableColFromInfix(expr ast.Node) (string, string) {
 	switch expr := expr.(type) {
 	case ast.Infix:
-		left := expr.Left.(ast.Leaf)
-		right := expr.Right.(ast.Leaf)
-		tablename := left.Token.Str
-		if realTableName, ok := aliases[tablename]; ok {
-			tablename = realTableName
+		right, okRight := expr.Right.(ast.Leaf)
+		if !expr.Is(".") || !okRight {
+			log.Debug().Type("node", expr).Msg("getTableColFromInfix unhandled infix")
+			return "", ""
+		}
+		tablename := ""
+		switch left := expr.Left.(type) {
+		case ast.Leaf:
+			tablename = left.Token.Str
+			if realTableName, ok := an.aliases[tablename]; ok {
+				tablename = realTableName
+			} else {
+				tablename = an.qualifiedTable(tablename)
+			}
+		case ast.Infix: // schema.table.column
+			schema, okSchema := left.Left.(ast.Leaf)
+			table, okTable := left.Right.(ast.Leaf)
+			if !left.Is(".") || !okSchema || !okTable {
+				log.Debug().Type("node", expr).Msg("getTableColFromInfix unhandled infix")
+				return "", ""
+			}
+			tablename = schema.Token.Str + "." + table.Token.Str
+			// MySQL view definitions qualify aliases with the schema
+			if realTableName, ok := an.aliases[table.Token.Str]; ok {
+				tablename = realTableName
+			}
+		default:
+			log.Debug().Type("node", expr).Msg("getTableColFromInfix unhandled infix")
 		}
 		if tablename == "" {
 			return "", ""
@@ -237,16 +441,16 @@ func getTableColFromInfix(expr ast.Node) (string, string) {
 	return "", ""
 }
 
-func getTableColumnFromInfixOrLeaf(expr ast.Node) (string, string) {
+func (an *analyzer) getTableColumnFromInfixOrLeaf(expr ast.Node) (string, string) {
 	switch expr := expr.(type) {
 	case ast.Infix:
-		return getTableColFromInfix(expr)
+		return an.getTableColFromInfix(expr)
 	case ast.Leaf:
-		if len(tables) != 1 {
+		if len(an.tables) != 1 {
 			log.Debug().Type("node", expr).Msg("column is a leaf, but there's multiple tables, potentially ambiguous column name, skipping")
 			return "", ""
 		}
-		for table := range tables {
+		for table := range an.tables {
 			return table, expr.Token.Str
 		}
 	default:
@@ -255,11 +459,44 @@ func getTableColumnFromInfixOrLeaf(expr ast.Node) (string, string) {
 	return "", ""
 }
 
-func removeAlias(s string) string {
-	if s2, ok := aliases[s]; ok {
-		return s2
+// qualifiedTable returns how the query names the table, with its schema when the query qualifies it.
+// table.col is allowed for a table written schema.table, unless tables of several schemas have this name
+func (an *analyzer) qualifiedTable(name string) string {
+	if _, ok := an.tables[name]; ok {
+		return name
+	}
+	found := ""
+	for qualified := range an.tables {
+		if _, table := SplitTableName(qualified); table == name {
+			if found != "" {
+				return name
+			}
+			found = qualified
+		}
+	}
+	if found == "" {
+		return name
+	}
+	return found
+}
+
+// SplitTableName splits schema.table, the schema being empty when the name is not qualified
+func SplitTableName(name string) (string, string) {
+	if i := strings.LastIndex(name, "."); i != -1 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// unwrapParens returns the expression of a single item list, like the ON((a.id = b.id)) of MySQL view definitions
+func unwrapParens(n ast.Node) ast.Node {
+	for {
+		list, ok := n.(ast.List)
+		if !ok || len(list.Items) != 1 {
+			return n
+		}
+		n = list.Items[0].Expression
 	}
-	return s
 }
 
 func getItemValue(expr ast.Node) string {
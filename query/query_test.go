@@ -0,0 +1,108 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeParameters(t *testing.T) {
+	analysis, err := Analyze(`select * from t1 join t2 on t1.id = t2.t1_id
+		where t1.a = 1 and t1.b in ('x', 'y') and t1.c <> 2 and t1.d like 'ab%'
+		and t1.e not in (3, 4) and t1.f between 5 and 6 and t2.g = 'z'`, DialectPostgres, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operators := map[string]string{}
+	for _, param := range analysis.Parameters {
+		operators[param.Table+"."+param.Column] = param.Operator
+	}
+	expectedOperators := map[string]string{
+		"t1.a": "=", "t1.b": "IN", "t1.c": "<>", "t1.d": "LIKE", "t1.e": "NOT IN", "t1.f": "BETWEEN", "t2.g": "=",
+	}
+	if !reflect.DeepEqual(operators, expectedOperators) {
+		t.Errorf("expected parameters %v, got %v", expectedOperators, operators)
+	}
+
+	// only = and IN values can be injected
+	expectedParams := map[string][]string{"t1.a": {"1"}, "t1.b": {"x", "y"}, "t2.g": {"z"}}
+	if params := analysis.QueryParams(); !reflect.DeepEqual(params, expectedParams) {
+		t.Errorf("expected query params %v, got %v", expectedParams, params)
+	}
+}
+
+func TestAnalyzeDiagnostics(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		diagnostics []Diagnostic
+	}{
+		{
+			name:  "ambiguous column",
+			query: "select * from t1 join t2 on t1.id = t2.t1_id where a = 1",
+			diagnostics: []Diagnostic{
+				{Kind: DiagnosticPredicate, Reason: ReasonAmbiguousColumn, Clause: "a = 1"},
+			},
+		},
+		{
+			name:  "subquery",
+			query: "select * from t1 where t1.id in (select t1_id from t2)",
+			diagnostics: []Diagnostic{
+				{Kind: DiagnosticPredicate, Reason: ReasonSubquery, Clause: "t1.id in (select t1_id from t2)"},
+			},
+		},
+		{
+			name:  "join on a subquery",
+			query: "select * from t1 join (select t1_id from t2) s on t1.id = s.t1_id",
+			diagnostics: []Diagnostic{
+				{Kind: DiagnosticJoin, Reason: ReasonSubquery, Clause: "t1.id = s.t1_id"},
+			},
+		},
+		{
+			name:        "nothing skipped",
+			query:       "select * from t1 join t2 on t1.id = t2.t1_id where t1.a = 1",
+			diagnostics: []Diagnostic{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			analysis, err := Analyze(test.query, DialectPostgres, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(analysis.Diagnostics, test.diagnostics) {
+				t.Errorf("expected diagnostics %v, got %v", test.diagnostics, analysis.Diagnostics)
+			}
+		})
+	}
+}
+
+// aliases of a query must not resolve the columns of the next one, like the views analyzed while expanding a query
+func TestAnalyzeKeepsNoState(t *testing.T) {
+	if _, err := Analyze("select * from t1 a join t2 b on a.id = b.t1_id", DialectPostgres, false); err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := Analyze("select * from t3 join t4 on a.id = t4.t3_id", DialectPostgres, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Aliases) != 0 {
+		t.Errorf("expected no aliases, got %v", analysis.Aliases)
+	}
+	expected := []VirtualJoin{{Left: VirtualJoinPart{Table: "a", Columns: []string{"id"}}, Right: VirtualJoinPart{Table: "t4", Columns: []string{"t3_id"}}}}
+	if !reflect.DeepEqual(analysis.Joins, expected) {
+		t.Errorf("expected the join to read the table a, got %v", analysis.Joins)
+	}
+}
+
+func TestAnalyzeParametersQualified(t *testing.T) {
+	analysis, err := Analyze(`select * from crm.orders o join orders p on o.id = p.id
+		where o.status = 'x' and p.status = 'y'`, DialectPostgres, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tables of the same name in two schemas keep their own values
+	expectedParams := map[string][]string{"crm.orders.status": {"x"}, "orders.status": {"y"}}
+	if params := analysis.QueryParams(); !reflect.DeepEqual(params, expectedParams) {
+		t.Errorf("expected query params %v, got %v", expectedParams, params)
+	}
+}
@@ -0,0 +1,120 @@
+package query
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"gitlab.com/dalibo/transqlate/ast"
+)
+
+// Column is the table column read by a column of the outermost SELECT
+type Column struct {
+	Table string `json:"table"` // prefixed by its schema when the query qualifies it
+
+	Column string `json:"column"`
+}
+
+// traverseSelectColumns maps the columns of the outermost SELECT to the table column they read.
+// Expressions and * are left out
+func (an *analyzer) traverseSelectColumns(n ast.Node) map[string]Column {
+	columns := map[string]Column{}
+
+	traverser := func(n ast.Node) bool {
+		sel, ok := n.(ast.Select)
+		if !ok {
+			return len(columns) == 0
+		}
+		for _, item := range sel.List {
+			expr, name := item.Expression, ""
+			if alias, ok := expr.(ast.Alias); ok {
+				expr, name = alias.Expression, alias.Name.Str
+			}
+			table, col := an.getTableColumnFromInfixOrLeaf(expr)
+			if table == "" || col == "*" {
+				continue
+			}
+			if name == "" {
+				name = col
+			}
+			columns[name] = Column{Table: table, Column: col}
+		}
+		// nested SELECTs are not the output of the query
+		return false
+	}
+
+	n.Traverse(traverser)
+	return columns
+}
+
+// ExpandView replaces the view by the base tables read by its definition.
+// Joins and parameters on columns of the view are moved to the base table column they read, or dropped when they read an expression
+func (a *Analysis) ExpandView(view string, definition *Analysis) {
+	delete(a.Tables, view)
+	maps.Copy(a.Tables, definition.Tables)
+	a.Views = append(a.Views, view)
+
+	// an empty whitelist means every column is needed, because of a *
+	if len(a.Identifiers) > 0 && len(definition.Identifiers) > 0 {
+		maps.Copy(a.Identifiers, definition.Identifiers)
+	} else {
+		a.Identifiers = map[string]struct{}{}
+	}
+
+	source := func(table string, columns []string) (string, []string, bool) {
+		if table != view {
+			return table, columns, true
+		}
+		baseTable, baseColumns := "", []string{}
+		for _, col := range columns {
+			c, ok := definition.Columns[col]
+			if !ok || (baseTable != "" && c.Table != baseTable) {
+				return "", nil, false
+			}
+			baseTable = c.Table
+			baseColumns = append(baseColumns, c.Column)
+		}
+		return baseTable, baseColumns, true
+	}
+
+	joins := []VirtualJoin{}
+	for _, join := range a.Joins {
+		leftTable, leftColumns, okLeft := source(join.Left.Name(), join.Left.Columns)
+		rightTable, rightColumns, okRight := source(join.Right.Name(), join.Right.Columns)
+		if !okLeft || !okRight {
+			log.Debug().Str("view", view).Interface("join", join).Msg("join on a view column that is not a base table column, skipping")
+			continue
+		}
+		joins = append(joins, VirtualJoin{
+			Left:  NewVirtualJoinPart(leftTable, leftColumns),
+			Right: NewVirtualJoinPart(rightTable, rightColumns),
+		})
+	}
+	a.Joins = append(joins, definition.Joins...)
+
+	params := []Parameter{}
+	for _, param := range a.Parameters {
+		table, columns, ok := source(param.Table, []string{param.Column})
+		if !ok {
+			log.Debug().Str("view", view).Interface("parameter", param).Msg("parameter on a view column that is not a base table column, skipping")
+			continue
+		}
+		param.Table, param.Column = table, columns[0]
+		params = append(params, param)
+	}
+	a.Parameters = append(params, definition.Parameters...)
+
+	// the columns of the query now read the base tables
+	for name, c := range a.Columns {
+		if c.Table != view {
+			continue
+		}
+		if base, ok := definition.Columns[c.Column]; ok {
+			a.Columns[name] = base
+		} else {
+			delete(a.Columns, name)
+		}
+	}
+	a.Diagnostics = append(a.Diagnostics, definition.Diagnostics...)
+	slices.Sort(a.Views)
+}
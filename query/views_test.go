@@ -0,0 +1,117 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectColumns(t *testing.T) {
+	tests := []struct {
+		query   string
+		columns map[string]Column
+	}{
+		{
+			query:   "select id, name as label from customers",
+			columns: map[string]Column{"id": {"customers", "id"}, "label": {"customers", "name"}},
+		},
+		{
+			query:   "select t.id from sales.t",
+			columns: map[string]Column{"id": {"sales.t", "id"}},
+		},
+		{
+			// expressions do not read a single column
+			query:   "select c.id as customer_id, o.total, upper(c.name) as up, o.amount * 2 as double, 1 as one from customers c join orders o on c.id = o.customer_id",
+			columns: map[string]Column{"customer_id": {"customers", "id"}, "total": {"orders", "total"}},
+		},
+		{
+			// nested SELECTs are not the output of the query
+			query:   "select x.id from t x where x.id in (select name from u)",
+			columns: map[string]Column{"id": {"t", "id"}},
+		},
+		{
+			query:   "select * from customers",
+			columns: map[string]Column{},
+		},
+	}
+	for _, test := range tests {
+		analysis, err := Analyze(test.query, DialectPostgres, false)
+		if err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(analysis.Columns, test.columns) {
+			t.Errorf("%s: expected columns %v, got %v", test.query, test.columns, analysis.Columns)
+		}
+	}
+}
+
+func TestExpandView(t *testing.T) {
+	definition, err := Analyze(`select c.id as cid, c.name as label, o.amount * 2 as total
+		from customers c join orders o on c.id = o.customer_id where o.status = 'paid'`, DialectPostgres, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := Analyze(`select v.label, v.total from v join t2 on v.cid = t2.customer_id join t3 on v.total = t3.total
+		where v.label = 'x' and v.total = 3`, DialectPostgres, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis.ExpandView("v", definition)
+
+	expectedTables := map[string]struct{}{"customers": {}, "orders": {}, "t2": {}, "t3": {}}
+	if !reflect.DeepEqual(analysis.Tables, expectedTables) {
+		t.Errorf("expected tables %v, got %v", expectedTables, analysis.Tables)
+	}
+	if !reflect.DeepEqual(analysis.Views, []string{"v"}) {
+		t.Errorf("expected the view to be listed, got %v", analysis.Views)
+	}
+
+	// the join on the expression is dropped, the join of the view is added
+	expectedJoins := []VirtualJoin{
+		{Left: NewVirtualJoinPart("customers", []string{"id"}), Right: NewVirtualJoinPart("t2", []string{"customer_id"})},
+		{Left: NewVirtualJoinPart("customers", []string{"id"}), Right: NewVirtualJoinPart("orders", []string{"customer_id"})},
+	}
+	if !reflect.DeepEqual(analysis.Joins, expectedJoins) {
+		t.Errorf("expected joins %v, got %v", expectedJoins, analysis.Joins)
+	}
+
+	params := map[string][]string{}
+	for _, param := range analysis.Parameters {
+		params[param.Table+"."+param.Column] = param.Values
+	}
+	expectedParams := map[string][]string{"customers.name": {"x"}, "orders.status": {"paid"}}
+	if !reflect.DeepEqual(params, expectedParams) {
+		t.Errorf("expected parameters %v, got %v", expectedParams, params)
+	}
+
+	expectedColumns := map[string]Column{"label": {"customers", "name"}}
+	if !reflect.DeepEqual(analysis.Columns, expectedColumns) {
+		t.Errorf("expected columns %v, got %v", expectedColumns, analysis.Columns)
+	}
+}
+
+func TestExpandViewStar(t *testing.T) {
+	definition, err := Analyze("select c.id as cid, o.id as oid from customers c join orders o on c.id = o.customer_id", DialectPostgres, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := Analyze("select * from v join t2 on v.cid = t2.cid and v.oid = t2.oid", DialectPostgres, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis.ExpandView("v", definition)
+
+	// each column of the view joins its own base table
+	expectedJoins := []VirtualJoin{
+		{Left: NewVirtualJoinPart("customers", []string{"id"}), Right: NewVirtualJoinPart("t2", []string{"cid"})},
+		{Left: NewVirtualJoinPart("orders", []string{"id"}), Right: NewVirtualJoinPart("t2", []string{"oid"})},
+		{Left: NewVirtualJoinPart("customers", []string{"id"}), Right: NewVirtualJoinPart("orders", []string{"customer_id"})},
+	}
+	if !reflect.DeepEqual(analysis.Joins, expectedJoins) {
+		t.Errorf("expected joins %v, got %v", expectedJoins, analysis.Joins)
+	}
+	// * needs every column
+	if len(analysis.Identifiers) != 0 {
+		t.Errorf("expected no column whitelist, got %v", analysis.Identifiers)
+	}
+}